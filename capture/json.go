@@ -0,0 +1,44 @@
+package capture
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+)
+
+type messageJSON struct {
+	Time        time.Time                    `json:"time"`
+	Transport   string                       `json:"transport"`
+	Source      string                       `json:"source"`
+	Destination string                       `json:"destination"`
+	FromDevice  bool                         `json:"from_device"`
+	IMEI        string                       `json:"imei,omitempty"`
+	Kind        string                       `json:"kind"`
+	Data        string                       `json:"data"`
+	Decoded     *decoder_domain.CodecDecoded `json:"decoded,omitempty"`
+	Accepted    *int64                       `json:"accepted,omitempty"`
+	Gap         bool                         `json:"gap,omitempty"`
+}
+
+// MarshalJSON encodes the message with hex data and addresses as
+// "host:port". Accepted is written for ACKs and login responses only.
+func (m Message) MarshalJSON() ([]byte, error) {
+	value := messageJSON{
+		Time:        m.Time,
+		Transport:   m.Transport,
+		Source:      m.Source.String(),
+		Destination: m.Destination.String(),
+		FromDevice:  m.FromDevice,
+		IMEI:        m.IMEI,
+		Kind:        m.Kind,
+		Data:        hex.EncodeToString(m.Data),
+		Decoded:     m.Decoded,
+		Gap:         m.Gap,
+	}
+	if m.Kind == KindAck || m.Kind == KindLoginResponse {
+		value.Accepted = &m.Accepted
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,182 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// errSkipped reports a packet that carries no TCP or UDP payload the
+// extractor can use: another protocol, an IP fragment or a truncated
+// capture.
+var errSkipped = errors.New("packet skipped")
+
+const (
+	protocolTCP = 6
+	protocolUDP = 17
+)
+
+// TCP flags.
+const (
+	flagFIN = 0x01
+	flagSYN = 0x02
+	flagRST = 0x04
+	flagACK = 0x10
+)
+
+// segment is a TCP segment or UDP datagram.
+type segment struct {
+	time     time.Time
+	protocol int // protocolTCP or protocolUDP
+	source   netip.AddrPort
+	target   netip.AddrPort
+	sequence uint32 // TCP only
+	flags    byte   // TCP only
+	payload  []byte
+}
+
+// decodeSegment parses the link, network and transport headers of packet.
+func decodeSegment(packet Packet) (segment, error) {
+	data := packet.Data
+	if len(data) < packet.Length {
+		return segment{}, fmt.Errorf("%w: %d of %d bytes captured", errSkipped, len(data), packet.Length)
+	}
+	var etherType uint16
+	switch packet.LinkType {
+	case LinkTypeEthernet:
+		if len(data) < 14 {
+			return segment{}, fmt.Errorf("%w: short Ethernet header", errSkipped)
+		}
+		etherType, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+		// 802.1Q and 802.1ad VLAN tags
+		for (etherType == 0x8100 || etherType == 0x88A8) && len(data) >= 4 {
+			etherType, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return segment{}, fmt.Errorf("%w: short Linux cooked header", errSkipped)
+		}
+		etherType, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case LinkTypeSLL2:
+		if len(data) < 20 {
+			return segment{}, fmt.Errorf("%w: short Linux cooked v2 header", errSkipped)
+		}
+		etherType, data = binary.BigEndian.Uint16(data), data[20:]
+	case LinkTypeNull, LinkTypeLoop:
+		if len(data) < 4 {
+			return segment{}, fmt.Errorf("%w: short loopback header", errSkipped)
+		}
+		data = data[4:]
+	case LinkTypeRaw, LinkTypeIPv4, LinkTypeIPv6:
+	default:
+		return segment{}, fmt.Errorf("%w: link type %d", errSkipped, packet.LinkType)
+	}
+	if etherType == 0 && len(data) > 0 {
+		// Link types without an EtherType carry IP, told apart by version.
+		etherType = map[byte]uint16{4: 0x0800, 6: 0x86DD}[data[0]>>4]
+	}
+
+	var protocol byte
+	var source, target netip.Addr
+	var err error
+	switch etherType {
+	case 0x0800:
+		protocol, source, target, data, err = decodeIPv4(data)
+	case 0x86DD:
+		protocol, source, target, data, err = decodeIPv6(data)
+	default:
+		return segment{}, fmt.Errorf("%w: EtherType 0x%04X", errSkipped, etherType)
+	}
+	if err != nil {
+		return segment{}, err
+	}
+
+	decoded := segment{time: packet.Time}
+	switch protocol {
+	case protocolTCP:
+		if len(data) < 20 || len(data) < int(data[12]>>4)*4 || data[12]>>4 < 5 {
+			return segment{}, fmt.Errorf("%w: invalid TCP header", errSkipped)
+		}
+		decoded.protocol = protocolTCP
+		decoded.sequence = binary.BigEndian.Uint32(data[4:])
+		decoded.flags = data[13]
+		decoded.payload = data[int(data[12]>>4)*4:]
+	case protocolUDP:
+		if len(data) < 8 {
+			return segment{}, fmt.Errorf("%w: short UDP header", errSkipped)
+		}
+		length := int(binary.BigEndian.Uint16(data[4:]))
+		if length < 8 || length > len(data) {
+			return segment{}, fmt.Errorf("%w: UDP length %d", errSkipped, length)
+		}
+		decoded.protocol = protocolUDP
+		decoded.payload = data[8:length]
+	default:
+		return segment{}, fmt.Errorf("%w: IP protocol %d", errSkipped, protocol)
+	}
+	decoded.source = netip.AddrPortFrom(source, binary.BigEndian.Uint16(data))
+	decoded.target = netip.AddrPortFrom(target, binary.BigEndian.Uint16(data[2:]))
+	return decoded, nil
+}
+
+// decodeIPv4 returns the protocol, the addresses and the payload of an
+// IPv4 packet. Fragments are skipped.
+func decodeIPv4(data []byte) (byte, netip.Addr, netip.Addr, []byte, error) {
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: invalid IPv4 header", errSkipped)
+	}
+	headerLength := int(data[0]&0x0F) * 4
+	totalLength := int(binary.BigEndian.Uint16(data[2:]))
+	if headerLength < 20 || totalLength < headerLength || totalLength > len(data) {
+		return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: invalid IPv4 lengths", errSkipped)
+	}
+	if fragment := binary.BigEndian.Uint16(data[6:]); fragment&0x3FFF != 0 {
+		return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: IPv4 fragment", errSkipped)
+	}
+	source := netip.AddrFrom4([4]byte(data[12:16]))
+	target := netip.AddrFrom4([4]byte(data[16:20]))
+	return data[9], source, target, data[headerLength:totalLength], nil
+}
+
+// decodeIPv6 returns the protocol, the addresses and the payload of an
+// IPv6 packet after its extension headers. Fragments are skipped.
+func decodeIPv6(data []byte) (byte, netip.Addr, netip.Addr, []byte, error) {
+	if len(data) < 40 || data[0]>>4 != 6 {
+		return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: invalid IPv6 header", errSkipped)
+	}
+	source := netip.AddrFrom16([16]byte(data[8:24]))
+	target := netip.AddrFrom16([16]byte(data[24:40]))
+	next := data[6]
+	payload := data[40:]
+	if length := int(binary.BigEndian.Uint16(data[4:])); length != 0 {
+		if length > len(payload) {
+			return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: invalid IPv6 length", errSkipped)
+		}
+		payload = payload[:length]
+	}
+	for {
+		var size int
+		switch next {
+		case 0, 43, 60: // hop-by-hop, routing and destination options
+			if len(payload) < 2 {
+				return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: short IPv6 extension header", errSkipped)
+			}
+			size = (int(payload[1]) + 1) * 8
+		case 51: // authentication header
+			if len(payload) < 2 {
+				return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: short IPv6 extension header", errSkipped)
+			}
+			size = (int(payload[1]) + 2) * 4
+		case 44:
+			return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: IPv6 fragment", errSkipped)
+		default:
+			return next, source, target, payload, nil
+		}
+		if size > len(payload) {
+			return 0, netip.Addr{}, netip.Addr{}, nil, fmt.Errorf("%w: short IPv6 extension header", errSkipped)
+		}
+		next, payload = payload[0], payload[size:]
+	}
+}
@@ -0,0 +1,342 @@
+// Package capture imports Teltonika traffic from packet captures. It reads
+// pcap and pcapng files without libpcap, parses Ethernet, IPv4, IPv6, TCP
+// and UDP, reassembles TCP streams and extracts the logins, frames and
+// acknowledgements of Teltonika conversations, decoded with the decoders
+// of the pkg package.
+//
+// Example:
+//
+//	file, _ := os.Open("customer.pcapng")
+//	reader, err := capture.NewReader(file)
+//	extractor := &capture.Extractor{}
+//	stats, err := extractor.Extract(reader, func(message capture.Message) error {
+//		fmt.Println(message.Time, message.IMEI, message.Kind)
+//		return nil
+//	})
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+)
+
+var (
+	// ErrUnknownFormat reports a file that is neither pcap nor pcapng.
+	ErrUnknownFormat = errors.New("not a pcap or pcapng file")
+	// ErrMalformed reports a pcap or pcapng file with an invalid block or
+	// record.
+	ErrMalformed = errors.New("malformed capture file")
+)
+
+// Link types of the packets the package can parse.
+const (
+	LinkTypeNull     = 0
+	LinkTypeEthernet = 1
+	LinkTypeRaw      = 101
+	LinkTypeLoop     = 108
+	LinkTypeLinuxSLL = 113
+	LinkTypeIPv4     = 228
+	LinkTypeIPv6     = 229
+	LinkTypeSLL2     = 276
+)
+
+// maxPacketSize bounds the captured length of a packet, so that a corrupt
+// length cannot make the reader allocate without limit.
+const maxPacketSize = 1 << 18
+
+const (
+	pcapMagicMicro      = 0xA1B2C3D4
+	pcapMagicNano       = 0xA1B23C4D
+	pcapngSectionHeader = 0x0A0D0D0A
+	pcapngByteOrder     = 0x1A2B3C4D
+)
+
+// pcapng block types.
+const (
+	blockInterface      = 0x00000001
+	blockPacket         = 0x00000002 // obsolete Packet Block
+	blockSimplePacket   = 0x00000003
+	blockEnhancedPacket = 0x00000006
+)
+
+// Packet is a captured link-layer packet.
+type Packet struct {
+	Time     time.Time
+	LinkType int
+	Data     []byte
+	Length   int // length on the wire, more than len(Data) when truncated
+}
+
+// Reader reads the packets of a pcap or pcapng file in order.
+type Reader struct {
+	reader *bufio.Reader
+	order  binary.ByteOrder
+	ng     bool
+
+	// pcap
+	linkType int
+	nano     bool
+
+	// pcapng: the interfaces of the current section
+	interfaces []pcapngInterface
+}
+
+type pcapngInterface struct {
+	linkType   int
+	snapLength int
+	units      uint64 // timestamp units per second
+	offset     int64  // seconds added to timestamps
+}
+
+// NewReader detects the format of r from its first bytes and reads the
+// pcap file header. A pcapng file is read block by block by Next.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{reader: bufio.NewReaderSize(r, 1<<16)}
+	magic, err := reader.reader.Peek(4)
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if binary.BigEndian.Uint32(magic) == pcapngSectionHeader {
+		reader.ng = true
+		return reader, nil
+	}
+
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(reader.reader, header); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		switch order.Uint32(header) {
+		case pcapMagicMicro:
+			reader.order = order
+		case pcapMagicNano:
+			reader.order, reader.nano = order, true
+		}
+	}
+	if reader.order == nil {
+		return nil, ErrUnknownFormat
+	}
+	// The upper bits of the link type field carry the FCS length.
+	reader.linkType = int(reader.order.Uint32(header[20:]) & 0x03FFFFFF)
+	return reader, nil
+}
+
+// Next returns the next packet, or io.EOF at the end of the file.
+func (r *Reader) Next() (Packet, error) {
+	if r.ng {
+		return r.nextBlock()
+	}
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r.reader, header); err != nil {
+		return Packet{}, endOfFile(err)
+	}
+	seconds := int64(r.order.Uint32(header))
+	fraction := int64(r.order.Uint32(header[4:]))
+	captured := int(r.order.Uint32(header[8:]))
+	if captured > maxPacketSize {
+		return Packet{}, fmt.Errorf("%w: packet of %d bytes", ErrMalformed, captured)
+	}
+	data := make([]byte, captured)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return Packet{}, truncated(err)
+	}
+	if !r.nano {
+		fraction *= 1000
+	}
+	return Packet{
+		Time:     time.Unix(seconds, fraction).UTC(),
+		LinkType: r.linkType,
+		Data:     data,
+		Length:   int(r.order.Uint32(header[12:])),
+	}, nil
+}
+
+// nextBlock reads pcapng blocks until a packet block.
+func (r *Reader) nextBlock() (Packet, error) {
+	for {
+		head, err := r.reader.Peek(8)
+		if err != nil {
+			if len(head) == 0 {
+				return Packet{}, io.EOF
+			}
+			return Packet{}, truncated(io.ErrUnexpectedEOF)
+		}
+		if binary.BigEndian.Uint32(head) == pcapngSectionHeader {
+			if err := r.sectionHeader(); err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+		if r.order == nil {
+			return Packet{}, fmt.Errorf("%w: block before the section header", ErrMalformed)
+		}
+		blockType := r.order.Uint32(head)
+		body, err := r.block(int(r.order.Uint32(head[4:])))
+		if err != nil {
+			return Packet{}, err
+		}
+		switch blockType {
+		case blockInterface:
+			if err := r.addInterface(body); err != nil {
+				return Packet{}, err
+			}
+		case blockEnhancedPacket:
+			return r.enhancedPacket(body)
+		case blockPacket:
+			return r.obsoletePacket(body)
+		case blockSimplePacket:
+			return r.simplePacket(body)
+		}
+	}
+}
+
+// sectionHeader reads a Section Header Block, which sets the byte order
+// and starts a new list of interfaces.
+func (r *Reader) sectionHeader() error {
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(r.reader, head); err != nil {
+		return truncated(err)
+	}
+	switch {
+	case binary.BigEndian.Uint32(head[8:]) == pcapngByteOrder:
+		r.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(head[8:]) == pcapngByteOrder:
+		r.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("%w: invalid byte-order magic", ErrMalformed)
+	}
+	length := int(r.order.Uint32(head[4:]))
+	if length < 28 || length%4 != 0 || length > maxPacketSize {
+		return fmt.Errorf("%w: section header of %d bytes", ErrMalformed, length)
+	}
+	if _, err := r.reader.Discard(length - 12); err != nil {
+		return truncated(err)
+	}
+	r.interfaces = r.interfaces[:0]
+	return nil
+}
+
+// block reads a block of length bytes and returns its body, the bytes
+// between the leading type and length and the trailing length.
+func (r *Reader) block(length int) ([]byte, error) {
+	if length < 12 || length%4 != 0 || length > maxPacketSize+64 {
+		return nil, fmt.Errorf("%w: block of %d bytes", ErrMalformed, length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return nil, truncated(err)
+	}
+	if trailer := int(r.order.Uint32(data[length-4:])); trailer != length {
+		return nil, fmt.Errorf("%w: block lengths %d and %d differ", ErrMalformed, length, trailer)
+	}
+	return data[8 : length-4], nil
+}
+
+// addInterface reads an Interface Description Block with its timestamp
+// resolution and offset options.
+func (r *Reader) addInterface(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("%w: short interface description", ErrMalformed)
+	}
+	iface := pcapngInterface{
+		linkType:   int(r.order.Uint16(body)),
+		snapLength: int(r.order.Uint32(body[4:])),
+		units:      1e6,
+	}
+	for options := body[8:]; len(options) >= 4; {
+		code, length := r.order.Uint16(options), int(r.order.Uint16(options[2:]))
+		if code == 0 || 4+length > len(options) {
+			break
+		}
+		value := options[4 : 4+length]
+		switch {
+		case code == 9 && length == 1: // if_tsresol
+			exponent := int(value[0] & 0x7F)
+			switch {
+			case value[0]&0x80 != 0 && exponent < 64:
+				iface.units = 1 << exponent
+			case value[0]&0x80 == 0 && exponent < 20:
+				iface.units = 1
+				for range exponent {
+					iface.units *= 10
+				}
+			default:
+				return fmt.Errorf("%w: timestamp resolution 0x%02X", ErrMalformed, value[0])
+			}
+		case code == 14 && length == 8: // if_tsoffset
+			iface.offset = int64(r.order.Uint64(value))
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+	r.interfaces = append(r.interfaces, iface)
+	return nil
+}
+
+func (r *Reader) enhancedPacket(body []byte) (Packet, error) {
+	if len(body) < 20 {
+		return Packet{}, fmt.Errorf("%w: short enhanced packet block", ErrMalformed)
+	}
+	timestamp := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+	return r.packet(int(r.order.Uint32(body)), timestamp, body[20:], int(r.order.Uint32(body[12:])), int(r.order.Uint32(body[16:])))
+}
+
+func (r *Reader) obsoletePacket(body []byte) (Packet, error) {
+	if len(body) < 20 {
+		return Packet{}, fmt.Errorf("%w: short packet block", ErrMalformed)
+	}
+	timestamp := uint64(r.order.Uint32(body[4:]))<<32 | uint64(r.order.Uint32(body[8:]))
+	return r.packet(int(r.order.Uint16(body)), timestamp, body[20:], int(r.order.Uint32(body[12:])), int(r.order.Uint32(body[16:])))
+}
+
+// simplePacket reads a Simple Packet Block, which belongs to the first
+// interface and has no timestamp.
+func (r *Reader) simplePacket(body []byte) (Packet, error) {
+	if len(body) < 4 || len(r.interfaces) == 0 {
+		return Packet{}, fmt.Errorf("%w: invalid simple packet block", ErrMalformed)
+	}
+	length := int(r.order.Uint32(body))
+	captured := min(length, len(body)-4)
+	if snap := r.interfaces[0].snapLength; snap > 0 {
+		captured = min(captured, snap)
+	}
+	return Packet{LinkType: r.interfaces[0].linkType, Data: body[4 : 4+captured], Length: length}, nil
+}
+
+func (r *Reader) packet(id int, timestamp uint64, data []byte, captured int, length int) (Packet, error) {
+	if id >= len(r.interfaces) {
+		return Packet{}, fmt.Errorf("%w: packet of undeclared interface %d", ErrMalformed, id)
+	}
+	if captured > len(data) {
+		return Packet{}, fmt.Errorf("%w: packet of %d bytes in a shorter block", ErrMalformed, captured)
+	}
+	iface := r.interfaces[id]
+	seconds, remainder := timestamp/iface.units, timestamp%iface.units
+	high, low := bits.Mul64(remainder, 1e9)
+	nanoseconds, _ := bits.Div64(high, low, iface.units)
+	return Packet{
+		Time:     time.Unix(int64(seconds)+iface.offset, int64(nanoseconds)).UTC(),
+		LinkType: iface.linkType,
+		Data:     data[:captured],
+		Length:   length,
+	}, nil
+}
+
+// endOfFile maps a clean end between records to io.EOF.
+func endOfFile(err error) error {
+	if err == io.EOF {
+		return io.EOF
+	}
+	return truncated(err)
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %w", ErrMalformed, io.ErrUnexpectedEOF)
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package capture
+
+import "time"
+
+// stream reassembles one direction of a TCP connection. Segments are
+// delivered in sequence order; retransmitted bytes are dropped and
+// segments arriving early wait until the bytes before them arrive. When
+// too much data waits, or the capture ends, the missing bytes are given up
+// on and the stream reports a gap.
+type stream struct {
+	started bool
+	next    uint32 // sequence number of the next byte to deliver
+	pending map[uint32]pendingSegment
+	waiting int // bytes in pending
+
+	buffer []byte    // delivered bytes not consumed yet
+	time   time.Time // latest time of the segments delivered
+	gap    bool      // bytes were lost before the start of buffer
+}
+
+type pendingSegment struct {
+	time    time.Time
+	payload []byte
+}
+
+// add passes a segment to the stream. It returns whether new bytes were
+// delivered to the buffer. The first segment sets the initial sequence
+// number; a new connection on the same addresses needs a new stream.
+func (s *stream) add(segment segment, maxWaiting int) bool {
+	if !s.started {
+		s.started = true
+		s.next = segment.sequence
+		if segment.flags&flagSYN != 0 {
+			s.next++
+		}
+	}
+	if len(segment.payload) == 0 {
+		return false
+	}
+	if int32(segment.sequence-s.next) > 0 {
+		if s.pending == nil {
+			s.pending = make(map[uint32]pendingSegment)
+		}
+		if previous, ok := s.pending[segment.sequence]; !ok || len(previous.payload) < len(segment.payload) {
+			s.pending[segment.sequence] = pendingSegment{time: segment.time, payload: segment.payload}
+			s.waiting += len(segment.payload) - len(previous.payload)
+		}
+		if s.waiting <= maxWaiting {
+			return false
+		}
+		s.skip()
+		return true
+	}
+	delivered := s.deliver(segment.sequence, segment.time, segment.payload)
+	return s.drain() || delivered
+}
+
+// flush gives up on the bytes missing before the segments that wait,
+// delivering them all. It returns whether bytes were delivered.
+func (s *stream) flush() bool {
+	delivered := false
+	for len(s.pending) > 0 {
+		s.skip()
+		delivered = true
+	}
+	return delivered
+}
+
+// skip jumps over the missing bytes before the first waiting segment. The
+// undelivered rest of the buffer cannot be completed and is dropped.
+func (s *stream) skip() {
+	first := true
+	var earliest uint32
+	for sequence := range s.pending {
+		if first || int32(sequence-earliest) < 0 {
+			earliest, first = sequence, false
+		}
+	}
+	s.next = earliest
+	s.buffer, s.gap = nil, true
+	s.drain()
+}
+
+// deliver appends the bytes of a segment at sequence that follow the
+// delivered ones.
+func (s *stream) deliver(sequence uint32, time time.Time, payload []byte) bool {
+	overlap := int(s.next - sequence)
+	if overlap >= len(payload) {
+		return false
+	}
+	s.buffer = append(s.buffer, payload[overlap:]...)
+	s.next += uint32(len(payload) - overlap)
+	if time.After(s.time) {
+		s.time = time
+	}
+	return true
+}
+
+// drain delivers the waiting segments that have become contiguous.
+func (s *stream) drain() bool {
+	delivered := false
+	for progress := true; progress; {
+		progress = false
+		for sequence, segment := range s.pending {
+			if int32(sequence-s.next) > 0 {
+				continue
+			}
+			delete(s.pending, sequence)
+			s.waiting -= len(segment.payload)
+			if s.deliver(sequence, segment.time, segment.payload) {
+				delivered, progress = true, true
+			}
+		}
+	}
+	return delivered
+}
+
+// consume removes n bytes from the front of the buffer.
+func (s *stream) consume(n int) {
+	s.buffer = s.buffer[n:]
+	if len(s.buffer) == 0 {
+		s.buffer = nil
+	}
+}
@@ -0,0 +1,533 @@
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+	"slices"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	journal "github.com/danieljvsa/teltonika-go/journal"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// Kinds of Message.
+const (
+	KindLogin         = "login"
+	KindLoginResponse = "login response"
+	KindFrame         = "frame"
+	KindAck           = "ack"
+)
+
+// Message is a Teltonika packet found in a capture.
+type Message struct {
+	// Time is the capture time of the packet that completed the message.
+	Time        time.Time
+	Transport   string // "TCP" or "UDP"
+	Source      netip.AddrPort
+	Destination netip.AddrPort
+	// FromDevice is set for logins and the frames a device sends, and
+	// unset for login responses, ACKs and server commands.
+	FromDevice bool
+	// IMEI identifies the device. It is empty for a TCP connection whose
+	// login is not in the capture.
+	IMEI string
+	Kind string
+	Data []byte
+	// Decoded holds the decoded login or frame.
+	Decoded *decoder_domain.CodecDecoded
+	// Accepted is the record count of an ACK, or 1 or 0 for a login
+	// response that accepts or rejects the device.
+	Accepted int64
+	// Gap reports that bytes of the connection before the message are
+	// missing from the capture.
+	Gap bool
+}
+
+// JournalEntry returns the message as a journal entry, as a server would
+// have recorded it on receipt. Only messages from the device other than
+// logins have one.
+func (m Message) JournalEntry() (journal.Entry, bool) {
+	if !m.FromDevice || m.Kind != KindFrame {
+		return journal.Entry{}, false
+	}
+	return journal.Entry{Time: m.Time, IMEI: m.IMEI, Transport: m.Transport, RemoteAddr: m.Source.String(), Frame: m.Data}, true
+}
+
+// Extractor finds Teltonika conversations in a capture. A TCP connection
+// is one when a side starts with a login packet, or, for connections
+// already open when the capture started, with an AVL frame whose CRC
+// matches. A UDP datagram is one when it has a valid UDP header with an
+// IMEI. The zero value of each field selects the default given in its
+// comment.
+type Extractor struct {
+	// Ports restricts the extraction to traffic to or from these ports.
+	// Empty looks at every port.
+	Ports []uint16
+	// DecoderOptions are passed to the decoders.
+	DecoderOptions pkg.DecoderOptions
+	// MaxBuffered bounds the bytes held for one direction of a TCP
+	// connection while segments are missing or a message is incomplete,
+	// 1 MiB if zero.
+	MaxBuffered int
+}
+
+// Stats counts what an extraction went through.
+type Stats struct {
+	Packets       int // packets read
+	Skipped       int // packets without usable TCP or UDP payload
+	Conversations int // TCP connections and UDP device addresses found
+	Messages      int
+}
+
+// extraction is the state of one Extract call.
+type extraction struct {
+	*Extractor
+	emit          func(Message) error
+	stats         Stats
+	conversations map[[2]netip.AddrPort]*conversation
+	order         []*conversation
+	udpDevices    map[netip.AddrPort]string
+}
+
+// conversation is a TCP connection. Side i sends streams[i] from
+// endpoints[i].
+type conversation struct {
+	endpoints [2]netip.AddrPort
+	streams   [2]stream
+	device    int // index of the device side, -1 while unknown
+	ignored   bool
+	imei      string
+	notDevice [2]bool // a side that does not start like a device
+	closed    [2]bool // a side that sent a FIN
+	// expectLogin and expectResponse are set while the login packet and
+	// its response are due.
+	expectLogin    bool
+	expectResponse bool
+	gaps           [2]bool // a gap precedes the next message of a side
+}
+
+// Extract reads every packet of reader and calls fn with each Teltonika
+// message, in the order the messages complete. A non-nil error from fn
+// stops the extraction and is returned, as is a malformed capture file.
+func (e *Extractor) Extract(reader *Reader, fn func(Message) error) (Stats, error) {
+	x := &extraction{
+		Extractor:     e,
+		emit:          fn,
+		conversations: map[[2]netip.AddrPort]*conversation{},
+		udpDevices:    map[netip.AddrPort]string{},
+	}
+	for {
+		packet, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return x.stats, err
+		}
+		x.stats.Packets++
+		segment, err := decodeSegment(packet)
+		if errors.Is(err, errSkipped) {
+			x.stats.Skipped++
+			continue
+		}
+		if !x.wanted(segment) {
+			continue
+		}
+		if segment.protocol == protocolUDP {
+			err = x.datagram(segment)
+		} else {
+			err = x.segment(segment)
+		}
+		if err != nil {
+			return x.stats, err
+		}
+	}
+	for _, conv := range x.order {
+		if err := x.finish(conv); err != nil {
+			return x.stats, err
+		}
+	}
+	return x.stats, nil
+}
+
+func (x *extraction) wanted(segment segment) bool {
+	if len(x.Ports) == 0 {
+		return true
+	}
+	return slices.Contains(x.Ports, segment.source.Port()) || slices.Contains(x.Ports, segment.target.Port())
+}
+
+func (x *extraction) maxBuffered() int {
+	if x.MaxBuffered <= 0 {
+		return 1 << 20
+	}
+	return x.MaxBuffered
+}
+
+// segment passes a TCP segment to its conversation. A SYN opening a new
+// connection on the addresses of an old one starts a new conversation.
+func (x *extraction) segment(segment segment) error {
+	key := [2]netip.AddrPort{segment.source, segment.target}
+	if segment.source.Compare(segment.target) > 0 {
+		key = [2]netip.AddrPort{segment.target, segment.source}
+	}
+	conv := x.conversations[key]
+	if conv != nil && segment.flags&(flagSYN|flagACK) == flagSYN {
+		side := conv.side(segment.source)
+		if !conv.streams[side].started || conv.streams[side].next != segment.sequence+1 {
+			if err := x.finish(conv); err != nil {
+				return err
+			}
+			conv = nil
+		}
+	}
+	if conv == nil {
+		conv = &conversation{endpoints: [2]netip.AddrPort{segment.source, segment.target}, device: -1}
+		x.conversations[key] = conv
+		x.order = append(x.order, conv)
+	}
+	if conv.ignored {
+		return nil
+	}
+	side := conv.side(segment.source)
+	if conv.streams[side].add(segment, x.maxBuffered()) {
+		if err := x.parse(conv, false); err != nil {
+			return err
+		}
+	}
+	// A reset or a FIN from both sides with no segment missing ends the
+	// connection.
+	conv.closed[side] = conv.closed[side] || segment.flags&flagFIN != 0
+	if segment.flags&flagRST != 0 || (conv.closed[0] && conv.closed[1] && len(conv.streams[0].pending) == 0 && len(conv.streams[1].pending) == 0) {
+		return x.finish(conv)
+	}
+	return nil
+}
+
+// finish delivers what the capture left incomplete in a conversation and
+// forgets it.
+func (x *extraction) finish(conv *conversation) error {
+	if x.conversations[conv.key()] == conv {
+		delete(x.conversations, conv.key())
+	}
+	if conv.ignored {
+		return nil
+	}
+	conv.streams[0].flush()
+	conv.streams[1].flush()
+	err := x.parse(conv, true)
+	conv.ignored, conv.streams = true, [2]stream{}
+	return err
+}
+
+func (conv *conversation) side(source netip.AddrPort) int {
+	if source == conv.endpoints[0] {
+		return 0
+	}
+	return 1
+}
+
+func (conv *conversation) key() [2]netip.AddrPort {
+	if conv.endpoints[0].Compare(conv.endpoints[1]) > 0 {
+		return [2]netip.AddrPort{conv.endpoints[1], conv.endpoints[0]}
+	}
+	return conv.endpoints
+}
+
+// parse identifies the device side of a conversation, then extracts the
+// complete messages of both sides. At the end of the capture, atEnd
+// settles what more bytes would otherwise decide.
+func (x *extraction) parse(conv *conversation, atEnd bool) error {
+	if conv.device < 0 && !x.identify(conv, atEnd) {
+		return nil
+	}
+	if err := x.parseDevice(conv); err != nil {
+		return err
+	}
+	return x.parseServer(conv, atEnd)
+}
+
+// identify looks for the device side of a conversation: the side that
+// starts with a login packet, or with an AVL frame when the capture
+// started after the login.
+func (x *extraction) identify(conv *conversation, atEnd bool) bool {
+	for side := range conv.streams {
+		stream := &conv.streams[side]
+		if conv.notDevice[side] {
+			// The bytes of this side cannot be told apart until the
+			// other side shows the conversation is Teltonika.
+			stream.buffer, stream.gap = nil, false
+			continue
+		}
+		start, complete := sniff(stream.buffer)
+		switch {
+		case stream.gap:
+			conv.notDevice[side] = true
+			stream.buffer, stream.gap = nil, false
+			continue
+		case start == KindLogin:
+			conv.expectLogin, conv.expectResponse = true, true
+		case start == KindFrame:
+		case !complete && !atEnd && len(stream.buffer) <= x.maxBuffered():
+			continue
+		default:
+			conv.notDevice[side] = true
+			stream.buffer = nil
+			continue
+		}
+		conv.device = side
+		x.stats.Conversations++
+		return true
+	}
+	if (conv.notDevice[0] && conv.notDevice[1]) || atEnd {
+		conv.ignored = true
+		conv.streams = [2]stream{}
+	}
+	return false
+}
+
+// sniff tells whether data starts with a login packet or a TCP AVL frame
+// with a matching CRC. complete is unset while more bytes could change
+// the answer.
+func sniff(data []byte) (kind string, complete bool) {
+	if len(data) < 2 {
+		return "", false
+	}
+	if length := int(binary.BigEndian.Uint16(data)); length > 0 && length <= pkg.MaxIMEILength {
+		if len(data) < 2+length {
+			return "", false
+		}
+		if pkg.IsLoginPacket(data[:2+length]) {
+			return KindLogin, true
+		}
+		return "", true
+	}
+	if len(data) < 8 {
+		return "", binary.BigEndian.Uint16(data) != 0 || (len(data) >= 4 && binary.BigEndian.Uint32(data) != 0)
+	}
+	size, ok := frameSize(data)
+	if !ok {
+		return "", true
+	}
+	if len(data) < size {
+		return "", false
+	}
+	if pkg.IsAVLCodec(data[8]) && tools.IsValidTram(data[8:size]) {
+		return KindFrame, true
+	}
+	return "", true
+}
+
+// parseDevice extracts the login and frames of the device side.
+func (x *extraction) parseDevice(conv *conversation) error {
+	side := conv.device
+	stream := &conv.streams[side]
+	for {
+		if stream.gap {
+			conv.expectLogin = false
+			if !resync(stream) {
+				return nil
+			}
+			conv.gaps[side] = true
+		}
+		data := stream.buffer
+		if conv.expectLogin {
+			if len(data) < 2 || len(data) < 2+int(binary.BigEndian.Uint16(data)) {
+				return nil
+			}
+			packet := data[:2+int(binary.BigEndian.Uint16(data))]
+			decoded := pkg.LoginDecoder(packet)
+			if decoded.Response != nil && decoded.Response.Result.IMEI != nil {
+				conv.imei = *decoded.Response.Result.IMEI
+			}
+			conv.expectLogin = false
+			stream.consume(len(packet))
+			if err := x.message(conv, side, KindLogin, packet, decoded, 0); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(data) < 8 {
+			return nil
+		}
+		size, ok := frameSize(data)
+		if !ok || size > x.maxBuffered() {
+			stream.gap = true
+			continue
+		}
+		if len(data) < size {
+			return nil
+		}
+		frame := data[:size]
+		stream.consume(size)
+		if err := x.message(conv, side, KindFrame, frame, pkg.TramDecoder(frame, x.DecoderOptions), 0); err != nil {
+			return err
+		}
+	}
+}
+
+// resync drops the bytes of a stream up to the next AVL frame with a
+// matching CRC after a gap. It returns false while the stream holds no
+// such frame yet.
+func resync(stream *stream) bool {
+	for i := 0; i+8 <= len(stream.buffer); i++ {
+		data := stream.buffer[i:]
+		if binary.BigEndian.Uint32(data) != 0 {
+			continue
+		}
+		size, ok := frameSize(data)
+		if !ok {
+			continue
+		}
+		if len(data) < size {
+			stream.consume(i)
+			return false
+		}
+		if pkg.IsAVLCodec(data[8]) && tools.IsValidTram(data[8:size]) {
+			stream.consume(i)
+			stream.gap = false
+			return true
+		}
+	}
+	if len(stream.buffer) > 7 {
+		stream.consume(len(stream.buffer) - 7)
+	}
+	return false
+}
+
+// parseServer extracts the login response, ACKs and command frames of the
+// server side. Four zero bytes are an ACK of no records unless a command
+// frame with a matching CRC follows from them.
+func (x *extraction) parseServer(conv *conversation, atEnd bool) error {
+	side := 1 - conv.device
+	stream := &conv.streams[side]
+	for {
+		if stream.gap {
+			// Without a marker to find the next message, the bytes
+			// delivered after a gap are dropped.
+			stream.buffer, stream.gap = nil, false
+			conv.gaps[side], conv.expectResponse = true, false
+			return nil
+		}
+		data := stream.buffer
+		if conv.expectResponse {
+			if len(data) < 1 || conv.expectLogin {
+				return nil
+			}
+			conv.expectResponse = false
+			stream.consume(1)
+			if err := x.message(conv, side, KindLoginResponse, data[:1], nil, int64(data[0])); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(data) < 4 {
+			return nil
+		}
+		if binary.BigEndian.Uint32(data) == 0 {
+			size, ok := frameSize(data)
+			switch {
+			case len(data) < 8 && !atEnd:
+				return nil
+			case len(data) >= 8 && ok && size <= x.maxBuffered() && len(data) < size && !atEnd:
+				return nil
+			case len(data) >= 8 && ok && len(data) >= size && tools.IsValidTram(data[8:size]):
+				frame := data[:size]
+				stream.consume(size)
+				if err := x.message(conv, side, KindFrame, frame, pkg.TramDecoder(frame, x.DecoderOptions), 0); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		stream.consume(4)
+		if err := x.message(conv, side, KindAck, data[:4], nil, int64(binary.BigEndian.Uint32(data))); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *extraction) message(conv *conversation, side int, kind string, data []byte, decoded *decoder_domain.CodecDecoded, accepted int64) error {
+	message := Message{
+		Time:        conv.streams[side].time,
+		Transport:   "TCP",
+		Source:      conv.endpoints[side],
+		Destination: conv.endpoints[1-side],
+		FromDevice:  side == conv.device,
+		IMEI:        conv.imei,
+		Kind:        kind,
+		Data:        slices.Clone(data),
+		Decoded:     decoded,
+		Accepted:    accepted,
+		Gap:         conv.gaps[side],
+	}
+	conv.gaps[side] = false
+	x.stats.Messages++
+	return x.emit(message)
+}
+
+// datagram extracts a UDP frame or, when sent to a known device, an ACK.
+func (x *extraction) datagram(segment segment) error {
+	data := segment.payload
+	message := Message{
+		Time:        segment.time,
+		Transport:   "UDP",
+		Source:      segment.source,
+		Destination: segment.target,
+		Data:        slices.Clone(data),
+	}
+	if imei, ok := x.udpDevices[segment.target]; ok && len(data) == 7 {
+		ack, err := tools.DecodeUDPAck(data)
+		if err != nil {
+			return nil
+		}
+		message.IMEI, message.Kind, message.Accepted = imei, KindAck, ack.Accepted
+		x.stats.Messages++
+		return x.emit(message)
+	}
+
+	header, err := pkg.DecodeHeader(data)
+	if err != nil || header.HeaderUDP == nil || !isDigits([]byte(header.HeaderUDP.IMEI)) || len(data) <= header.HeaderUDP.LastByte {
+		return nil
+	}
+	_, toDevice := x.udpDevices[segment.target]
+	if !toDevice {
+		if _, known := x.udpDevices[segment.source]; !known {
+			x.stats.Conversations++
+		}
+		x.udpDevices[segment.source] = header.HeaderUDP.IMEI
+	}
+	message.FromDevice = !toDevice
+	message.IMEI = header.HeaderUDP.IMEI
+	message.Kind = KindFrame
+	message.Decoded = pkg.TramDecoder(data, x.DecoderOptions)
+	x.stats.Messages++
+	return x.emit(message)
+}
+
+// frameSize returns the size of the TCP frame starting with data, whose
+// first 8 bytes must be present: preamble, data length, codec and CRC.
+func frameSize(data []byte) (int, bool) {
+	if binary.BigEndian.Uint32(data) != 0 {
+		return 0, false
+	}
+	length := binary.BigEndian.Uint32(data[4:])
+	if length < 1 || length > 1<<24 {
+		return 0, false
+	}
+	return 8 + int(length) + 4, true
+}
+
+// isDigits reports whether data is a non-empty string of ASCII digits.
+func isDigits(data []byte) bool {
+	for _, b := range data {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return len(data) > 0
+}
@@ -0,0 +1,825 @@
+// Command teltonika_go decodes, encodes and checks Teltonika frames from
+// the command line.
+//
+// Usage:
+//
+//	teltonika_go decode [-format auto|hex|base64|raw] [-f file] [-lenient] [-names] [-dict file] [frame]
+//	teltonika_go inspect [-format auto|hex|base64|raw] [-f file] [-json] [-names] [-dict file] [frame]
+//	teltonika_go encode [-f file] [-format hex|base64] [document]
+//	teltonika_go crc [-format auto|hex|base64|raw] [-f file] [-verify] [data]
+//	teltonika_go login [-format hex|base64] imei
+//	teltonika_go simulate -addr host:port [-imei imei] [-protocol tcp|udp] [-codec 8|8E|16] [-route waypoints] [-io id=value[:size]]... [flags]
+//	teltonika_go replay [-speed x] [-failed] [-lenient] [-names] [-dict file] [-send host:port] journal
+//	teltonika_go pcap [-ports list] [-journal file] [-lenient] [-names] [-dict file] capture
+//	teltonika_go load -addr host:port [-devices n] [-codecs 8=3,8E=1] [-duration d] [-ramp d] [-reconnect-after n] [flags]
+//
+// Inputs are read from the argument, the -f file or standard input, in
+// that order. For example, to decode a frame copied from a device log:
+//
+//	teltonika_go decode 000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	capture "github.com/danieljvsa/teltonika-go/capture"
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	inspect_domain "github.com/danieljvsa/teltonika-go/internal/inspect"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	"github.com/danieljvsa/teltonika-go/iodict"
+	journal "github.com/danieljvsa/teltonika-go/journal"
+	loadgen "github.com/danieljvsa/teltonika-go/loadgen"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	simulator "github.com/danieljvsa/teltonika-go/simulator"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// command is a subcommand. run returns errUsage for invalid arguments.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, env *environment) error
+}
+
+// environment holds the standard streams of a command.
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var errUsage = errors.New("invalid arguments")
+
+func commands() []command {
+	return []command{
+		{name: "decode", summary: "decode a frame or login packet and print it as JSON", run: runDecode},
+		{name: "inspect", summary: "print the annotated field tree of a frame or login packet", run: runInspect},
+		{name: "encode", summary: "encode a JSON document into a frame", run: runEncode},
+		{name: "crc", summary: "compute the CRC of data or verify the CRC of a TCP frame", run: runCRC},
+		{name: "login", summary: "build the login packet of an IMEI", run: runLogin},
+		{name: "simulate", summary: "emulate a device streaming records to a server", run: runSimulate},
+		{name: "replay", summary: "decode the frames of a journal or send them to a server", run: runReplay},
+		{name: "pcap", summary: "extract Teltonika messages from a pcap or pcapng capture", run: runPcap},
+		{name: "load", summary: "load a server with many simulated devices and report throughput", run: runLoad},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand in args and returns the exit status: 0 on
+// success, 1 on failure and 2 for invalid arguments.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, cmd := range commands() {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], &environment{stdin: stdin, stdout: stdout, stderr: stderr})
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case err == errUsage:
+			return 2 // already reported by the flag set
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "teltonika_go %s: %v\n", cmd.name, err)
+			return 2
+		default:
+			fmt.Fprintf(stderr, "teltonika_go %s: %v\n", cmd.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "teltonika_go: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: teltonika_go <command> [flags] [input]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun teltonika_go <command> -h for the flags of a command.")
+}
+
+// newFlagSet returns a flag set that returns its errors instead of
+// exiting, and prints its usage to the standard error of env.
+func newFlagSet(name string, usageLine string, env *environment) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: teltonika_go %s %s\n", name, usageLine)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses args. The flag set prints its errors with the usage,
+// so they are returned as the bare errUsage.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
+
+// readInput returns the positional argument, else the contents of file
+// ("-" for standard input), else standard input.
+func readInput(flags *flag.FlagSet, file string, stdin io.Reader) ([]byte, error) {
+	switch {
+	case flags.NArg() > 1:
+		return nil, fmt.Errorf("%w: expected one input, got %d", errUsage, flags.NArg())
+	case flags.NArg() == 1:
+		return []byte(flags.Arg(0)), nil
+	case file != "" && file != "-":
+		return os.ReadFile(file)
+	}
+	return io.ReadAll(stdin)
+}
+
+// parseBytes turns input into bytes according to format: "hex", "base64",
+// "raw" or "auto". Auto detection takes text made only of hex digits as
+// hex, then tries base64, and otherwise uses the input as raw bytes.
+// Whitespace and a "0x" prefix are ignored in hex and base64 input.
+func parseBytes(input []byte, format string) ([]byte, error) {
+	if format == "raw" {
+		return input, nil
+	}
+	text := strings.Join(strings.Fields(string(input)), "")
+	switch format {
+	case "hex":
+		return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X"))
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	case "auto":
+		if data, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(text, "0x"), "0X")); err == nil && len(data) > 0 {
+			return data, nil
+		}
+		if data, err := base64.StdEncoding.DecodeString(text); err == nil && len(data) > 0 {
+			return data, nil
+		}
+		return input, nil
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", errUsage, format)
+}
+
+// formatBytes writes data as uppercase hex or base64, followed by a
+// newline.
+func formatBytes(w io.Writer, data []byte, format string) error {
+	switch format {
+	case "hex":
+		_, err := fmt.Fprintf(w, "%X\n", data)
+		return err
+	case "base64":
+		_, err := fmt.Fprintln(w, base64.StdEncoding.EncodeToString(data))
+		return err
+	}
+	return fmt.Errorf("%w: unknown output format %q", errUsage, format)
+}
+
+// isDigits reports whether text is a non-empty string of ASCII digits.
+func isDigits(text string) bool {
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return text != ""
+}
+
+func runDecode(args []string, env *environment) error {
+	flags := newFlagSet("decode", "[flags] [frame]", env)
+	format := flags.String("format", "auto", "input format: auto, hex, base64 or raw")
+	file := flags.String("f", "", "read the frame from `file` (\"-\" for standard input)")
+	lenient := flags.Bool("lenient", false, "keep the records decoded before a malformed one")
+	names := flags.Bool("names", false, "add the names of the built-in IO dictionary")
+	dictionary := flags.String("dict", "", "add IO names from a JSON or CSV dictionary `file`")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	input, err := readInput(flags, *file, env.stdin)
+	if err != nil {
+		return err
+	}
+	frame, err := parseBytes(input, *format)
+	if err != nil {
+		return err
+	}
+
+	decoded := pkg.LoginDecoder(frame)
+	if !pkg.IsLoginPacket(frame) {
+		decoded = pkg.TramDecoder(frame, pkg.DecoderOptions{Lenient: *lenient})
+	}
+	dict, err := loadDictionary(*names, *dictionary)
+	if err != nil {
+		return err
+	}
+	if dict != nil && decoded.Response != nil {
+		dict.Annotate(decoded.Response.Result.CodecData)
+	}
+
+	document, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(env.stdout, "%s\n", document); err != nil {
+		return err
+	}
+	return decoded.Error
+}
+
+// loadDictionary returns the built-in IO dictionary when builtin is set,
+// with the entries of file added when it is not empty, or nil when
+// neither asks for names.
+func loadDictionary(builtin bool, file string) (*iodict.Dictionary, error) {
+	if !builtin && file == "" {
+		return nil, nil
+	}
+	dict := iodict.New()
+	if builtin {
+		dict = iodict.Builtin()
+	}
+	if file != "" {
+		if err := dict.LoadFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return dict, nil
+}
+
+func runInspect(args []string, env *environment) error {
+	flags := newFlagSet("inspect", "[flags] [frame]", env)
+	format := flags.String("format", "auto", "input format: auto, hex, base64 or raw")
+	file := flags.String("f", "", "read the frame from `file` (\"-\" for standard input)")
+	asJSON := flags.Bool("json", false, "print the field tree as JSON")
+	names := flags.Bool("names", false, "resolve IO elements with the built-in IO dictionary")
+	dictionary := flags.String("dict", "", "resolve IO elements with a JSON or CSV dictionary `file`")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	input, err := readInput(flags, *file, env.stdin)
+	if err != nil {
+		return err
+	}
+	frame, err := parseBytes(input, *format)
+	if err != nil {
+		return err
+	}
+	dict, err := loadDictionary(*names, *dictionary)
+	if err != nil {
+		return err
+	}
+
+	root, inspectErr := pkg.Inspect(frame)
+	if dict != nil {
+		root.Walk(func(field *inspect_domain.Field, depth int) {
+			if field.IO == nil {
+				return
+			}
+			if value, err := dict.Resolve(*field.IO); err == nil {
+				field.IO.Name = value.Name
+				field.Value = value.String()
+			}
+		})
+	}
+	if *asJSON {
+		document, err := json.MarshalIndent(root, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(env.stdout, "%s\n", document); err != nil {
+			return err
+		}
+	} else if err := writeFieldTree(env.stdout, root); err != nil {
+		return err
+	}
+	return inspectErr
+}
+
+// fieldTreeBytes is the number of raw bytes shown on each line of the
+// field tree; longer fields end with "..".
+const fieldTreeBytes = 16
+
+// writeFieldTree prints one line per field: its offset, its length, its
+// first raw bytes and its name and value, indented by depth. Fields with
+// a problem end with it after "!!".
+func writeFieldTree(w io.Writer, root *inspect_domain.Field) error {
+	var err error
+	fmt.Fprintf(w, "%6s %5s  %-*s  %s\n", "offset", "len", 2*fieldTreeBytes+2, "bytes", "field")
+	root.Walk(func(field *inspect_domain.Field, depth int) {
+		raw := fmt.Sprintf("%X", field.Raw[:min(len(field.Raw), fieldTreeBytes)])
+		if len(field.Raw) > fieldTreeBytes {
+			raw += ".."
+		}
+		line := strings.Repeat("  ", depth) + field.Name
+		if field.Value != "" {
+			line += ": " + field.Value
+		}
+		if field.Error != nil {
+			line += "  !! " + field.Error.Error()
+		}
+		if _, writeErr := fmt.Fprintf(w, "%6d %5d  %-*s  %s\n", field.Offset, field.Length, 2*fieldTreeBytes+2, raw, line); err == nil {
+			err = writeErr
+		}
+	})
+	return err
+}
+
+func runEncode(args []string, env *environment) error {
+	flags := newFlagSet("encode", "[flags] [document]", env)
+	file := flags.String("f", "", "read the JSON document from `file` (\"-\" for standard input)")
+	format := flags.String("format", "hex", "output format: hex or base64")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	document, err := readInput(flags, *file, env.stdin)
+	if err != nil {
+		return err
+	}
+	frame, err := pkg.TramEncoderJSON(document)
+	if err != nil {
+		return err
+	}
+	return formatBytes(env.stdout, frame, *format)
+}
+
+func runCRC(args []string, env *environment) error {
+	flags := newFlagSet("crc", "[flags] [data]", env)
+	format := flags.String("format", "auto", "input format: auto, hex, base64 or raw")
+	file := flags.String("f", "", "read the data from `file` (\"-\" for standard input)")
+	verify := flags.Bool("verify", false, "verify the CRC at the end of a TCP frame")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	input, err := readInput(flags, *file, env.stdin)
+	if err != nil {
+		return err
+	}
+	data, err := parseBytes(input, *format)
+	if err != nil {
+		return err
+	}
+	if !*verify {
+		_, err := fmt.Fprintf(env.stdout, "%04X\n", tools.Crc16IBM(data))
+		return err
+	}
+
+	// The CRC of a TCP frame covers the bytes between the 8-byte header
+	// and the 4-byte CRC field.
+	if len(data) < 8+1+4 {
+		return fmt.Errorf("frame too short for a CRC: %d bytes", len(data))
+	}
+	expected := binary.BigEndian.Uint32(data[len(data)-4:])
+	computed := uint32(tools.Crc16IBM(data[8 : len(data)-4]))
+	if expected != computed {
+		return fmt.Errorf("%w: frame carries %04X, computed %04X", pkg.ErrCRCMismatch, expected, computed)
+	}
+	_, err = fmt.Fprintf(env.stdout, "CRC %04X is valid\n", computed)
+	return err
+}
+
+func runLogin(args []string, env *environment) error {
+	flags := newFlagSet("login", "[flags] imei", env)
+	format := flags.String("format", "hex", "output format: hex or base64")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: expected one IMEI", errUsage)
+	}
+	imei := flags.Arg(0)
+	if !isDigits(imei) {
+		return fmt.Errorf("IMEI %q must be made of digits", imei)
+	}
+	packet, err := tools.EncodeLogin(imei)
+	if err != nil {
+		return err
+	}
+	return formatBytes(env.stdout, packet, *format)
+}
+
+// listFlag collects the values of a flag given several times.
+type listFlag []string
+
+func (list *listFlag) String() string {
+	return strings.Join(*list, " ")
+}
+
+func (list *listFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+func runSimulate(args []string, env *environment) error {
+	flags := newFlagSet("simulate", "-addr host:port [flags]", env)
+	addr := flags.String("addr", "", "server `address`")
+	imei := flags.String("imei", "356307042441013", "device IMEI")
+	protocol := flags.String("protocol", "tcp", "transport: tcp or udp")
+	codec := flags.String("codec", "8", "AVL codec: 8, 8E or 16")
+	route := flags.String("route", "", "`waypoints` as lat,lon[,alt] separated by semicolons")
+	records := flags.Int("records", 1, "records per frame")
+	interval := flags.Duration("interval", 10*time.Second, "time between frames")
+	frames := flags.Int("frames", 0, "frames to send, 0 until interrupted")
+	timeout := flags.Duration("timeout", 10*time.Second, "ACK timeout")
+	retries := flags.Int("retries", 0, "UDP retries of an unacknowledged packet")
+	verbose := flags.Bool("v", false, "print every ACK")
+	var ioFlags, responseFlags listFlag
+	flags.Var(&ioFlags, "io", "IO element of every record as `id=value[:size]`, repeatable")
+	flags.Var(&responseFlags, "respond", "canned answer to a command as `command=response`, repeatable")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *addr == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return errUsage
+	}
+	if err := checkRecords(*records); err != nil {
+		return err
+	}
+
+	codecID, err := parseCodec(*codec)
+	if err != nil {
+		return err
+	}
+	waypoints, err := simulator.ParseRoute(*route)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	ios := make([]io_domain.IOData, 0, len(ioFlags))
+	for _, value := range ioFlags {
+		element, err := parseIO(value)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		ios = append(ios, element)
+	}
+	var responses map[string]string
+	if len(responseFlags) > 0 {
+		responses = maps.Clone(simulator.DefaultResponses)
+		for _, value := range responseFlags {
+			command, response, ok := strings.Cut(value, "=")
+			if !ok {
+				return fmt.Errorf("%w: response %q is not command=response", errUsage, value)
+			}
+			responses[command] = response
+		}
+	}
+
+	device := &simulator.Device{
+		IMEI:            *imei,
+		Addr:            *addr,
+		Protocol:        *protocol,
+		CodecID:         codecID,
+		Route:           waypoints,
+		RecordsPerFrame: *records,
+		Interval:        *interval,
+		Frames:          *frames,
+		IOs:             ios,
+		Responses:       responses,
+		AckTimeout:      *timeout,
+		Retries:         *retries,
+		ErrorLog:        log.New(env.stderr, "", log.LstdFlags),
+	}
+	if *verbose {
+		device.OnAck = func(records int, accepted int64, latency time.Duration) {
+			fmt.Fprintf(env.stdout, "ACK %d/%d records in %v\n", accepted, records, latency.Round(time.Microsecond))
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = device.Run(ctx)
+	stats := device.Stats()
+	fmt.Fprintf(env.stdout, "frames %d, records %d, acknowledged %d, commands %d\n", stats.Frames, stats.Records, stats.Acknowledged, stats.Commands)
+	return err
+}
+
+// replayedFrame is a journal entry with its decoded frame, as printed by
+// the replay command.
+type replayedFrame struct {
+	Entry   journal.Entry                `json:"entry"`
+	Decoded *decoder_domain.CodecDecoded `json:"decoded"`
+}
+
+func runReplay(args []string, env *environment) error {
+	flags := newFlagSet("replay", "[flags] journal", env)
+	speed := flags.Float64("speed", 0, "pace relative to the recording: 1 for real time, 0 for no waiting")
+	failed := flags.Bool("failed", false, "print only the frames that fail to decode")
+	lenient := flags.Bool("lenient", false, "keep the records decoded before a malformed one")
+	names := flags.Bool("names", false, "add the names of the built-in IO dictionary")
+	dictionary := flags.String("dict", "", "add IO names from a JSON or CSV dictionary `file`")
+	addr := flags.String("send", "", "send the frames to the server at `address` instead of decoding them")
+	timeout := flags.Duration("timeout", 10*time.Second, "ACK timeout when sending")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	dict, err := loadDictionary(*names, *dictionary)
+	if err != nil {
+		return err
+	}
+	input := env.stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	reader, err := journal.NewReader(input)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	replayer := &journal.Replayer{Speed: *speed, DecoderOptions: pkg.DecoderOptions{Lenient: *lenient}, AckTimeout: *timeout}
+	if *addr != "" {
+		sent, err := replayer.Send(ctx, reader, *addr)
+		fmt.Fprintf(env.stdout, "sent %d frames\n", sent)
+		return err
+	}
+
+	var frames, failures int
+	encoder := json.NewEncoder(env.stdout)
+	err = replayer.Decode(ctx, reader, func(entry journal.Entry, decoded *decoder_domain.CodecDecoded) error {
+		frames++
+		if decoded.Error != nil {
+			failures++
+		} else if *failed {
+			return nil
+		}
+		if dict != nil && decoded.Response != nil {
+			dict.Annotate(decoded.Response.Result.CodecData)
+		}
+		return encoder.Encode(replayedFrame{Entry: entry, Decoded: decoded})
+	})
+	if err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d frames failed to decode", failures, frames)
+	}
+	return nil
+}
+
+func runPcap(args []string, env *environment) error {
+	flags := newFlagSet("pcap", "[flags] capture", env)
+	ports := flags.String("ports", "", "only traffic to or from these comma-separated `ports`")
+	output := flags.String("journal", "", "append the device frames to the journal `file` instead of printing JSON")
+	lenient := flags.Bool("lenient", false, "keep the records decoded before a malformed one")
+	names := flags.Bool("names", false, "add the names of the built-in IO dictionary")
+	dictionary := flags.String("dict", "", "add IO names from a JSON or CSV dictionary `file`")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errUsage
+	}
+	extractor := &capture.Extractor{DecoderOptions: pkg.DecoderOptions{Lenient: *lenient}}
+	if *ports != "" {
+		for _, text := range strings.Split(*ports, ",") {
+			port, err := strconv.ParseUint(strings.TrimSpace(text), 10, 16)
+			if err != nil {
+				return fmt.Errorf("%w: invalid port %q", errUsage, text)
+			}
+			extractor.Ports = append(extractor.Ports, uint16(port))
+		}
+	}
+	dict, err := loadDictionary(*names, *dictionary)
+	if err != nil {
+		return err
+	}
+
+	input := env.stdin
+	if path := flags.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+	reader, err := capture.NewReader(input)
+	if err != nil {
+		return err
+	}
+
+	emit := func(message capture.Message) error {
+		if dict != nil && message.Decoded != nil && message.Decoded.Response != nil {
+			dict.Annotate(message.Decoded.Response.Result.CodecData)
+		}
+		return json.NewEncoder(env.stdout).Encode(message)
+	}
+	if *output != "" {
+		writer, err := journal.OpenFile(*output)
+		if err != nil {
+			return err
+		}
+		defer writer.Close()
+		emit = func(message capture.Message) error {
+			if entry, ok := message.JournalEntry(); ok {
+				return writer.Write(entry)
+			}
+			return nil
+		}
+	}
+	stats, err := extractor.Extract(reader, emit)
+	fmt.Fprintf(env.stderr, "packets %d, skipped %d, conversations %d, messages %d\n", stats.Packets, stats.Skipped, stats.Conversations, stats.Messages)
+	return err
+}
+
+func runLoad(args []string, env *environment) error {
+	flags := newFlagSet("load", "-addr host:port [flags]", env)
+	addr := flags.String("addr", "", "server `address`")
+	protocol := flags.String("protocol", "tcp", "transport: tcp or udp")
+	devices := flags.Int("devices", 100, "simulated devices")
+	imeiBase := flags.Int64("imei-base", loadgen.DefaultIMEIBase, "IMEI of the first device, the others follow")
+	codecs := flags.String("codecs", "8", "codec mix as `codec[=weight],...`, e.g. 8=3,8E=1,16=1")
+	route := flags.String("route", "", "`waypoints` as lat,lon[,alt] separated by semicolons")
+	records := flags.Int("records", 1, "records per frame")
+	interval := flags.Duration("interval", 10*time.Second, "time between frames of a device")
+	ramp := flags.Duration("ramp", 0, "time over which the devices connect")
+	duration := flags.Duration("duration", 0, "length of the run, 0 until interrupted")
+	reconnectAfter := flags.Int("reconnect-after", 0, "frames per connection, 0 to keep connections open")
+	reconnect := flags.Bool("reconnect", true, "reconnect devices whose connection fails")
+	reconnectDelay := flags.Duration("reconnect-delay", time.Second, "wait before a device connects again, 1s after a failure if 0")
+	timeout := flags.Duration("timeout", 10*time.Second, "ACK timeout")
+	every := flags.Duration("report", 5*time.Second, "progress report interval, 0 for none")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *addr == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return errUsage
+	}
+	if err := checkRecords(*records); err != nil {
+		return err
+	}
+
+	shares, err := parseCodecMix(*codecs)
+	if err != nil {
+		return err
+	}
+	waypoints, err := simulator.ParseRoute(*route)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	generator := &loadgen.Generator{
+		Addr:            *addr,
+		Protocol:        *protocol,
+		Devices:         *devices,
+		IMEIBase:        *imeiBase,
+		RecordsPerFrame: *records,
+		Interval:        *interval,
+		Codecs:          shares,
+		Route:           waypoints,
+		Ramp:            *ramp,
+		Duration:        *duration,
+		ReconnectAfter:  *reconnectAfter,
+		Reconnect:       *reconnect,
+		ReconnectDelay:  *reconnectDelay,
+		AckTimeout:      *timeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	done := make(chan struct{})
+	if *every > 0 {
+		go func() {
+			ticker := time.NewTicker(*every)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					report := generator.Snapshot()
+					fmt.Fprintf(env.stdout, "%v: active %d, frames %d (%.1f/s), acknowledged %d, p99 %v, errors %d\n",
+						report.Elapsed.Round(time.Second), report.Active, report.Frames, report.FramesPerSecond,
+						report.Acknowledged, report.Latency.P99, errorTotal(report.Errors))
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	report, err := generator.Run(ctx)
+	close(done)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	fmt.Fprintln(env.stdout, report)
+	return nil
+}
+
+// parseCodecMix parses a codec mix written as codec[=weight] items
+// separated by commas; a codec without a weight weighs 1.
+func parseCodecMix(text string) ([]loadgen.CodecShare, error) {
+	var shares []loadgen.CodecShare
+	for _, item := range strings.Split(text, ",") {
+		name, weightText, hasWeight := strings.Cut(strings.TrimSpace(item), "=")
+		codecID, err := parseCodec(name)
+		if err != nil {
+			return nil, err
+		}
+		weight := 1
+		if hasWeight {
+			if weight, err = strconv.Atoi(weightText); err != nil || weight < 0 {
+				return nil, fmt.Errorf("%w: invalid weight in codec mix %q", errUsage, item)
+			}
+		}
+		shares = append(shares, loadgen.CodecShare{CodecID: codecID, Weight: weight})
+	}
+	return shares, nil
+}
+
+func errorTotal(counts map[string]int64) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// checkRecords validates the -records flag of the simulate and load
+// commands.
+func checkRecords(records int) error {
+	if records < 1 || records > simulator.MaxRecordsPerFrame {
+		return fmt.Errorf("%w: -records must be between 1 and %d, got %d", errUsage, simulator.MaxRecordsPerFrame, records)
+	}
+	return nil
+}
+
+// parseCodec parses an AVL codec name: 8, 8E or 16, with an optional
+// "0x" prefix.
+func parseCodec(name string) (byte, error) {
+	switch strings.TrimPrefix(strings.ToUpper(name), "0X") {
+	case "8", "08":
+		return 0x08, nil
+	case "8E":
+		return 0x8E, nil
+	case "16", "10":
+		return 0x10, nil
+	}
+	return 0, fmt.Errorf("%w: unknown AVL codec %q", errUsage, name)
+}
+
+// parseIO parses an IO element written as id=value[:size]. The value is
+// an unsigned integer, decimal or with a "0x" prefix; without a size it
+// takes the fewest bytes of 1, 2, 4 or 8 that hold it.
+func parseIO(text string) (io_domain.IOData, error) {
+	id, value, ok := strings.Cut(text, "=")
+	if !ok {
+		return io_domain.IOData{}, fmt.Errorf("IO %q is not id=value[:size]", text)
+	}
+	value, sizeText, hasSize := strings.Cut(value, ":")
+	ioID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return io_domain.IOData{}, fmt.Errorf("IO %q: invalid ID", text)
+	}
+	number, err := strconv.ParseUint(value, 0, 64)
+	if err != nil {
+		return io_domain.IOData{}, fmt.Errorf("IO %q: invalid value", text)
+	}
+	size := 8
+	if hasSize {
+		if size, err = strconv.Atoi(sizeText); err != nil {
+			return io_domain.IOData{}, fmt.Errorf("IO %q: invalid size", text)
+		}
+	} else {
+		for _, fit := range []int{1, 2, 4} {
+			if number < 1<<(8*fit) {
+				size = fit
+				break
+			}
+		}
+	}
+	return tools.NewIOUint(ioID, size, number)
+}
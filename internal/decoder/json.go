@@ -0,0 +1,268 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	header "github.com/danieljvsa/teltonika-go/internal/header"
+	io "github.com/danieljvsa/teltonika-go/internal/io"
+	tool "github.com/danieljvsa/teltonika-go/internal/tool"
+)
+
+// JSONSchemaVersion is the version of the JSON schema of decoded frames,
+// written as "schema" by CodecDecoded and CodecHeaderResponse. It changes
+// when a field is renamed, removed or changes meaning; new optional
+// fields keep the version.
+const JSONSchemaVersion = 1
+
+// checkSchema rejects documents written for a newer schema.
+func checkSchema(schema int) error {
+	if schema > JSONSchemaVersion {
+		return fmt.Errorf("JSON schema version %d is newer than supported version %d", schema, JSONSchemaVersion)
+	}
+	return nil
+}
+
+type recordJSON struct {
+	Timestamp          *time.Time              `json:"timestamp,omitempty"`
+	Priority           *int64                  `json:"priority,omitempty"`
+	GPS                *tool.GPSData           `json:"gps,omitempty"`
+	EventIO            *int64                  `json:"event_io,omitempty"`
+	GenerationType     *GenerationType         `json:"generation_type,omitempty"`
+	GenerationTypeName string                  `json:"generation_type_name,omitempty"`
+	NumberOfIOs        *int64                  `json:"number_of_ios,omitempty"`
+	IOs                *[]io.IOData            `json:"ios,omitempty"`
+	CommandResponses   *[]tool.CommandResponse `json:"command_responses,omitempty"`
+	CommandType        *string                 `json:"command_type,omitempty"`
+	CodecID            *int                    `json:"codec_id,omitempty"`
+	RawData            *string                 `json:"raw_data,omitempty"`
+	Attributes         *map[string]any         `json:"attributes,omitempty"`
+}
+
+// MarshalJSON encodes the fields the record has, omitting absent ones.
+// The timestamp is RFC 3339, raw data is hex and the generation type is
+// its byte value, followed by its name.
+func (record Record) MarshalJSON() ([]byte, error) {
+	value := recordJSON{
+		Timestamp:        record.Timestamp,
+		Priority:         record.Priority,
+		GPS:              record.GPSData,
+		EventIO:          record.EventIO,
+		GenerationType:   record.GenerationType,
+		NumberOfIOs:      record.NumberOfIOs,
+		IOs:              record.IOs,
+		CommandResponses: record.CommandResponses,
+		CommandType:      record.CommandType,
+		CodecID:          record.CodecID,
+		Attributes:       record.Attributes,
+	}
+	if record.GenerationType != nil {
+		value.GenerationTypeName = record.GenerationType.String()
+	}
+	if record.RawData != nil {
+		raw := hex.EncodeToString(*record.RawData)
+		value.RawData = &raw
+	}
+	return json.Marshal(value)
+}
+
+func (record *Record) UnmarshalJSON(data []byte) error {
+	var value recordJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*record = Record{
+		Timestamp:        value.Timestamp,
+		Priority:         value.Priority,
+		GPSData:          value.GPS,
+		EventIO:          value.EventIO,
+		NumberOfIOs:      value.NumberOfIOs,
+		IOs:              value.IOs,
+		CommandResponses: value.CommandResponses,
+		CommandType:      value.CommandType,
+		GenerationType:   value.GenerationType,
+		CodecID:          value.CodecID,
+		Attributes:       value.Attributes,
+	}
+	if value.RawData != nil {
+		raw, err := hex.DecodeString(*value.RawData)
+		if err != nil {
+			return fmt.Errorf("raw_data: %w", err)
+		}
+		record.RawData = &raw
+	}
+	return nil
+}
+
+type diagnosticsJSON struct {
+	Complete      bool   `json:"complete"`
+	FailureOffset int    `json:"failure_offset"`
+	CountMatched  bool   `json:"count_matched"`
+	LengthMatched bool   `json:"length_matched"`
+	CRCChecked    bool   `json:"crc_checked"`
+	CRCMatched    bool   `json:"crc_matched"`
+	Trailing      string `json:"trailing,omitempty"`
+}
+
+func (diagnostics Diagnostics) MarshalJSON() ([]byte, error) {
+	return json.Marshal(diagnosticsJSON{
+		Complete:      diagnostics.Complete,
+		FailureOffset: diagnostics.FailureOffset,
+		CountMatched:  diagnostics.CountMatched,
+		LengthMatched: diagnostics.LengthMatched,
+		CRCChecked:    diagnostics.CRCChecked,
+		CRCMatched:    diagnostics.CRCMatched,
+		Trailing:      hex.EncodeToString(diagnostics.Trailing),
+	})
+}
+
+func (diagnostics *Diagnostics) UnmarshalJSON(data []byte) error {
+	var value diagnosticsJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	var trailing []byte
+	if value.Trailing != "" {
+		var err error
+		if trailing, err = hex.DecodeString(value.Trailing); err != nil {
+			return fmt.Errorf("invalid diagnostics trailing hex: %w", err)
+		}
+	}
+	*diagnostics = Diagnostics{
+		Complete:      value.Complete,
+		FailureOffset: value.FailureOffset,
+		CountMatched:  value.CountMatched,
+		LengthMatched: value.LengthMatched,
+		CRCChecked:    value.CRCChecked,
+		CRCMatched:    value.CRCMatched,
+		Trailing:      trailing,
+	}
+	return nil
+}
+
+type codecDataJSON struct {
+	CodecID         string       `json:"codec_id,omitempty"`
+	NumberOfRecords int64        `json:"number_of_records"`
+	Records         []Record     `json:"records"`
+	Diagnostics     *Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// MarshalJSON encodes the codec ID as two hex digits, such as "8E".
+func (codecData CodecData) MarshalJSON() ([]byte, error) {
+	value := codecDataJSON{
+		NumberOfRecords: codecData.NumberOfRecords,
+		Records:         codecData.Records,
+		Diagnostics:     codecData.Diagnostics,
+	}
+	if value.Records == nil {
+		value.Records = []Record{}
+	}
+	if codecData.CodecID != 0 {
+		value.CodecID = fmt.Sprintf("%02X", codecData.CodecID)
+	}
+	return json.Marshal(value)
+}
+
+func (codecData *CodecData) UnmarshalJSON(data []byte) error {
+	var value codecDataJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*codecData = CodecData{
+		NumberOfRecords: value.NumberOfRecords,
+		Records:         value.Records,
+		Diagnostics:     value.Diagnostics,
+	}
+	if value.CodecID != "" {
+		codecID, err := strconv.ParseUint(value.CodecID, 16, 8)
+		if err != nil {
+			return fmt.Errorf("codec_id %q: %w", value.CodecID, err)
+		}
+		codecData.CodecID = byte(codecID)
+	}
+	return nil
+}
+
+type codecHeaderResponseJSON struct {
+	Schema    int                `json:"schema"`
+	Header    *header.HeaderData `json:"header,omitempty"`
+	CodecData *CodecData         `json:"codec_data,omitempty"`
+	Length    *int64             `json:"length,omitempty"`
+	IMEI      *string            `json:"imei,omitempty"`
+}
+
+func newCodecHeaderResponseJSON(response CodecHeaderResponse) codecHeaderResponseJSON {
+	return codecHeaderResponseJSON{
+		Schema:    JSONSchemaVersion,
+		Header:    response.HeaderData,
+		CodecData: response.CodecData,
+		Length:    response.Length,
+		IMEI:      response.IMEI,
+	}
+}
+
+func (value codecHeaderResponseJSON) response() CodecHeaderResponse {
+	return CodecHeaderResponse{CodecData: value.CodecData, HeaderData: value.Header, Length: value.Length, IMEI: value.IMEI}
+}
+
+// MarshalJSON encodes a decoded frame or login packet with the schema
+// version. The result can be unmarshaled and passed to TramEncoder.
+func (response CodecHeaderResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newCodecHeaderResponseJSON(response))
+}
+
+func (response *CodecHeaderResponse) UnmarshalJSON(data []byte) error {
+	var value codecHeaderResponseJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if err := checkSchema(value.Schema); err != nil {
+		return err
+	}
+	*response = value.response()
+	return nil
+}
+
+type codecDecodedJSON struct {
+	codecHeaderResponseJSON
+	Type  string `json:"type,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the response fields next to its type ("Tram" or
+// "Login") and the error message, if any.
+func (decoded CodecDecoded) MarshalJSON() ([]byte, error) {
+	value := codecDecodedJSON{codecHeaderResponseJSON: codecHeaderResponseJSON{Schema: JSONSchemaVersion}}
+	if decoded.Response != nil {
+		value.codecHeaderResponseJSON = newCodecHeaderResponseJSON(decoded.Response.Result)
+		value.Type = decoded.Response.Type
+	}
+	if decoded.Error != nil {
+		value.Error = decoded.Error.Error()
+	}
+	return json.Marshal(value)
+}
+
+// UnmarshalJSON restores the response and, as a plain error carrying the
+// message, the error.
+func (decoded *CodecDecoded) UnmarshalJSON(data []byte) error {
+	var value codecDecodedJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if err := checkSchema(value.Schema); err != nil {
+		return err
+	}
+	*decoded = CodecDecoded{}
+	if value.Type != "" || value.Header != nil || value.CodecData != nil || value.IMEI != nil {
+		decoded.Response = &ResponseType{Type: value.Type, Result: value.response()}
+	}
+	if value.Error != "" {
+		decoded.Error = errors.New(value.Error)
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ type Record struct {
 	IOs              *[]io.IOData
 	CommandResponses *[]tool.CommandResponse
 	CommandType      *string
+	GenerationType   *GenerationType // Codec 16 only
 
 	// Codec-specific metadata
 	CodecID    *int //will be nil for some codecs
@@ -26,9 +27,54 @@ type Record struct {
 	Attributes *map[string]any // catch-all for extra codec-specific values
 }
 
+// GenerationType is the Codec 16 byte telling which event generated a
+// record. Values without a name are kept as received.
+type GenerationType byte
+
+const (
+	GenerationOnExit GenerationType = iota
+	GenerationOnEntrance
+	GenerationOnBoth
+	GenerationReserved
+	GenerationHysteresis
+	GenerationOnChange
+	GenerationEventual
+	GenerationPeriodical
+)
+
+var generationTypeNames = [...]string{
+	"On Exit", "On Entrance", "On Both", "Reserved",
+	"Hysteresis", "On Change", "Eventual", "Periodical",
+}
+
+// String returns the Teltonika name of the generation type, or "Unknown
+// generation type".
+func (generationType GenerationType) String() string {
+	if int(generationType) < len(generationTypeNames) {
+		return generationTypeNames[generationType]
+	}
+	return "Unknown generation type"
+}
+
 type CodecData struct {
+	CodecID         byte // set by TramDecoder, selects the codec in TramEncoder
 	NumberOfRecords int64
 	Records         []Record
+	Diagnostics     *Diagnostics // set by the AVL decoders in lenient mode
+}
+
+// Diagnostics describes how far a lenient decode got. When decoding stops
+// at a malformed record, Records holds the records decoded before it and
+// Trailing the unparsed bytes from the start of the malformed record to
+// the end of the data.
+type Diagnostics struct {
+	Complete      bool   // every announced record was decoded
+	FailureOffset int    // byte offset of the failure, -1 if Complete
+	CountMatched  bool   // the repeated record count matched the first one
+	LengthMatched bool   // false if TramDecoder found a header length that disagrees with the frame
+	CRCChecked    bool   // the frame carries a CRC (TCP)
+	CRCMatched    bool   // the CRC was checked and matched
+	Trailing      []byte // unparsed bytes, nil if Complete
 }
 
 type CodecDecoded struct {
@@ -0,0 +1,84 @@
+package header
+
+import "encoding/json"
+
+type headerDataTCPJSON struct {
+	Preamble   string `json:"preamble"`
+	DataLength int64  `json:"data_length"`
+}
+
+type headerDataUDPJSON struct {
+	Length      int64  `json:"length"`
+	PacketID    int64  `json:"packet_id"`
+	AVLPacketID int64  `json:"avl_packet_id"`
+	IMEI        string `json:"imei"`
+}
+
+type headerDataJSON struct {
+	Protocol string         `json:"protocol"`
+	TCP      *HeaderDataTCP `json:"tcp,omitempty"`
+	UDP      *HeaderDataUDP `json:"udp,omitempty"`
+}
+
+// MarshalJSON encodes the TCP header fields. LastByte is a decoding
+// position and is not part of the JSON schema.
+func (header HeaderDataTCP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(headerDataTCPJSON{Preamble: header.Header, DataLength: header.DataLength})
+}
+
+func (header *HeaderDataTCP) UnmarshalJSON(data []byte) error {
+	var value headerDataTCPJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*header = HeaderDataTCP{Header: value.Preamble, DataLength: value.DataLength, LastByte: 8}
+	return nil
+}
+
+// MarshalJSON encodes the UDP header fields. The IMEI length follows from
+// the IMEI and LastByte is a decoding position, so neither is part of the
+// JSON schema.
+func (header HeaderDataUDP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(headerDataUDPJSON{
+		Length:      header.Length,
+		PacketID:    header.PacketID,
+		AVLPacketID: header.AVLPacketID,
+		IMEI:        header.IMEI,
+	})
+}
+
+func (header *HeaderDataUDP) UnmarshalJSON(data []byte) error {
+	var value headerDataUDPJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*header = HeaderDataUDP{
+		Length:      value.Length,
+		PacketID:    value.PacketID,
+		AVLPacketID: value.AVLPacketID,
+		IMEILength:  int64(len(value.IMEI)),
+		IMEI:        value.IMEI,
+		LastByte:    8 + len(value.IMEI),
+	}
+	return nil
+}
+
+// MarshalJSON encodes the protocol and the header of that protocol.
+func (header HeaderData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(headerDataJSON{Protocol: header.Protocol, TCP: header.HeaderTCP, UDP: header.HeaderUDP})
+}
+
+func (header *HeaderData) UnmarshalJSON(data []byte) error {
+	var value headerDataJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*header = HeaderData{HeaderTCP: value.TCP, HeaderUDP: value.UDP, Protocol: value.Protocol}
+	switch {
+	case value.TCP != nil:
+		header.LastByte = value.TCP.LastByte
+	case value.UDP != nil:
+		header.LastByte = value.UDP.LastByte
+	}
+	return nil
+}
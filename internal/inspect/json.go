@@ -0,0 +1,37 @@
+package inspect
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	io "github.com/danieljvsa/teltonika-go/internal/io"
+)
+
+type fieldJSON struct {
+	Name     string     `json:"name"`
+	Offset   int        `json:"offset"`
+	Length   int        `json:"length"`
+	Raw      string     `json:"raw"`
+	Value    string     `json:"value,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	IO       *io.IOData `json:"io,omitempty"`
+	Children []*Field   `json:"children,omitempty"`
+}
+
+// MarshalJSON encodes the field with its raw bytes as lowercase hex and
+// its error as text.
+func (field *Field) MarshalJSON() ([]byte, error) {
+	value := fieldJSON{
+		Name:     field.Name,
+		Offset:   field.Offset,
+		Length:   field.Length,
+		Raw:      hex.EncodeToString(field.Raw),
+		Value:    field.Value,
+		IO:       field.IO,
+		Children: field.Children,
+	}
+	if field.Error != nil {
+		value.Error = field.Error.Error()
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,32 @@
+package inspect
+
+import (
+	io "github.com/danieljvsa/teltonika-go/internal/io"
+)
+
+// Field is one element of a frame dissected by Inspect: the frame itself,
+// a header field, a record, a GPS coordinate or an IO element. Groups such
+// as records hold their elements in Children.
+type Field struct {
+	Name     string
+	Offset   int        // from the start of the frame
+	Length   int        // number of bytes, including those of Children
+	Raw      []byte     // the Length bytes at Offset
+	Value    string     // interpreted value, empty for groups without one
+	Error    error      // problem found in this field, nil if none
+	IO       *io.IOData // set on IO elements, to resolve them with a dictionary
+	Children []*Field
+}
+
+// Walk calls visit for field and every field below it, depth first, with
+// the depth of each field below field.
+func (field *Field) Walk(visit func(field *Field, depth int)) {
+	field.walk(visit, 0)
+}
+
+func (field *Field) walk(visit func(field *Field, depth int), depth int) {
+	visit(field, depth)
+	for _, child := range field.Children {
+		child.walk(visit, depth+1)
+	}
+}
@@ -0,0 +1,31 @@
+package io
+
+import (
+	"encoding/hex"
+	"encoding/json"
+)
+
+type ioDataJSON struct {
+	ID    int64  `json:"id"`
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+}
+
+// MarshalJSON encodes the element as its AVL ID, its value as lowercase
+// hex and, when set, its dictionary name.
+func (io IOData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ioDataJSON{ID: io.IO, Value: io.Value, Name: io.Name})
+}
+
+// UnmarshalJSON decodes an element, rejecting a value that is not hex.
+func (io *IOData) UnmarshalJSON(data []byte) error {
+	var value ioDataJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if _, err := hex.DecodeString(value.Value); err != nil {
+		return err
+	}
+	*io = IOData{IO: value.ID, Value: value.Value, Name: value.Name}
+	return nil
+}
@@ -1,8 +1,106 @@
 package io
 
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// IOData is one AVL IO element. Value holds the value bytes as a hex
+// string; Bytes and the typed accessors read it, so editing Value is
+// enough to change the element.
 type IOData struct {
 	IO    int64
 	Value string
+	Name  string // AVL ID name set by iodict.Dictionary.Annotate, empty otherwise
+}
+
+// Scaler turns a raw integer into a physical value. iodict.Entry
+// implements it with the multiplier and signedness of an AVL ID.
+type Scaler interface {
+	Scale() float64
+	IsSigned() bool
+}
+
+// Bytes returns the value bytes, or nil if Value is not valid hex.
+func (io IOData) Bytes() []byte {
+	data, err := hex.DecodeString(io.Value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Uint64 returns the value as a big-endian unsigned integer. Values longer
+// than 8 bytes, such as Codec 8E NX elements, keep their last 8 bytes.
+func (io IOData) Uint64() uint64 {
+	value, _ := io.integer()
+	return value
+}
+
+// integer parses Value as a big-endian integer without allocating. It
+// returns the last 8 bytes of the value and the width of the element, at
+// most 8 bytes; both are zero if Value is not valid hex.
+func (io IOData) integer() (uint64, int) {
+	if len(io.Value)%2 != 0 {
+		return 0, 0
+	}
+	var value uint64
+	for i := 0; i < len(io.Value); i++ {
+		digit, ok := hexDigit(io.Value[i])
+		if !ok {
+			return 0, 0
+		}
+		value = value<<4 | uint64(digit)
+	}
+	return value, min(len(io.Value)/2, 8)
+}
+
+func hexDigit(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// Int64 returns the value as a two's complement integer of the width of
+// the element: "FF" is -1 and "00FF" is 255.
+func (io IOData) Int64() int64 {
+	value, width := io.integer()
+	if width == 0 {
+		return 0
+	}
+	shift := 64 - 8*width
+	return int64(value<<shift) >> shift
+}
+
+// Float returns the value scaled by scaler, read as signed if scaler says
+// so. A nil scaler reads an unsigned value with multiplier 1.
+//
+// Example:
+//
+//	entry, _ := iodict.Builtin().Lookup(io.IO)
+//	volts := io.Float(entry)
+func (io IOData) Float(scaler Scaler) float64 {
+	if scaler == nil {
+		return float64(io.Uint64())
+	}
+	if scaler.IsSigned() {
+		return float64(io.Int64()) * scaler.Scale()
+	}
+	return float64(io.Uint64()) * scaler.Scale()
+}
+
+// Bool reports whether any byte of the value is non-zero.
+func (io IOData) Bool() bool {
+	if _, width := io.integer(); width == 0 {
+		return false
+	}
+	return strings.Trim(io.Value, "0") != ""
 }
 
 type ResponseDecode struct {
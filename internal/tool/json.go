@@ -0,0 +1,69 @@
+package tool
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type gpsDataJSON struct {
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Altitude   int64   `json:"altitude"`
+	Angle      int64   `json:"angle"`
+	Satellites int64   `json:"satellites"`
+	Speed      int64   `json:"speed"`
+	Valid      bool    `json:"valid"`
+}
+
+// MarshalJSON encodes the GPS element with snake_case field names.
+func (gpsData GPSData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(gpsDataJSON{
+		Latitude:   gpsData.Latitude,
+		Longitude:  gpsData.Longitude,
+		Altitude:   gpsData.Altitude,
+		Angle:      gpsData.Angle,
+		Satellites: gpsData.Satelites,
+		Speed:      gpsData.Speed,
+		Valid:      gpsData.Valid,
+	})
+}
+
+func (gpsData *GPSData) UnmarshalJSON(data []byte) error {
+	var value gpsDataJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*gpsData = GPSData{
+		Latitude:  value.Latitude,
+		Longitude: value.Longitude,
+		Altitude:  value.Altitude,
+		Angle:     value.Angle,
+		Satelites: value.Satellites,
+		Speed:     value.Speed,
+		Valid:     value.Valid,
+	}
+	return nil
+}
+
+type commandResponseJSON struct {
+	Timestamp   *time.Time `json:"timestamp,omitempty"`
+	Response    string     `json:"response"`
+	HexMessage  string     `json:"hex_message,omitempty"`
+	CommandType string     `json:"command_type,omitempty"`
+	IMEI        string     `json:"imei,omitempty"`
+}
+
+// MarshalJSON encodes the command or response with snake_case field names
+// and an RFC 3339 timestamp.
+func (response CommandResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(commandResponseJSON(response))
+}
+
+func (response *CommandResponse) UnmarshalJSON(data []byte) error {
+	var value commandResponseJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*response = CommandResponse(value)
+	return nil
+}
@@ -8,7 +8,11 @@ type GPSData struct {
 	Altitude  int64
 	Angle     int64
 	Satelites int64
-	Speed     int64
+	Speed     int64 // km/h
+	// Valid is set by the decoder: false when the device had no fix and
+	// sent 0 satellites and speed 0 with its last known position. The
+	// encoder ignores it.
+	Valid bool
 }
 
 type LoginData struct {
@@ -16,6 +20,14 @@ type LoginData struct {
 	IMEI   string
 }
 
+type UDPAckData struct {
+	Length      int64
+	PacketID    int64
+	PacketType  int64
+	AVLPacketID int64
+	Accepted    int64
+}
+
 type ProtocolData struct {
 	Protocol string
 }
@@ -0,0 +1,73 @@
+package iodict
+
+var onOff = map[int64]string{0: "Off", 1: "On"}
+
+// builtinEntries are common AVL IDs shared by the FMB, FMC and FMM
+// families, as published in the Teltonika AVL ID tables.
+var builtinEntries = []Entry{
+	{ID: 1, Name: "Digital Input 1", Size: 1, Values: onOff},
+	{ID: 2, Name: "Digital Input 2", Size: 1, Values: onOff},
+	{ID: 3, Name: "Digital Input 3", Size: 1, Values: onOff},
+	{ID: 9, Name: "Analog Input 1", Size: 2, Multiplier: 0.001, Unit: "V"},
+	{ID: 6, Name: "Analog Input 2", Size: 2, Multiplier: 0.001, Unit: "V"},
+	{ID: 10, Name: "SD Status", Size: 1, Values: map[int64]string{0: "Not present", 1: "Present"}},
+	{ID: 11, Name: "ICCID1", Size: 8},
+	{ID: 12, Name: "Fuel Used GPS", Size: 4, Multiplier: 0.001, Unit: "l"},
+	{ID: 13, Name: "Fuel Rate GPS", Size: 2, Multiplier: 0.01, Unit: "l/100km"},
+	{ID: 14, Name: "ICCID2", Size: 8},
+	{ID: 15, Name: "Eco Score", Size: 2, Multiplier: 0.01},
+	{ID: 16, Name: "Total Odometer", Size: 4, Unit: "m"},
+	{ID: 17, Name: "Axis X", Size: 2, Signed: true, Unit: "mG"},
+	{ID: 18, Name: "Axis Y", Size: 2, Signed: true, Unit: "mG"},
+	{ID: 19, Name: "Axis Z", Size: 2, Signed: true, Unit: "mG"},
+	{ID: 21, Name: "GSM Signal", Size: 1},
+	{ID: 24, Name: "Speed", Size: 2, Unit: "km/h"},
+	{ID: 66, Name: "External Voltage", Size: 2, Multiplier: 0.001, Unit: "V"},
+	{ID: 67, Name: "Battery Voltage", Size: 2, Multiplier: 0.001, Unit: "V"},
+	{ID: 68, Name: "Battery Current", Size: 2, Multiplier: 0.001, Unit: "A"},
+	{ID: 69, Name: "GNSS Status", Size: 1, Values: map[int64]string{0: "GNSS off", 1: "GNSS on with fix", 2: "GNSS on without fix", 3: "GNSS sleep"}},
+	{ID: 72, Name: "Dallas Temperature 1", Size: 4, Signed: true, Multiplier: 0.1, Unit: "°C"},
+	{ID: 73, Name: "Dallas Temperature 2", Size: 4, Signed: true, Multiplier: 0.1, Unit: "°C"},
+	{ID: 78, Name: "iButton", Size: 8},
+	{ID: 80, Name: "Data Mode", Size: 1, Values: map[int64]string{
+		0: "Home On Stop", 1: "Home On Moving", 2: "Roaming On Stop",
+		3: "Roaming On Moving", 4: "Unknown On Stop", 5: "Unknown On Moving",
+	}},
+	{ID: 113, Name: "Battery Level", Size: 1, Unit: "%"},
+	{ID: 179, Name: "Digital Output 1", Size: 1, Values: onOff},
+	{ID: 180, Name: "Digital Output 2", Size: 1, Values: onOff},
+	{ID: 181, Name: "GNSS PDOP", Size: 2, Multiplier: 0.1},
+	{ID: 182, Name: "GNSS HDOP", Size: 2, Multiplier: 0.1},
+	{ID: 199, Name: "Trip Odometer", Size: 4, Unit: "m"},
+	{ID: 200, Name: "Sleep Mode", Size: 1, Values: map[int64]string{
+		0: "No Sleep", 1: "GPS Sleep", 2: "Deep Sleep", 3: "Online Sleep", 4: "Ultra Sleep",
+	}},
+	{ID: 205, Name: "GSM Cell ID", Size: 2},
+	{ID: 206, Name: "GSM Area Code", Size: 2},
+	{ID: 237, Name: "Network Type", Size: 1, Values: map[int64]string{
+		0: "3G", 1: "GSM", 2: "4G", 3: "LTE CAT M1", 4: "LTE CAT NB1", 99: "Unknown",
+	}},
+	{ID: 238, Name: "User ID", Size: 8},
+	{ID: 239, Name: "Ignition", Size: 1, Values: onOff},
+	{ID: 240, Name: "Movement", Size: 1, Values: onOff},
+	{ID: 241, Name: "Active GSM Operator", Size: 4},
+	{ID: 246, Name: "Towing", Size: 1, Values: map[int64]string{0: "Steady", 1: "Towing"}},
+	{ID: 247, Name: "Crash Detection", Size: 1},
+	{ID: 249, Name: "Jamming", Size: 1, Values: map[int64]string{0: "Jamming stop", 1: "Jamming start"}},
+	{ID: 250, Name: "Trip", Size: 1, Values: map[int64]string{0: "Trip stop", 1: "Trip start"}},
+	{ID: 251, Name: "Idling", Size: 1, Values: map[int64]string{0: "Moving", 1: "Idling"}},
+	{ID: 252, Name: "Unplug", Size: 1, Values: map[int64]string{0: "Battery present", 1: "Battery unplugged"}},
+	{ID: 253, Name: "Green Driving Type", Size: 1, Values: map[int64]string{1: "Harsh acceleration", 2: "Harsh braking", 3: "Harsh cornering"}},
+	{ID: 255, Name: "Over Speeding", Size: 1, Unit: "km/h"},
+	{ID: 263, Name: "BT Status", Size: 1, Values: map[int64]string{
+		0: "BT disabled", 1: "BT enabled, no device connected", 2: "Device connected, BTv3 only",
+		3: "Device connected, BLE only", 4: "Device connected, BLE and BT",
+	}},
+}
+
+// Builtin returns a new dictionary holding the common FMB, FMC and FMM
+// AVL IDs. Each call returns an independent copy, so registering entries
+// in it does not affect other callers.
+func Builtin() *Dictionary {
+	return New(builtinEntries...)
+}
@@ -0,0 +1,187 @@
+// Package iodict describes the AVL IO elements of Teltonika devices: the
+// name, size, signedness, multiplier, unit and value labels of each AVL ID,
+// so a decoded IOData can be turned into a named physical value.
+package iodict
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+)
+
+var (
+	// ErrUnknownID reports an AVL ID without an entry in the dictionary.
+	ErrUnknownID = errors.New("unknown AVL ID")
+	// ErrValueSize reports a value whose size differs from its entry.
+	ErrValueSize = errors.New("IO value size mismatch")
+)
+
+// Entry describes one AVL IO element.
+type Entry struct {
+	ID         int64            `json:"id"`
+	Name       string           `json:"name"`
+	Size       int              `json:"size"`                 // value size in bytes, 0 for variable-length values
+	Signed     bool             `json:"signed,omitempty"`     // the value is a two's complement integer
+	Multiplier float64          `json:"multiplier,omitempty"` // scale of the raw integer, 0 means 1
+	Unit       string           `json:"unit,omitempty"`       // unit of the scaled value, empty if none
+	Values     map[int64]string `json:"values,omitempty"`     // labels of enumerated raw values
+}
+
+// Scale returns the multiplier of the entry, 1 when it is not set.
+func (entry Entry) Scale() float64 {
+	if entry.Multiplier == 0 {
+		return 1
+	}
+	return entry.Multiplier
+}
+
+// IsSigned reports whether the value is a two's complement integer. With
+// Scale it makes Entry an io.Scaler for IOData.Float.
+func (entry Entry) IsSigned() bool {
+	return entry.Signed
+}
+
+// Value is an IO element resolved through a Dictionary.
+type Value struct {
+	Entry
+	Raw     []byte  // value bytes as received
+	Integer int64   // raw value as an integer, sign-extended if the entry is signed; 0 for variable-length values
+	Number  float64 // Integer scaled by the multiplier
+	Label   string  // label of Integer in Entry.Values, empty if none
+}
+
+// String formats the value as "Name: label" for enumerations, "Name: number
+// unit" for numeric values and "Name: hex" for variable-length values.
+func (value Value) String() string {
+	switch {
+	case value.Label != "":
+		return value.Name + ": " + value.Label
+	case value.Size == 0:
+		return value.Name + ": " + hex.EncodeToString(value.Raw)
+	}
+	number := strconv.FormatFloat(value.Number, 'f', -1, 64)
+	if value.Unit == "" {
+		return value.Name + ": " + number
+	}
+	return value.Name + ": " + number + " " + value.Unit
+}
+
+// Dictionary is a concurrency-safe registry of IO entries keyed by AVL ID.
+// Its zero value is an empty dictionary.
+type Dictionary struct {
+	mu      sync.RWMutex
+	entries map[int64]Entry
+}
+
+// New returns a dictionary holding entries.
+func New(entries ...Entry) *Dictionary {
+	dictionary := &Dictionary{entries: make(map[int64]Entry, len(entries))}
+	for _, entry := range entries {
+		dictionary.entries[entry.ID] = entry
+	}
+	return dictionary
+}
+
+// Register adds entry to the dictionary, replacing the entry with the same
+// ID.
+func (dictionary *Dictionary) Register(entry Entry) {
+	dictionary.mu.Lock()
+	defer dictionary.mu.Unlock()
+	if dictionary.entries == nil {
+		dictionary.entries = make(map[int64]Entry)
+	}
+	dictionary.entries[entry.ID] = entry
+}
+
+// Clone returns an independent copy of the dictionary.
+func (dictionary *Dictionary) Clone() *Dictionary {
+	return New(dictionary.Entries()...)
+}
+
+// Lookup returns the entry of the AVL ID id.
+func (dictionary *Dictionary) Lookup(id int64) (Entry, bool) {
+	dictionary.mu.RLock()
+	defer dictionary.mu.RUnlock()
+	entry, ok := dictionary.entries[id]
+	return entry, ok
+}
+
+// Entries returns the entries of the dictionary sorted by ID.
+func (dictionary *Dictionary) Entries() []Entry {
+	dictionary.mu.RLock()
+	entries := make([]Entry, 0, len(dictionary.entries))
+	for _, entry := range dictionary.entries {
+		entries = append(entries, entry)
+	}
+	dictionary.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+// Resolve turns a decoded IO element into a named physical value.
+//
+// Example:
+//
+//	value, err := iodict.Builtin().Resolve(io_domain.IOData{IO: 66, Value: "3071"})
+//	fmt.Println(value) // External Voltage: 12.401 V
+func (dictionary *Dictionary) Resolve(io io_domain.IOData) (Value, error) {
+	entry, ok := dictionary.Lookup(io.IO)
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %d", ErrUnknownID, io.IO)
+	}
+	raw, err := hex.DecodeString(io.Value)
+	if err != nil {
+		return Value{}, fmt.Errorf("IO %d: %w", io.IO, err)
+	}
+	return entry.Resolve(raw)
+}
+
+// Resolve interprets raw as the value of the entry.
+func (entry Entry) Resolve(raw []byte) (Value, error) {
+	value := Value{Entry: entry, Raw: raw}
+	if entry.Size == 0 {
+		return value, nil
+	}
+	if len(raw) != entry.Size || len(raw) > 8 {
+		return Value{}, fmt.Errorf("%w: IO %d has %d bytes, %s takes %d", ErrValueSize, entry.ID, len(raw), entry.Name, entry.Size)
+	}
+	var unsigned uint64
+	for _, b := range raw {
+		unsigned = unsigned<<8 | uint64(b)
+	}
+	value.Integer = int64(unsigned)
+	value.Number = float64(unsigned) * entry.Scale()
+	if entry.Signed {
+		// Sign-extend the value from its width.
+		shift := 64 - 8*len(raw)
+		value.Integer = int64(unsigned<<shift) >> shift
+		value.Number = float64(value.Integer) * entry.Scale()
+	}
+	value.Label = entry.Values[value.Integer]
+	return value, nil
+}
+
+// Annotate sets the Name of the IO elements of codecData that have an
+// entry in the dictionary, so that their JSON carries the name.
+func (dictionary *Dictionary) Annotate(codecData *decoder_domain.CodecData) {
+	if codecData == nil {
+		return
+	}
+	for _, record := range codecData.Records {
+		if record.IOs == nil {
+			continue
+		}
+		ios := *record.IOs
+		for i := range ios {
+			if entry, ok := dictionary.Lookup(ios[i].IO); ok {
+				ios[i].Name = entry.Name
+			}
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package iodict
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTable reports a dictionary file that cannot be loaded.
+var ErrInvalidTable = errors.New("invalid IO dictionary table")
+
+// LoadFile registers the entries of a JSON or CSV file, chosen by its
+// extension, in the dictionary.
+func (dictionary *Dictionary) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = dictionary.LoadJSON(file)
+	case ".csv":
+		err = dictionary.LoadCSV(file)
+	default:
+		err = fmt.Errorf("%w: unsupported file extension %q", ErrInvalidTable, filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadJSON registers the entries of a JSON table in the dictionary. The
+// table is an array of entries, or an object holding it under "entries":
+//
+//	[{"id": 66, "name": "External Voltage", "size": 2, "multiplier": 0.001, "unit": "V"},
+//	 {"id": 239, "name": "Ignition", "size": 1, "values": {"0": "Off", "1": "On"}}]
+func (dictionary *Dictionary) LoadJSON(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var entries []Entry
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var table struct {
+			Entries []Entry `json:"entries"`
+		}
+		err = json.Unmarshal(data, &table)
+		entries = table.Entries
+	} else {
+		err = json.Unmarshal(data, &entries)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTable, err)
+	}
+	for _, entry := range entries {
+		dictionary.Register(entry)
+	}
+	return nil
+}
+
+// csvColumns lists the accepted header names of each CSV column, in
+// lower case. The second names are those of the AVL ID tables published
+// by Teltonika.
+var csvColumns = map[string][]string{
+	"id":         {"id", "property id in avl packet", "avl id"},
+	"name":       {"name", "property name"},
+	"size":       {"size", "bytes"},
+	"type":       {"type"},
+	"signed":     {"signed"},
+	"multiplier": {"multiplier"},
+	"unit":       {"unit", "units"},
+	"values":     {"values", "description"},
+}
+
+// LoadCSV registers the entries of a CSV table in the dictionary. The
+// first row names the columns, in any order: ID, Name, Size, Signed,
+// Multiplier, Unit and Values, or the columns of the Teltonika AVL ID
+// tables ("Property ID in AVL packet", "Property Name", "Bytes", "Type",
+// "Multiplier", "Units", "Description"). Other columns are ignored; only
+// the ID and name are required.
+//
+// Values labels enumerated values as "0 - Off; 1 - On", one label per line
+// or semicolon-separated part. "Type" is Signed, Unsigned, HEX or ASCII;
+// a "-" or "Variable" size means a variable-length value.
+func (dictionary *Dictionary) LoadCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: reading header: %v", ErrInvalidTable, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		for column, names := range csvColumns {
+			for _, candidate := range names {
+				if _, found := columns[column]; name == candidate && !found {
+					columns[column] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["id"]; !ok {
+		return fmt.Errorf("%w: no ID column", ErrInvalidTable)
+	}
+	if _, ok := columns["name"]; !ok {
+		return fmt.Errorf("%w: no name column", ErrInvalidTable)
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTable, err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		if field("id") == "" {
+			continue // blank or section rows
+		}
+		entry, err := parseCSVEntry(field)
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrInvalidTable, line, err)
+		}
+		dictionary.Register(entry)
+	}
+}
+
+// parseCSVEntry builds an entry from the cells returned by field.
+func parseCSVEntry(field func(column string) string) (Entry, error) {
+	id, err := strconv.ParseInt(field("id"), 10, 64)
+	if err != nil {
+		return Entry{}, fmt.Errorf("ID %q: %v", field("id"), err)
+	}
+	entry := Entry{ID: id, Name: field("name"), Unit: field("unit")}
+	if entry.Unit == "-" {
+		entry.Unit = ""
+	}
+	if size := field("size"); size != "" && size != "-" && !strings.EqualFold(size, "variable") {
+		if entry.Size, err = strconv.Atoi(size); err != nil {
+			return Entry{}, fmt.Errorf("size %q: %v", size, err)
+		}
+	}
+	entry.Signed = strings.EqualFold(field("type"), "signed")
+	if signed := field("signed"); signed != "" {
+		if entry.Signed, err = strconv.ParseBool(signed); err != nil {
+			return Entry{}, fmt.Errorf("signed %q: %v", signed, err)
+		}
+	}
+	if multiplier := field("multiplier"); multiplier != "" && multiplier != "-" {
+		if entry.Multiplier, err = strconv.ParseFloat(multiplier, 64); err != nil {
+			return Entry{}, fmt.Errorf("multiplier %q: %v", multiplier, err)
+		}
+	}
+	entry.Values = parseValueLabels(field("values"))
+	return entry, nil
+}
+
+// valueLabel matches an enumerated value label such as "1 - On" or "2: Deep Sleep".
+var valueLabel = regexp.MustCompile(`^\s*(-?\d+)\s*[-–=:]\s*(.+?)\s*$`)
+
+// parseValueLabels extracts the labels of enumerated values from text,
+// ignoring the parts that are not labels, such as free-form descriptions.
+func parseValueLabels(text string) map[int64]string {
+	var values map[int64]string
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ';' }) {
+		match := valueLabel.FindStringSubmatch(part)
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if values == nil {
+			values = map[int64]string{}
+		}
+		values[value] = match[2]
+	}
+	return values
+}
@@ -0,0 +1,147 @@
+package iodict
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Profiles selects the dictionary of a device. Device families assign
+// different meanings to the same AVL IDs, so each device model can have
+// its own dictionary, and a device is mapped to a model, or to a
+// dictionary of its own, by IMEI. Its zero value has no profiles and an
+// empty fallback dictionary.
+//
+// Example:
+//
+//	profiles := iodict.NewProfiles(iodict.Builtin())
+//	if err := profiles.LoadDir("/etc/teltonika/iodict"); err != nil { // FMB640.csv, FMC130.json, ...
+//		log.Fatal(err)
+//	}
+//	profiles.AssignModel("356307042441013", "FMB640")
+//	value, err := profiles.ForIMEI(imei).Resolve(io)
+type Profiles struct {
+	mu       sync.RWMutex
+	fallback *Dictionary
+	models   map[string]*Dictionary
+	devices  map[string]*Dictionary // dictionaries set for one IMEI
+	assigned map[string]string      // model of each IMEI
+}
+
+// NewProfiles returns profiles that use fallback for devices without a
+// profile. A nil fallback selects an empty dictionary.
+func NewProfiles(fallback *Dictionary) *Profiles {
+	if fallback == nil {
+		fallback = New()
+	}
+	return &Profiles{
+		fallback: fallback,
+		models:   map[string]*Dictionary{},
+		devices:  map[string]*Dictionary{},
+		assigned: map[string]string{},
+	}
+}
+
+// normalizeModel makes model names case-insensitive.
+func normalizeModel(model string) string {
+	return strings.ToUpper(strings.TrimSpace(model))
+}
+
+// SetModel sets the dictionary of a device model, such as "FMB640".
+func (profiles *Profiles) SetModel(model string, dictionary *Dictionary) {
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	if profiles.models == nil {
+		profiles.models = make(map[string]*Dictionary)
+	}
+	profiles.models[normalizeModel(model)] = dictionary
+}
+
+// SetIMEI sets a dictionary for one device, taking precedence over the
+// dictionary of its model.
+func (profiles *Profiles) SetIMEI(imei string, dictionary *Dictionary) {
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	if profiles.devices == nil {
+		profiles.devices = make(map[string]*Dictionary)
+	}
+	profiles.devices[imei] = dictionary
+}
+
+// AssignModel records the model of the device with the given IMEI.
+func (profiles *Profiles) AssignModel(imei string, model string) {
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	if profiles.assigned == nil {
+		profiles.assigned = make(map[string]string)
+	}
+	profiles.assigned[imei] = normalizeModel(model)
+}
+
+// ForModel returns the dictionary of model, or the fallback.
+func (profiles *Profiles) ForModel(model string) *Dictionary {
+	profiles.mu.RLock()
+	dictionary, ok := profiles.models[normalizeModel(model)]
+	profiles.mu.RUnlock()
+	if ok {
+		return dictionary
+	}
+	return profiles.fallbackDictionary()
+}
+
+// ForIMEI returns the dictionary set for the device, else the dictionary
+// of its assigned model, else the fallback.
+func (profiles *Profiles) ForIMEI(imei string) *Dictionary {
+	profiles.mu.RLock()
+	dictionary, ok := profiles.devices[imei]
+	model, assigned := profiles.assigned[imei]
+	profiles.mu.RUnlock()
+	if ok {
+		return dictionary
+	}
+	if assigned {
+		return profiles.ForModel(model)
+	}
+	return profiles.fallbackDictionary()
+}
+
+// fallbackDictionary returns the fallback, creating an empty one for the
+// zero Profiles.
+func (profiles *Profiles) fallbackDictionary() *Dictionary {
+	profiles.mu.RLock()
+	fallback := profiles.fallback
+	profiles.mu.RUnlock()
+	if fallback != nil {
+		return fallback
+	}
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	if profiles.fallback == nil {
+		profiles.fallback = New()
+	}
+	return profiles.fallback
+}
+
+// LoadDir sets a model profile for each JSON or CSV file in dir, named
+// after the file without its extension: FMB640.csv defines model FMB640.
+// Each profile starts from a copy of the fallback dictionary, so a file
+// only needs the entries it adds or changes.
+func (profiles *Profiles) LoadDir(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		extension := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || (extension != ".json" && extension != ".csv") {
+			continue
+		}
+		dictionary := profiles.fallbackDictionary().Clone()
+		if err := dictionary.LoadFile(filepath.Join(dir, file.Name())); err != nil {
+			return err
+		}
+		profiles.SetModel(strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())), dictionary)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+// Package journal records raw device traffic and plays it back. A journal
+// is an append-only file of entries, each holding a raw frame as a server
+// received it together with the receive time, the IMEI, the transport and
+// the remote address, so that a parsing bug seen in production can be
+// reproduced from the exact bytes.
+//
+// A journal starts with the 8-byte magic "TELJRNL\x01". Each entry follows
+// as a 4-byte big-endian body length, the body and a 4-byte CRC-32 (IEEE)
+// of the body. The body holds the receive time in Unix nanoseconds (8
+// bytes), the transport (1 byte: 1 for TCP, 2 for UDP), the IMEI and the
+// remote address (each a 1-byte length and the ASCII text) and the frame.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotJournal reports data that does not start with the journal magic.
+	ErrNotJournal = errors.New("not a frame journal")
+	// ErrCorrupt reports an entry that is truncated or fails its CRC.
+	ErrCorrupt = errors.New("corrupt journal entry")
+)
+
+// magic starts every journal; its last byte is the format version.
+var magic = []byte("TELJRNL\x01")
+
+// MaxFrameSize bounds the frame of an entry, so that a corrupt length
+// cannot make a Reader allocate without limit.
+const MaxFrameSize = 1 << 24
+
+// entryOverhead is the size of an entry without its IMEI, remote address
+// and frame: length, time, transport, the two text lengths and CRC.
+const entryOverhead = 4 + 8 + 1 + 1 + 1 + 4
+
+var transports = map[string]byte{"TCP": 1, "UDP": 2}
+
+// Entry is a raw frame received from a device.
+type Entry struct {
+	Time       time.Time
+	IMEI       string // empty when a UDP datagram has no valid header
+	Transport  string // "TCP" or "UDP"
+	RemoteAddr string
+	Frame      []byte
+}
+
+type entryJSON struct {
+	Time       time.Time `json:"time"`
+	IMEI       string    `json:"imei,omitempty"`
+	Transport  string    `json:"transport"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	Frame      string    `json:"frame"`
+}
+
+// MarshalJSON encodes the entry with an RFC 3339 time and a hex frame.
+func (entry Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(entryJSON{
+		Time:       entry.Time,
+		IMEI:       entry.IMEI,
+		Transport:  entry.Transport,
+		RemoteAddr: entry.RemoteAddr,
+		Frame:      hex.EncodeToString(entry.Frame),
+	})
+}
+
+func (entry *Entry) UnmarshalJSON(data []byte) error {
+	var value entryJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	frame, err := hex.DecodeString(value.Frame)
+	if err != nil {
+		return fmt.Errorf("invalid frame: %w", err)
+	}
+	*entry = Entry{Time: value.Time, IMEI: value.IMEI, Transport: value.Transport, RemoteAddr: value.RemoteAddr, Frame: frame}
+	return nil
+}
+
+// Writer appends entries to a journal. It is safe for concurrent use, so
+// one Writer can record every connection of a server:
+//
+//	writer, err := journal.OpenFile("traffic.tjl")
+//	srv := &server.TCPServer{Addr: ":5027", Handler: handler, Journal: writer.Write}
+type Writer struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+	buffer []byte
+}
+
+// NewWriter writes the journal magic to w and returns a Writer appending
+// entries after it.
+func NewWriter(w io.Writer) (*Writer, error) {
+	if _, err := w.Write(magic); err != nil {
+		return nil, err
+	}
+	return &Writer{writer: w}, nil
+}
+
+// OpenFile opens the journal at path for appending, creating it if it does
+// not exist. An entry cut short by a crash at the end of an existing
+// journal is removed before new entries are appended; corruption anywhere
+// else fails with ErrCorrupt.
+func OpenFile(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := openFile(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("journal %s: %w", path, err)
+	}
+	return writer, nil
+}
+
+func openFile(file *os.File) (*Writer, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		writer, err := NewWriter(file)
+		if err != nil {
+			return nil, err
+		}
+		writer.closer = file
+		return writer, nil
+	}
+
+	reader, err := NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			if err := file.Truncate(reader.offset); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Writer{writer: file, closer: file}, nil
+}
+
+// Write appends entry to the journal with a single write to the underlying
+// writer. Its signature matches server.FrameRecorder.
+func (w *Writer) Write(entry Entry) error {
+	transport, ok := transports[entry.Transport]
+	if !ok {
+		return fmt.Errorf("unknown transport %q", entry.Transport)
+	}
+	if len(entry.IMEI) > 255 || len(entry.RemoteAddr) > 255 {
+		return fmt.Errorf("IMEI or remote address longer than 255 bytes")
+	}
+	if len(entry.Frame) > MaxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds %d", len(entry.Frame), MaxFrameSize)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	size := entryOverhead + len(entry.IMEI) + len(entry.RemoteAddr) + len(entry.Frame)
+	buffer := w.buffer[:0]
+	buffer = binary.BigEndian.AppendUint32(buffer, uint32(size-8))
+	buffer = binary.BigEndian.AppendUint64(buffer, uint64(entry.Time.UnixNano()))
+	buffer = append(buffer, transport, byte(len(entry.IMEI)))
+	buffer = append(buffer, entry.IMEI...)
+	buffer = append(buffer, byte(len(entry.RemoteAddr)))
+	buffer = append(buffer, entry.RemoteAddr...)
+	buffer = append(buffer, entry.Frame...)
+	buffer = binary.BigEndian.AppendUint32(buffer, crc32.ChecksumIEEE(buffer[4:]))
+	w.buffer = buffer
+	_, err := w.writer.Write(buffer)
+	return err
+}
+
+// Close closes the file of a Writer returned by OpenFile. It does nothing
+// for a Writer returned by NewWriter.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// Reader reads the entries of a journal in order.
+type Reader struct {
+	reader *bufio.Reader
+	offset int64 // end of the last complete entry
+}
+
+// NewReader checks the journal magic at the start of r.
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotJournal
+		}
+		return nil, err
+	}
+	if !bytes.Equal(header, magic) {
+		return nil, ErrNotJournal
+	}
+	return &Reader{reader: reader, offset: int64(len(magic))}, nil
+}
+
+// Next returns the next entry. At the end of the journal it returns
+// io.EOF; an entry cut short fails with ErrCorrupt wrapping
+// io.ErrUnexpectedEOF.
+func (r *Reader) Next() (Entry, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r.reader, length[:]); err != nil {
+		if err == io.EOF {
+			return Entry{}, io.EOF
+		}
+		return Entry{}, r.corrupt(err)
+	}
+	size := int(binary.BigEndian.Uint32(length[:]))
+	if size < entryOverhead-8 || size > entryOverhead-8+2*255+MaxFrameSize {
+		return Entry{}, r.corrupt(fmt.Errorf("invalid entry length %d", size))
+	}
+	data := make([]byte, size+4)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return Entry{}, r.corrupt(unexpectedEOF(err))
+	}
+	body := data[:size]
+	if crc := binary.BigEndian.Uint32(data[size:]); crc != crc32.ChecksumIEEE(body) {
+		return Entry{}, r.corrupt(fmt.Errorf("CRC 0x%08X does not match", crc))
+	}
+
+	entry, err := parseBody(body)
+	if err != nil {
+		return Entry{}, r.corrupt(err)
+	}
+	r.offset += int64(4 + len(data))
+	return entry, nil
+}
+
+// parseBody decodes an entry body whose CRC matched.
+func parseBody(body []byte) (Entry, error) {
+	entry := Entry{Time: time.Unix(0, int64(binary.BigEndian.Uint64(body))).UTC()}
+	for name, code := range transports {
+		if body[8] == code {
+			entry.Transport = name
+		}
+	}
+	if entry.Transport == "" {
+		return Entry{}, fmt.Errorf("unknown transport %d", body[8])
+	}
+	rest := body[9:]
+	for _, text := range []*string{&entry.IMEI, &entry.RemoteAddr} {
+		if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+			return Entry{}, fmt.Errorf("text longer than the entry")
+		}
+		*text = string(rest[1 : 1+rest[0]])
+		rest = rest[1+rest[0]:]
+	}
+	entry.Frame = rest
+	return entry, nil
+}
+
+func (r *Reader) corrupt(err error) error {
+	return fmt.Errorf("%w at offset %d: %w", ErrCorrupt, r.offset, err)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// ErrLoginRejected is returned by Send when the server rejects the IMEI
+// of a TCP entry.
+var ErrLoginRejected = errors.New("login rejected")
+
+// Replayer plays the entries of a journal back, either through the
+// decoder or to a server. The zero value replays as fast as possible.
+//
+// Example:
+//
+//	reader, err := journal.NewReader(file)
+//	replayer := &journal.Replayer{Speed: 10}
+//	err = replayer.Decode(ctx, reader, func(entry journal.Entry, decoded *decoder_domain.CodecDecoded) error {
+//		if decoded.Error != nil {
+//			fmt.Println(entry.Time, entry.IMEI, decoded.Error)
+//		}
+//		return nil
+//	})
+type Replayer struct {
+	// Speed scales the time between entries: 1 replays them at their
+	// original pace, 10 ten times faster. Zero does not wait.
+	Speed float64
+	// DecoderOptions are passed to pkg.TramDecoder by Decode.
+	DecoderOptions pkg.DecoderOptions
+	// AckTimeout bounds the wait for each ACK during Send, 10 seconds if
+	// zero.
+	AckTimeout time.Duration
+}
+
+// Decode reads reader to its end, decodes every frame with pkg.TramDecoder
+// and calls fn with the entry and its result. A non-nil error from fn
+// stops the replay and is returned, as is a corrupt entry.
+func (p *Replayer) Decode(ctx context.Context, reader *Reader, fn func(entry Entry, decoded *decoder_domain.CodecDecoded) error) error {
+	return p.play(ctx, reader, func(entry Entry) error {
+		return fn(entry, pkg.TramDecoder(entry.Frame, p.DecoderOptions))
+	})
+}
+
+// Send reads reader to its end and sends every frame to the server at addr
+// the way its device did: TCP entries over one connection per IMEI that
+// starts with a login, UDP entries as datagrams from one socket per IMEI.
+// It waits for the ACK of each AVL frame, and returns the number of
+// entries sent with the first failure.
+func (p *Replayer) Send(ctx context.Context, reader *Reader, addr string) (int, error) {
+	senders := map[string]*sender{}
+	defer func() {
+		for _, sender := range senders {
+			sender.conn.Close()
+		}
+	}()
+
+	sent := 0
+	err := p.play(ctx, reader, func(entry Entry) error {
+		key := entry.Transport + "/" + entry.IMEI
+		s, ok := senders[key]
+		if !ok {
+			var err error
+			if s, err = p.connect(ctx, addr, entry); err != nil {
+				return err
+			}
+			senders[key] = s
+		}
+		if err := s.send(entry.Frame, p.ackTimeout()); err != nil {
+			return fmt.Errorf("sending frame of %s received at %v: %w", entry.IMEI, entry.Time, err)
+		}
+		sent++
+		return nil
+	})
+	return sent, err
+}
+
+// play calls fn with every entry of reader, waiting between entries as
+// Speed asks.
+func (p *Replayer) play(ctx context.Context, reader *Reader, fn func(entry Entry) error) error {
+	var first time.Time
+	start := time.Now()
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first.IsZero() {
+			first = entry.Time
+		}
+		if p.Speed > 0 {
+			due := start.Add(time.Duration(float64(entry.Time.Sub(first)) / p.Speed))
+			if err := sleepUntil(ctx, due); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// sender is the connection replaying the entries of one device.
+type sender struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	udp    bool
+}
+
+// connect opens the connection of the device of entry, logging in over
+// TCP.
+func (p *Replayer) connect(ctx context.Context, addr string, entry Entry) (*sender, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, map[string]string{"TCP": "tcp", "UDP": "udp"}[entry.Transport], addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &sender{conn: conn, reader: bufio.NewReader(conn), udp: entry.Transport == "UDP"}
+	if s.udp {
+		return s, nil
+	}
+
+	login, err := tools.EncodeLogin(entry.IMEI)
+	if err == nil {
+		err = s.exchange(login, 1, p.ackTimeout(), func(reply []byte) error {
+			accepted, err := tools.DecodeLoginResponse(reply)
+			if err == nil && !accepted {
+				err = fmt.Errorf("%w: %s", ErrLoginRejected, entry.IMEI)
+			}
+			return err
+		})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// send writes frame and, when it is an AVL frame, reads its ACK.
+func (s *sender) send(frame []byte, timeout time.Duration) error {
+	if s.udp {
+		header, err := pkg.DecodeHeader(frame)
+		if err != nil || header.HeaderUDP == nil || len(frame) <= header.LastByte || !pkg.IsAVLCodec(frame[header.LastByte]) {
+			_, err := s.conn.Write(frame)
+			return err
+		}
+		return s.exchange(frame, 7, timeout, func(reply []byte) error {
+			_, err := tools.DecodeUDPAck(reply)
+			return err
+		})
+	}
+	if len(frame) <= 8 || !pkg.IsAVLCodec(frame[8]) {
+		_, err := s.conn.Write(frame)
+		return err
+	}
+	return s.exchange(frame, 4, timeout, func(reply []byte) error {
+		_, err := tools.DecodeTCPAck(reply)
+		return err
+	})
+}
+
+// exchange writes request and passes the reply of size bytes to check.
+func (s *sender) exchange(request []byte, size int, timeout time.Duration, check func(reply []byte) error) error {
+	if _, err := s.conn.Write(request); err != nil {
+		return err
+	}
+	s.conn.SetReadDeadline(time.Now().Add(timeout))
+	reply := make([]byte, size)
+	if s.udp {
+		n, err := s.conn.Read(reply)
+		if err != nil {
+			return err
+		}
+		return check(reply[:n])
+	}
+	if _, err := io.ReadFull(s.reader, reply); err != nil {
+		return err
+	}
+	return check(reply)
+}
+
+func (p *Replayer) ackTimeout() time.Duration {
+	if p.AckTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return p.AckTimeout
+}
+
+// sleepUntil waits until t or until ctx is done, returning ctx.Err() then.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
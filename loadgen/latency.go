@@ -0,0 +1,74 @@
+package loadgen
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxLatencySamples bounds the memory of a latency recorder. Past it,
+// samples are kept by reservoir sampling, so percentiles stay unbiased
+// estimates however long the run.
+const maxLatencySamples = 1 << 20
+
+// Latency summarizes the ACK latencies of a run.
+type Latency struct {
+	Samples int64 // number of ACKs measured
+	Mean    time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// latencyRecorder collects ACK latencies from every device of a run.
+type latencyRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	count   int64
+	sum     time.Duration
+	max     time.Duration
+}
+
+func (recorder *latencyRecorder) add(latency time.Duration) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.count++
+	recorder.sum += latency
+	recorder.max = max(recorder.max, latency)
+	if len(recorder.samples) < maxLatencySamples {
+		recorder.samples = append(recorder.samples, latency)
+		return
+	}
+	if i := rand.Int64N(recorder.count); i < maxLatencySamples {
+		recorder.samples[i] = latency
+	}
+}
+
+func (recorder *latencyRecorder) summary() Latency {
+	recorder.mu.Lock()
+	samples := slices.Clone(recorder.samples)
+	latency := Latency{Samples: recorder.count, Max: recorder.max}
+	if recorder.count > 0 {
+		latency.Mean = recorder.sum / time.Duration(recorder.count)
+	}
+	recorder.mu.Unlock()
+
+	if len(samples) == 0 {
+		return latency
+	}
+	slices.Sort(samples)
+	latency.P50 = percentile(samples, 50)
+	latency.P90 = percentile(samples, 90)
+	latency.P95 = percentile(samples, 95)
+	latency.P99 = percentile(samples, 99)
+	return latency
+}
+
+// percentile returns the nearest-rank percentile p of sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
@@ -0,0 +1,350 @@
+// Package loadgen benchmarks ingestion servers with many simulated
+// devices at once. Each device is a simulator.Device on its own
+// connection; the Generator mixes codecs across them, reconnects them as
+// configured and reports throughput, ACK latency percentiles and errors.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	simulator "github.com/danieljvsa/teltonika-go/simulator"
+)
+
+// DefaultIMEIBase is the IMEI of the first device of a Generator whose
+// IMEIBase is not set; device i gets IMEIBase + i.
+const DefaultIMEIBase = 350000000000000
+
+// CodecShare is the share of the devices of a Generator that send a codec.
+type CodecShare struct {
+	CodecID byte // 0x08, 0x8E or 0x10
+	Weight  int
+}
+
+// Generator opens Devices concurrent connections to a server, each sending
+// frames like a simulator.Device. The zero value of each optional field
+// selects the default given in its comment.
+//
+// Example:
+//
+//	generator := &loadgen.Generator{
+//		Addr:     "ingest.example.com:5027",
+//		Devices:  20000,
+//		Interval: 30 * time.Second,
+//		Codecs:   []loadgen.CodecShare{{CodecID: 0x08, Weight: 3}, {CodecID: 0x8E, Weight: 1}},
+//		Ramp:     time.Minute,
+//		Duration: 10 * time.Minute,
+//	}
+//	report, err := generator.Run(ctx)
+//	fmt.Println(report)
+type Generator struct {
+	// Addr is the server address.
+	Addr string
+	// Protocol is "TCP" or "UDP", TCP if empty.
+	Protocol string
+	// Devices is the number of simulated devices, 1 if zero.
+	Devices int
+	// IMEIBase is the IMEI of the first device, DefaultIMEIBase if zero.
+	IMEIBase int64
+	// RecordsPerFrame is the number of records of each frame, 1 if zero,
+	// at most simulator.MaxRecordsPerFrame.
+	RecordsPerFrame int
+	// Interval is the time between two frames of a device, 10 seconds if
+	// zero.
+	Interval time.Duration
+	// Codecs splits the devices between codecs in proportion to their
+	// weights. Empty selects Codec 8 for every device.
+	Codecs []CodecShare
+	// Route and IOs are used by every device; see simulator.Device.
+	Route []simulator.Waypoint
+	IOs   []io_domain.IOData
+	// Ramp spreads the first connections of the devices evenly over its
+	// duration; zero connects them all at once.
+	Ramp time.Duration
+	// Duration ends the run: no frame is sent after it, and the ACKs of
+	// the frames already sent are awaited for up to AckTimeout. Zero runs
+	// until the context is done.
+	Duration time.Duration
+	// ReconnectAfter closes the connection of a device after that many
+	// frames and opens a new one, like devices that upload and hang up.
+	// Zero keeps each connection open.
+	ReconnectAfter int
+	// Reconnect makes a device whose connection fails connect again after
+	// ReconnectDelay; otherwise the device stops.
+	Reconnect bool
+	// ReconnectDelay is the wait before a new connection. After a failed
+	// connection it is 1 second if zero; a planned reconnect after
+	// ReconnectAfter frames waits only for its next frame if it is zero.
+	ReconnectDelay time.Duration
+	// AckTimeout bounds the wait for each ACK, 10 seconds if zero.
+	AckTimeout time.Duration
+
+	mu          sync.Mutex
+	devices     []*simulator.Device
+	started     time.Time
+	recorder    *latencyRecorder
+	connections atomic.Int64
+	active      atomic.Int64
+	errorCounts map[string]int64
+}
+
+// Report describes the traffic of a run.
+type Report struct {
+	Elapsed          time.Duration
+	Devices          int
+	Active           int64 // connections open when the report was taken
+	Connections      int64 // connections opened, reconnections included
+	Frames           int64
+	Records          int64
+	Acknowledged     int64 // records the server acknowledged as accepted
+	Commands         int64
+	FramesPerSecond  float64
+	RecordsPerSecond float64
+	Latency          Latency
+	Errors           map[string]int64 // by kind, see errorKind
+}
+
+// Run starts the devices and waits until Duration has passed or ctx is
+// done and every device has stopped, which includes the wait for the ACKs
+// of the last frames. The error reports invalid settings;
+// device failures are counted in the report.
+func (g *Generator) Run(ctx context.Context) (Report, error) {
+	codecs, err := g.codecPlan()
+	if err != nil {
+		return Report{}, err
+	}
+	if g.RecordsPerFrame > simulator.MaxRecordsPerFrame {
+		return Report{}, fmt.Errorf("%d records per frame, at most %d fit in a frame", g.RecordsPerFrame, simulator.MaxRecordsPerFrame)
+	}
+	// Devices stop sending when sending is done; ctx also ends the wait
+	// for their last ACKs.
+	sending := ctx
+	var until time.Time
+	if g.Duration > 0 {
+		until = time.Now().Add(g.Duration)
+		var cancel context.CancelFunc
+		sending, cancel = context.WithDeadline(ctx, until)
+		defer cancel()
+		ctx, cancel = context.WithDeadline(ctx, until.Add(g.ackTimeout()))
+		defer cancel()
+	}
+
+	count := max(g.Devices, 1)
+	imeiBase := g.IMEIBase
+	if imeiBase == 0 {
+		imeiBase = DefaultIMEIBase
+	}
+	recorder := &latencyRecorder{}
+	devices := make([]*simulator.Device, count)
+	for i := range devices {
+		devices[i] = &simulator.Device{
+			IMEI:            strconv.FormatInt(imeiBase+int64(i), 10),
+			Addr:            g.Addr,
+			Protocol:        g.Protocol,
+			CodecID:         codecs[i%len(codecs)],
+			Route:           g.Route,
+			RecordsPerFrame: g.RecordsPerFrame,
+			Interval:        g.Interval,
+			Frames:          g.ReconnectAfter,
+			Until:           until,
+			IOs:             g.IOs,
+			AckTimeout:      g.AckTimeout,
+			OnAck:           func(records int, accepted int64, latency time.Duration) { recorder.add(latency) },
+			ErrorLog:        log.New(io.Discard, "", 0),
+		}
+	}
+	g.mu.Lock()
+	g.devices, g.started, g.recorder, g.errorCounts = devices, time.Now(), recorder, map[string]int64{}
+	g.connections.Store(0)
+	g.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if g.Ramp > 0 && sleep(sending, g.Ramp*time.Duration(i)/time.Duration(count)) != nil {
+				return
+			}
+			g.runDevice(ctx, sending, device)
+		}()
+	}
+	wg.Wait()
+	return g.Snapshot(), nil
+}
+
+// runDevice runs device until sending is done or its next frame is due
+// after its Until, connecting it again as the reconnect settings ask. A
+// reconnect waits for the next frame, so it keeps Interval.
+func (g *Generator) runDevice(ctx, sending context.Context, device *simulator.Device) {
+	for sending.Err() == nil && (device.Until.IsZero() || device.Next().Before(device.Until)) {
+		if sleep(sending, time.Until(device.Next())) != nil {
+			return
+		}
+		g.connections.Add(1)
+		g.active.Add(1)
+		err := device.Run(ctx)
+		g.active.Add(-1)
+		if err != nil {
+			g.countError(err)
+			if !g.Reconnect {
+				return
+			}
+		} else if g.ReconnectAfter == 0 {
+			return
+		}
+		// A planned reconnect waits for ReconnectDelay only if it is set;
+		// the next frame paces it otherwise.
+		if err != nil || g.ReconnectDelay > 0 {
+			if sleep(sending, g.reconnectDelay()) != nil {
+				return
+			}
+		}
+	}
+}
+
+// Snapshot returns the report of the run so far. It may be called while
+// Run is running.
+func (g *Generator) Snapshot() Report {
+	g.mu.Lock()
+	devices, started, recorder := g.devices, g.started, g.recorder
+	errorCounts := make(map[string]int64, len(g.errorCounts))
+	for kind, count := range g.errorCounts {
+		errorCounts[kind] = count
+	}
+	g.mu.Unlock()
+
+	report := Report{
+		Devices:     len(devices),
+		Active:      g.active.Load(),
+		Connections: g.connections.Load(),
+		Errors:      errorCounts,
+	}
+	if started.IsZero() {
+		return report
+	}
+	report.Elapsed = time.Since(started)
+	for _, device := range devices {
+		stats := device.Stats()
+		report.Frames += stats.Frames
+		report.Records += stats.Records
+		report.Acknowledged += stats.Acknowledged
+		report.Commands += stats.Commands
+	}
+	if seconds := report.Elapsed.Seconds(); seconds > 0 {
+		report.FramesPerSecond = float64(report.Frames) / seconds
+		report.RecordsPerSecond = float64(report.Records) / seconds
+	}
+	report.Latency = recorder.summary()
+	return report
+}
+
+func (g *Generator) countError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errorCounts[errorKind(err)]++
+}
+
+// errorKind sorts device failures into the kinds of a Report: "connect",
+// "login rejected", "ACK timeout", "connection closed" and "other".
+func errorKind(err error) string {
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return "connect"
+	case errors.Is(err, simulator.ErrLoginRejected):
+		return "login rejected"
+	case errors.Is(err, simulator.ErrAckTimeout):
+		return "ACK timeout"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return "connection closed"
+	case errors.As(err, &opErr):
+		return "connection closed"
+	}
+	return "other"
+}
+
+// codecPlan lays out the codec mix as a cycle in which each codec appears
+// as often as its weight; device i takes entry i of the cycle.
+func (g *Generator) codecPlan() ([]byte, error) {
+	if len(g.Codecs) == 0 {
+		return []byte{0x08}, nil
+	}
+	var plan []byte
+	for _, share := range g.Codecs {
+		switch share.CodecID {
+		case 0x08, 0x8E, 0x10:
+		default:
+			return nil, fmt.Errorf("unsupported AVL codec: 0x%02X", share.CodecID)
+		}
+		if share.Weight < 0 {
+			return nil, fmt.Errorf("negative weight for codec 0x%02X", share.CodecID)
+		}
+		for range share.Weight {
+			plan = append(plan, share.CodecID)
+		}
+	}
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("codec weights add up to zero")
+	}
+	return plan, nil
+}
+
+func (g *Generator) reconnectDelay() time.Duration {
+	if g.ReconnectDelay <= 0 {
+		return time.Second
+	}
+	return g.ReconnectDelay
+}
+
+func (g *Generator) ackTimeout() time.Duration {
+	if g.AckTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return g.AckTimeout
+}
+
+// sleep waits for d or until ctx is done, returning ctx.Err() then.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// String formats the report as a few lines of text.
+func (r Report) String() string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "elapsed %v, devices %d, active %d, connections %d\n", r.Elapsed.Round(time.Millisecond), r.Devices, r.Active, r.Connections)
+	fmt.Fprintf(&text, "frames %d (%.1f/s), records %d (%.1f/s), acknowledged %d, commands %d\n", r.Frames, r.FramesPerSecond, r.Records, r.RecordsPerSecond, r.Acknowledged, r.Commands)
+	latency := r.Latency
+	fmt.Fprintf(&text, "ACK latency over %d ACKs: mean %v, p50 %v, p90 %v, p95 %v, p99 %v, max %v\n", latency.Samples, latency.Mean, latency.P50, latency.P90, latency.P95, latency.P99, latency.Max)
+	kinds := make([]string, 0, len(r.Errors))
+	for kind := range r.Errors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	text.WriteString("errors:")
+	if len(kinds) == 0 {
+		text.WriteString(" none")
+	}
+	for _, kind := range kinds {
+		fmt.Fprintf(&text, " %s %d", kind, r.Errors[kind])
+	}
+	return text.String()
+}
@@ -0,0 +1,98 @@
+package teltonika_go
+
+import (
+	"sync"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+)
+
+// Codec decodes and encodes the data part of a frame for one codec ID: the
+// bytes following the codec ID, from the first record count up to and
+// including the CRC.
+//
+// Applications can implement Codec for vendor codecs and make TramDecoder
+// and TramEncoder use it with RegisterCodec.
+type Codec interface {
+	// ID returns the codec ID byte, for example 0x08 for Codec 8.
+	ID() byte
+	// Decode decodes the data part of a frame received over protocol
+	// ("TCP" or "UDP").
+	Decode(data []byte, protocol string) (*decoder_domain.CodecData, error)
+	// Encode encodes codecData into the data part of a frame.
+	Encode(codecData *decoder_domain.CodecData) ([]byte, error)
+}
+
+// OptionsCodec is a Codec that enforces DecoderOptions. TramDecoder passes
+// its options to registered codecs implementing it, and calls Decode on
+// the others. The built-in codecs implement OptionsCodec.
+type OptionsCodec interface {
+	Codec
+	// DecodeWithOptions decodes like Decode, rejecting data that exceeds
+	// the limits of options.
+	DecodeWithOptions(data []byte, protocol string, options DecoderOptions) (*decoder_domain.CodecData, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[byte]Codec{}
+)
+
+// builtinCodec adapts the DecodeCodecX and EncodeCodecX functions to
+// OptionsCodec.
+type builtinCodec struct {
+	id     byte
+	decode func(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error)
+	encode func(codecData *decoder_domain.CodecData) ([]byte, error)
+}
+
+func (codec builtinCodec) ID() byte {
+	return codec.id
+}
+
+func (codec builtinCodec) Decode(data []byte, protocol string) (*decoder_domain.CodecData, error) {
+	return codec.decode(data, protocol)
+}
+
+func (codec builtinCodec) DecodeWithOptions(data []byte, protocol string, options DecoderOptions) (*decoder_domain.CodecData, error) {
+	return codec.decode(data, protocol, options)
+}
+
+func (codec builtinCodec) Encode(codecData *decoder_domain.CodecData) ([]byte, error) {
+	return codec.encode(codecData)
+}
+
+func init() {
+	for _, codec := range []builtinCodec{
+		{id: 0x08, decode: DecodeCodec8, encode: EncodeCodec8},
+		{id: 0x8E, decode: DecodeCodec8Ext, encode: EncodeCodec8Ext},
+		{id: 0x0C, decode: DecodeCodec12, encode: EncodeCodec12},
+		{id: 0x0D, decode: DecodeCodec13, encode: EncodeCodec13},
+		{id: 0x0E, decode: DecodeCodec14, encode: EncodeCodec14},
+		{id: 0x0F, decode: DecodeCodec15, encode: EncodeCodec15},
+		{id: 0x10, decode: DecodeCodec16, encode: EncodeCodec16},
+	} {
+		RegisterCodec(codec)
+	}
+}
+
+// RegisterCodec makes codec available to TramDecoder and TramEncoder under
+// its ID. Registering a codec with the ID of a built-in or previously
+// registered codec replaces it.
+//
+// Example:
+//
+//	pkg.RegisterCodec(myVendorCodec{})
+//	decoded := pkg.TramDecoder(frame) // frames with myVendorCodec's ID use it
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[codec.ID()] = codec
+}
+
+// LookupCodec returns the codec registered for id.
+func LookupCodec(id byte) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[id]
+	return codec, ok
+}
@@ -1,25 +1,100 @@
 package teltonika_go
 
 import (
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
 	tools_domain "github.com/danieljvsa/teltonika-go/internal/tool"
 	tools "github.com/danieljvsa/teltonika-go/tools"
 )
 
-func ensureRead(data []byte, read int, need int) error {
-	if need < 0 {
-		return fmt.Errorf("invalid length requested")
+// decodeCommandHeader reads the command count and the command type byte
+// that start the data of Codec 12 to 15.
+func decodeCommandHeader(cursor *tools.Cursor, codecID byte, options DecoderOptions) (int64, int64, error) {
+	if err := checkLimit("MaxFrameSize", cursor.Len(), options.MaxFrameSize); err != nil {
+		return 0, 0, tools.AnnotateError(err, codecID, -1, "data", 0)
 	}
-	if read+need > len(data) {
-		return fmt.Errorf("data length too short")
+	numberOfCommands, err := cursor.Uint8()
+	if err != nil {
+		return 0, 0, tools.AnnotateError(err, codecID, -1, "number of commands", -1)
+	}
+	if err := checkLimit("MaxRecords", int(numberOfCommands), options.MaxRecords); err != nil {
+		return 0, 0, tools.AnnotateError(err, codecID, -1, "number of commands", 0)
+	}
+	responseTypeNumber, err := cursor.Uint8()
+	if err != nil {
+		return 0, 0, tools.AnnotateError(err, codecID, -1, "command type", -1)
+	}
+	return int64(numberOfCommands), int64(responseTypeNumber), nil
+}
+
+// decodeCommandSize reads the size of a command or response of Codec 12
+// to 15 and checks it against options.
+func decodeCommandSize(cursor *tools.Cursor, codecID byte, command int, options DecoderOptions) (int, error) {
+	offset := cursor.Offset()
+	responseSize, err := cursor.Uint32()
+	if err != nil {
+		return 0, tools.AnnotateError(err, codecID, command, "command size", -1)
+	}
+	if err := checkLimit("MaxCommandResponseSize", int(responseSize), options.MaxCommandResponseSize); err != nil {
+		return 0, tools.AnnotateError(err, codecID, command, "command size", offset)
+	}
+	return int(responseSize), nil
+}
+
+// decodeCommandTrailer reads the command count that ends the data of
+// Codec 12 to 15 and validates the CRC of TCP frames.
+func decodeCommandTrailer(cursor *tools.Cursor, data []byte, protocol string, codecID byte, numberOfCommands int64) error {
+	if err := checkTrailingCount(cursor, codecID, "number of commands 2", numberOfCommands); err != nil {
+		return err
+	}
+	return checkCRC(data, protocol, codecID)
+}
+
+// checkTrailingCount reads the count repeated after the records or
+// commands and compares it with the count that precedes them.
+func checkTrailingCount(cursor *tools.Cursor, codecID byte, field string, count int64) error {
+	offset := cursor.Offset()
+	count2, err := cursor.Uint8()
+	if err != nil {
+		return tools.AnnotateError(err, codecID, -1, field, -1)
+	}
+	if count != int64(count2) {
+		return tools.AnnotateError(fmt.Errorf("%w: %d != %d", tools.ErrRecordCountMismatch, count, count2), codecID, -1, field, offset)
+	}
+	return nil
+}
+
+// checkCRC validates the CRC at the end of the data of a TCP frame.
+func checkCRC(data []byte, protocol string, codecID byte) error {
+	if protocol == "TCP" && !tools.IsValidCodecData(codecID, data) {
+		return &tools.DecodeError{Err: tools.ErrCRCMismatch, CodecID: codecID, Record: -1, Field: "CRC", Offset: max(len(data)-4, 0)}
 	}
 	return nil
 }
 
+// commandCapacity bounds the preallocated command slice by what the
+// remaining bytes can hold, each command taking at least its size field.
+func commandCapacity(cursor *tools.Cursor, numberOfCommands int64) int {
+	return min(int(numberOfCommands), cursor.Len()/4)
+}
+
+// decodeCommandType translates the command type byte of Codec 12 and 14.
+func decodeCommandType(codecID byte, responseTypeNumber int64) (string, error) {
+	switch responseTypeNumber {
+	case 5:
+		return "Command", nil
+	case 6:
+		return "Response", nil
+	default:
+		return "", &tools.DecodeError{Err: fmt.Errorf("unknown response type: %d", responseTypeNumber), CodecID: codecID, Record: -1, Field: "command type", Offset: 1}
+	}
+}
+
 // DecodeCodec8 decodes Teltonika Codec 08 (AVL data) frames containing GPS records with I/O data.
 // Codec 08 is the most common protocol for transmitting vehicle location and telemetry.
 //
@@ -33,6 +108,7 @@ func ensureRead(data []byte, read int, need int) error {
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing all decoded records
@@ -46,80 +122,8 @@ func ensureRead(data []byte, read int, need int) error {
 //			fmt.Printf("Lat: %f, Lon: %f\n", record.GPSData.Latitude, record.GPSData.Longitude)
 //		}
 //	}
-func DecodeCodec8(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 1); err != nil {
-		return nil, err
-	}
-
-	numberOfRecords, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
-	}
-	read += 1
-	var records []decoder_domain.Record
-	for range int(numberOfRecords) {
-		if err := ensureRead(data, read, 8); err != nil {
-			return nil, err
-		}
-		timestamp, err := tools.CalcTimestamp(data[read : read+8])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing timestamp")
-		}
-		read += 8
-		if err := ensureRead(data, read, 1); err != nil {
-			return nil, err
-		}
-		priority, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing priority: %w", err)
-		}
-		read += 1
-		if err := ensureRead(data, read, 15); err != nil {
-			return nil, err
-		}
-		gpsData, err := tools.DecodeGPSData(data[read : read+15])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing GPS data")
-		}
-		read += 15
-		if err := ensureRead(data, read, 1); err != nil {
-			return nil, err
-		}
-		eventIO, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing event IO: %w", err)
-		}
-		read += 1
-		ioData, err := DecodeIos8(data[read:], 0)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing IO data: %w", err)
-		}
-		read += int(ioData.LastByte)
-		record := &decoder_domain.Record{
-			Timestamp:   timestamp,
-			Priority:    &priority,
-			GPSData:     gpsData,
-			EventIO:     &eventIO,
-			NumberOfIOs: &ioData.NumberOfIOs,
-			IOs:         &ioData.IOs,
-		}
-		records = append(records, *record)
-	}
-
-	if protocol == "TCP" {
-		tram := append([]byte{0x08}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
-	}
-
-	decodedData := &decoder_domain.CodecData{
-		NumberOfRecords: numberOfRecords,
-		Records:         records,
-	}
-	return decodedData, nil
+func DecodeCodec8(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	return decodeAVLData(data, protocol, 0x08, 1, ios8Layout, decoderOptions(options))
 }
 
 // DecodeCodec8Ext decodes Teltonika Codec 8E (Extended AVL data) frames.
@@ -131,6 +135,7 @@ func DecodeCodec8(data []byte, protocol string) (*decoder_domain.CodecData, erro
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing all decoded records
@@ -139,78 +144,8 @@ func DecodeCodec8(data []byte, protocol string) (*decoder_domain.CodecData, erro
 // Example:
 //
 //	codecData, err := DecodeCodec8Ext(frameData, "TCP")
-func DecodeCodec8Ext(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 1); err != nil {
-		return nil, err
-	}
-	numberOfRecords, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
-	}
-	read += 1
-	var records []decoder_domain.Record
-	for range int(numberOfRecords) {
-		if err := ensureRead(data, read, 8); err != nil {
-			return nil, err
-		}
-		timestamp, err := tools.CalcTimestamp(data[read : read+8])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing timestamp")
-		}
-		read += 8
-		if err := ensureRead(data, read, 1); err != nil {
-			return nil, err
-		}
-		priority, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing priority: %w", err)
-		}
-		read += 1
-		if err := ensureRead(data, read, 15); err != nil {
-			return nil, err
-		}
-		gpsData, err := tools.DecodeGPSData(data[read : read+15])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing GPS data")
-		}
-		read += 15
-		if err := ensureRead(data, read, 2); err != nil {
-			return nil, err
-		}
-		eventIO, err := strconv.ParseInt(hex.EncodeToString(data[read:read+2]), 16, 64)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing event IO: %w", err)
-		}
-		read += 2
-		ioData, err := DecodeIos8Extended(data[read:], 0)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing IO data: %w", err)
-		}
-		read += int(ioData.LastByte)
-		record := &decoder_domain.Record{
-			Timestamp:   timestamp,
-			Priority:    &priority,
-			GPSData:     gpsData,
-			EventIO:     &eventIO,
-			NumberOfIOs: &ioData.NumberOfIOs,
-			IOs:         &ioData.IOs,
-		}
-
-		records = append(records, *record)
-	}
-	if protocol == "TCP" {
-		tram := append([]byte{0x8E}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
-	}
-	decodedData := &decoder_domain.CodecData{
-		NumberOfRecords: numberOfRecords,
-		Records:         records,
-	}
-	return decodedData, nil
+func DecodeCodec8Ext(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	return decodeAVLData(data, protocol, 0x8E, 2, ios8ExtendedLayout, decoderOptions(options))
 }
 
 // DecodeCodec16 decodes Teltonika Codec 16 (GPRS/IQ frames with device type).
@@ -220,6 +155,7 @@ func DecodeCodec8Ext(data []byte, protocol string) (*decoder_domain.CodecData, e
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing all decoded records
@@ -228,80 +164,175 @@ func DecodeCodec8Ext(data []byte, protocol string) (*decoder_domain.CodecData, e
 // Example:
 //
 //	codecData, err := DecodeCodec16(frameData, "TCP")
-func DecodeCodec16(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 1); err != nil {
-		return nil, err
-	}
+func DecodeCodec16(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	return decodeAVLData(data, protocol, 0x10, 2, ios16Layout, decoderOptions(options))
+}
+
+// minAVLRecordSize is the size of an AVL record without IO elements:
+// timestamp, priority, GPS element and event IO ID.
+const minAVLRecordSize = 8 + 1 + tools.GPSElementSize + 1
 
-	numberOfRecords, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
+// decodeAVLData decodes the records of Codec 8, 8E and 16, which differ in
+// the size of the event IO ID and in the IO element layout. The fields of
+// all records are stored in a few slices allocated once per frame, so the
+// number of allocations does not grow with the number of records.
+func decodeAVLData(data []byte, protocol string, codecID byte, eventIOSize int, layout ioLayout, options DecoderOptions) (*decoder_domain.CodecData, error) {
+	if err := checkLimit("MaxFrameSize", len(data), options.MaxFrameSize); err != nil {
+		return nil, tools.AnnotateError(err, codecID, -1, "data", 0)
+	}
+	cursor := tools.NewCursor(data)
+	count, err := cursor.Uint8()
 	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
+		return nil, tools.AnnotateError(err, codecID, -1, "number of data", -1)
 	}
-	read += 1
-	var records []decoder_domain.Record
-	for range int(numberOfRecords) {
-		if err := ensureRead(data, read, 8); err != nil {
-			return nil, err
-		}
-		timestamp, err := tools.CalcTimestamp(data[read : read+8])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing timestamp")
-		}
-		read += 8
-		if err := ensureRead(data, read, 1); err != nil {
-			return nil, err
-		}
-		priority, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing priority: %w", err)
-		}
-		read += 1
-		if err := ensureRead(data, read, 15); err != nil {
-			return nil, err
-		}
-		gpsData, err := tools.DecodeGPSData(data[read : read+15])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing GPS data")
-		}
-		read += 15
-		if err := ensureRead(data, read, 2); err != nil {
-			return nil, err
-		}
-		eventIO, err := strconv.ParseInt(hex.EncodeToString(data[read:read+2]), 16, 64)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing event IO: %w", err)
-		}
-		read += 2
-		ioData, err := DecodeIos16(data[read:], 0)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing IO data: %w", err)
-		}
-		read += int(ioData.LastByte)
-		record := &decoder_domain.Record{
-			Timestamp:   timestamp,
-			Priority:    &priority,
-			GPSData:     gpsData,
-			EventIO:     &eventIO,
-			NumberOfIOs: &ioData.NumberOfIOs,
-			IOs:         &ioData.IOs,
-		}
-		records = append(records, *record)
+	numberOfRecords := int(count)
+	if err := checkLimit("MaxRecords", numberOfRecords, options.MaxRecords); err != nil {
+		return nil, tools.AnnotateError(err, codecID, -1, "number of data", 0)
+	}
+	// Every record takes at least minAVLRecordSize bytes, so a count the
+	// data cannot hold is rejected before allocating for it.
+	capacity := min(numberOfRecords, cursor.Len()/minAVLRecordSize)
+	if capacity < numberOfRecords && !options.Lenient {
+		return nil, &tools.DecodeError{Err: tools.ErrTruncated, CodecID: codecID, Record: -1, Field: "number of data", Offset: 0}
 	}
 
-	if protocol == "TCP" {
-		tram := append([]byte{0x10}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
+	records := make([]decoder_domain.Record, capacity)
+	timestamps := make([]time.Time, capacity)
+	gpsData := make([]tools_domain.GPSData, capacity)
+	values := make([]int64, 3*capacity) // priority, event IO and IO count of each record
+	ios := make([][]io_domain.IOData, capacity)
+	iosEnd := make([]int, capacity)
+	var generationTypes []decoder_domain.GenerationType
+	if layout.generationType {
+		generationTypes = make([]decoder_domain.GenerationType, capacity)
+	}
+	// IO values cannot outgrow the remaining data, so their hex buffer is
+	// sized once for the frame.
+	buffer := ioBuffer{values: make([]byte, 0, 2*cursor.Len())}
+	decoded := 0
+	failureStart := 0
+	var failure error
+	for i := range numberOfRecords {
+		recordStart := cursor.Offset()
+		if i < capacity {
+			var generationType *decoder_domain.GenerationType
+			if generationTypes != nil {
+				generationType = &generationTypes[i]
+			}
+			failure = decodeAVLRecord(&cursor, &buffer, eventIOSize, layout, options, &timestamps[i], &gpsData[i], values[3*i:3*i+3], generationType)
+		} else {
+			failure = tools.NewDecodeError(tools.ErrTruncated, "timestamp", recordStart)
+		}
+		if failure != nil {
+			failure = tools.AnnotateError(failure, codecID, i, "", -1)
+			if !options.Lenient {
+				return nil, failure
+			}
+			failureStart = recordStart
+			break
+		}
+		iosEnd[i] = len(buffer.ios)
+		records[i] = decoder_domain.Record{
+			Timestamp:   &timestamps[i],
+			Priority:    &values[3*i],
+			GPSData:     &gpsData[i],
+			EventIO:     &values[3*i+1],
+			NumberOfIOs: &values[3*i+2],
+			IOs:         &ios[i],
+		}
+		if generationTypes != nil {
+			records[i].GenerationType = &generationTypes[i]
+		}
+		decoded++
+	}
+	buffer.finish()
+	start := 0
+	for i, end := range iosEnd[:decoded] {
+		ios[i] = buffer.ios[start:end:end]
+		start = end
 	}
 
+	// The repeated count is only where expected when every record was
+	// decoded. Count mismatches are reported before CRC failures, since a
+	// frame with the wrong structure cannot be trusted to end in its CRC.
+	var countErr error
+	if failure == nil {
+		countErr = checkTrailingCount(&cursor, codecID, "number of data 2", int64(numberOfRecords))
+	}
+	crcErr := checkCRC(data, protocol, codecID)
 	decodedData := &decoder_domain.CodecData{
-		NumberOfRecords: numberOfRecords,
-		Records:         records,
+		NumberOfRecords: int64(numberOfRecords),
+		Records:         records[:decoded],
 	}
-	return decodedData, nil
+	if !options.Lenient {
+		if countErr != nil {
+			return nil, countErr
+		}
+		if crcErr != nil {
+			return nil, crcErr
+		}
+		return decodedData, nil
+	}
+
+	diagnostics := &decoder_domain.Diagnostics{
+		Complete:      failure == nil,
+		FailureOffset: -1,
+		CountMatched:  failure == nil && countErr == nil,
+		LengthMatched: true,
+		CRCChecked:    protocol == "TCP",
+		CRCMatched:    protocol == "TCP" && crcErr == nil,
+	}
+	decodedData.Diagnostics = diagnostics
+	if failure == nil {
+		if countErr != nil {
+			return decodedData, countErr
+		}
+		return decodedData, crcErr
+	}
+	var decodeErr *tools.DecodeError
+	if errors.As(failure, &decodeErr) {
+		diagnostics.FailureOffset = decodeErr.Offset
+	}
+	diagnostics.Trailing = data[failureStart:]
+	return decodedData, failure
+}
+
+// decodeAVLRecord decodes the fields of one AVL record into timestamp,
+// gpsData, values (priority, event IO ID and IO count) and, for layouts
+// that carry one, generationType, and its IO elements into buffer.
+func decodeAVLRecord(cursor *tools.Cursor, buffer *ioBuffer, eventIOSize int, layout ioLayout, options DecoderOptions, timestamp *time.Time, gpsData *tools_domain.GPSData, values []int64, generationType *decoder_domain.GenerationType) error {
+	milliseconds, err := cursor.Uint64()
+	if err != nil {
+		return tools.AnnotateError(err, 0, -1, "timestamp", -1)
+	}
+	*timestamp = time.UnixMilli(int64(milliseconds)).UTC()
+	priority, err := cursor.Uint8()
+	if err != nil {
+		return tools.AnnotateError(err, 0, -1, "priority", -1)
+	}
+	gps, err := cursor.Bytes(tools.GPSElementSize)
+	if err != nil {
+		return tools.AnnotateError(err, 0, -1, "GPS element", -1)
+	}
+	if err := tools.ReadGPSData(gps, gpsData); err != nil {
+		err = tools.ShiftErrorOffset(err, cursor.Offset()-len(gps))
+		return tools.AnnotateError(err, 0, -1, "GPS element", -1)
+	}
+	eventIO, err := cursor.Uint(eventIOSize)
+	if err != nil {
+		return tools.AnnotateError(err, 0, -1, "event IO ID", -1)
+	}
+	numberOfIOs, generation, err := buffer.decode(cursor, layout, options)
+	if err != nil {
+		return tools.AnnotateError(err, 0, -1, "IO elements", -1)
+	}
+	if generationType != nil {
+		*generationType = generation
+	}
+	values[0] = int64(priority)
+	values[1] = int64(eventIO)
+	values[2] = numberOfIOs
+	return nil
 }
 
 // DecodeCodec12 decodes Teltonika Codec 12 (Command response codec).
@@ -321,6 +352,7 @@ func DecodeCodec16(data []byte, protocol string) (*decoder_domain.CodecData, err
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing command responses
@@ -338,78 +370,40 @@ func DecodeCodec16(data []byte, protocol string) (*decoder_domain.CodecData, err
 //			}
 //		}
 //	}
-func DecodeCodec12(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 2); err != nil {
-		return nil, err
-	}
-	numberOfCommands, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
+func DecodeCodec12(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	limits := decoderOptions(options)
+	cursor := tools.NewCursor(data)
+	numberOfCommands, responseTypeNumber, err := decodeCommandHeader(&cursor, 0x0C, limits)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
-	}
-	read += 1
-	if err := ensureRead(data, read, 1); err != nil {
 		return nil, err
 	}
-	responseTypeNumber, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
+	responseType, err := decodeCommandType(0x0C, responseTypeNumber)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing response type: %w", err)
-	}
-	var responseType string
-	switch responseTypeNumber {
-	case 5:
-		responseType = "Command"
-	case 6:
-		responseType = "Response"
-	default:
-		return nil, fmt.Errorf("unknown response type: %d", responseTypeNumber)
+		return nil, err
 	}
-	read += 1
-	var command_responses []tools_domain.CommandResponse
-	for range int(numberOfCommands) {
-		if err := ensureRead(data, read, 4); err != nil {
+	command_responses := make([]tools_domain.CommandResponse, 0, commandCapacity(&cursor, numberOfCommands))
+	for i := range int(numberOfCommands) {
+		responseSize, err := decodeCommandSize(&cursor, 0x0C, i, limits)
+		if err != nil {
 			return nil, err
 		}
-		responseSize, err := strconv.ParseInt(hex.EncodeToString(data[read:read+4]), 16, 64)
+		offset := cursor.Offset()
+		response, err := cursor.Bytes(responseSize)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing size of message: %w", err)
+			return nil, tools.AnnotateError(err, 0x0C, i, "command", -1)
 		}
-		read += 4
-		if responseSize < 0 {
-			return nil, fmt.Errorf("invalid response size")
-		}
-		if err := ensureRead(data, read, int(responseSize)); err != nil {
-			return nil, err
-		}
-		hexString, message, err := tools.DecodeToHexThenASCII(data[read:read+int(responseSize)], []byte{}, int(responseSize))
+		hexString, message, err := tools.DecodeToHexThenASCII(response, []byte{}, len(response))
 		if err != nil {
-			return nil, fmt.Errorf("error parsing message: %w", err)
+			return nil, tools.AnnotateError(err, 0x0C, i, "command", offset)
 		}
-		read += int(responseSize)
-		commandResponse := &tools_domain.CommandResponse{
+		command_responses = append(command_responses, tools_domain.CommandResponse{
 			Response:   message,
 			HexMessage: hexString,
-		}
-		command_responses = append(command_responses, *commandResponse)
+		})
 	}
-	if err := ensureRead(data, read, 1); err != nil {
+	if err := decodeCommandTrailer(&cursor, data, protocol, 0x0C, numberOfCommands); err != nil {
 		return nil, err
 	}
-	responseType2, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing size of message: %w", err)
-	}
-	read += 1
-	if numberOfCommands != responseType2 {
-		return nil, fmt.Errorf("response type mismatch")
-	}
-	if protocol == "TCP" {
-		tram := append([]byte{0x0C}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
-	}
 	decodedData := &decoder_domain.CodecData{
 		NumberOfRecords: numberOfCommands,
 		Records: []decoder_domain.Record{
@@ -437,6 +431,7 @@ func DecodeCodec12(data []byte, protocol string) (*decoder_domain.CodecData, err
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing command responses with timestamps
@@ -454,92 +449,58 @@ func DecodeCodec12(data []byte, protocol string) (*decoder_domain.CodecData, err
 //			}
 //		}
 //	}
-func DecodeCodec13(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 2); err != nil {
-		return nil, err
-	}
-	numberOfCommands, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
+func DecodeCodec13(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	limits := decoderOptions(options)
+	cursor := tools.NewCursor(data)
+	numberOfCommands, responseTypeNumber, err := decodeCommandHeader(&cursor, 0x0D, limits)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
-	}
-	read += 1
-	if err := ensureRead(data, read, 1); err != nil {
 		return nil, err
 	}
-	responseTypeNumber, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
+	responseType, err := decodeCommandType(0x0D, responseTypeNumber)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing response type: %w", err)
+		return nil, err
 	}
-	var responseType string
-	switch responseTypeNumber {
-	case 5:
-		responseType = "Command"
-		return nil, fmt.Errorf("codec 13 does not support command type: %s (Only supports Response)", responseType)
-	case 6:
-		responseType = "Response"
-	default:
-		return nil, fmt.Errorf("unknown response type: %d", responseTypeNumber)
+	if responseType == "Command" {
+		return nil, &tools.DecodeError{Err: fmt.Errorf("codec 13 does not support command type: %s (Only supports Response)", responseType), CodecID: 0x0D, Record: -1, Field: "command type", Offset: 1}
 	}
-	read += 1
-	var command_responses []tools_domain.CommandResponse
-	for range int(numberOfCommands) {
-		if err := ensureRead(data, read, 4); err != nil {
-			return nil, err
-		}
-		responseSize, err := strconv.ParseInt(hex.EncodeToString(data[read:read+4]), 16, 64)
+	command_responses := make([]tools_domain.CommandResponse, 0, commandCapacity(&cursor, numberOfCommands))
+	for i := range int(numberOfCommands) {
+		offset := cursor.Offset()
+		responseSize, err := decodeCommandSize(&cursor, 0x0D, i, limits)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing size of message: %w", err)
+			return nil, err
 		}
-		read += 4
 		if responseSize < 8 {
-			return nil, fmt.Errorf("response size too small")
+			return nil, &tools.DecodeError{Err: fmt.Errorf("response size too small"), CodecID: 0x0D, Record: i, Field: "command size", Offset: offset}
 		}
-		if err := ensureRead(data, read, 8); err != nil {
-			return nil, err
+		timestampBytes, err := cursor.Bytes(8)
+		if err != nil {
+			return nil, tools.AnnotateError(err, 0x0D, i, "timestamp", -1)
 		}
-		timestamp, err := tools.CalcTimestamp(data[read : read+8])
+		timestamp, err := tools.CalcTimestamp(timestampBytes)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing timestamp: %w", err)
+			err = tools.ShiftErrorOffset(err, cursor.Offset()-8)
+			return nil, tools.AnnotateError(err, 0x0D, i, "timestamp", -1)
 		}
-		read += 8
 		commandSize := responseSize - 8
-		if commandSize < 0 {
-			return nil, fmt.Errorf("invalid command size")
-		}
-		if err := ensureRead(data, read, int(commandSize)); err != nil {
-			return nil, err
+		offset = cursor.Offset()
+		command, err := cursor.Bytes(commandSize)
+		if err != nil {
+			return nil, tools.AnnotateError(err, 0x0D, i, "command", -1)
 		}
-		hexString, message, err := tools.DecodeToHexThenASCII(data[read:read+int(commandSize)], []byte{}, int(commandSize))
+		hexString, message, err := tools.DecodeToHexThenASCII(command, []byte{}, commandSize)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing message: %w", err)
+			return nil, tools.AnnotateError(err, 0x0D, i, "command", offset)
 		}
-		read += int(commandSize)
-		commandResponse := &tools_domain.CommandResponse{
+		command_responses = append(command_responses, tools_domain.CommandResponse{
 			Timestamp:  timestamp,
 			Response:   message,
 			HexMessage: hexString,
-		}
-		command_responses = append(command_responses, *commandResponse)
+		})
 	}
-	if err := ensureRead(data, read, 1); err != nil {
+	if err := decodeCommandTrailer(&cursor, data, protocol, 0x0D, numberOfCommands); err != nil {
 		return nil, err
 	}
-	responseType2, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing size of message: %w", err)
-	}
-	read += 1
-	if numberOfCommands != responseType2 {
-		return nil, fmt.Errorf("response type mismatch: %d != %d", numberOfCommands, responseType2)
-	}
-	if protocol == "TCP" {
-		tram := append([]byte{0x0D}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
-	}
 	decodedData := &decoder_domain.CodecData{
 		NumberOfRecords: numberOfCommands,
 		Records: []decoder_domain.Record{
@@ -566,6 +527,7 @@ func DecodeCodec13(data []byte, protocol string) (*decoder_domain.CodecData, err
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing command responses
@@ -583,84 +545,52 @@ func DecodeCodec13(data []byte, protocol string) (*decoder_domain.CodecData, err
 //			}
 //		}
 //	}
-func DecodeCodec14(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 2); err != nil {
-		return nil, err
-	}
-	numberOfCommands, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
+func DecodeCodec14(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	limits := decoderOptions(options)
+	cursor := tools.NewCursor(data)
+	numberOfCommands, responseTypeNumber, err := decodeCommandHeader(&cursor, 0x0E, limits)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
-	}
-	read += 1
-	if err := ensureRead(data, read, 1); err != nil {
 		return nil, err
 	}
-	responseTypeNumber, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
+	responseType, err := decodeCommandType(0x0E, responseTypeNumber)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing response type: %w", err)
-	}
-	var responseType string
-	switch responseTypeNumber {
-	case 5:
-		responseType = "Command"
-	case 6:
-		responseType = "Response"
-	default:
-		return nil, fmt.Errorf("unknown response type: %d", responseTypeNumber)
+		return nil, err
 	}
-	read += 1
-	var command_responses []tools_domain.CommandResponse
-	for range int(numberOfCommands) {
-		if err := ensureRead(data, read, 4); err != nil {
-			return nil, err
-		}
-		responseSize, err := strconv.ParseInt(hex.EncodeToString(data[read:read+4]), 16, 64)
+	command_responses := make([]tools_domain.CommandResponse, 0, commandCapacity(&cursor, numberOfCommands))
+	for i := range int(numberOfCommands) {
+		offset := cursor.Offset()
+		responseSize, err := decodeCommandSize(&cursor, 0x0E, i, limits)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing size of message: %w", err)
+			return nil, err
 		}
-		read += 4
 		if responseSize < 8 {
-			return nil, fmt.Errorf("response size too small")
+			return nil, &tools.DecodeError{Err: fmt.Errorf("response size too small"), CodecID: 0x0E, Record: i, Field: "command size", Offset: offset}
 		}
-		if err := ensureRead(data, read, int(responseSize)); err != nil {
-			return nil, err
+		offset = cursor.Offset()
+		response, err := cursor.Bytes(responseSize)
+		if err != nil {
+			return nil, tools.AnnotateError(err, 0x0E, i, "command", -1)
 		}
-		imei, err := tools.DecodeIMEI(data[read : read+int(responseSize)])
+		// The response starts with the IMEI; HexMessage and Response keep
+		// it, as they always have.
+		imei, err := tools.DecodeIMEI(response[:8])
 		if err != nil {
-			return nil, fmt.Errorf("error parsing IMEI: %w", err)
+			return nil, tools.AnnotateError(err, 0x0E, i, "IMEI", offset)
 		}
-		hexString, message, err := tools.DecodeToHexThenASCII(data[read:read+int(responseSize)], []byte{}, int(responseSize))
+		hexString, message, err := tools.DecodeToHexThenASCII(response, []byte{}, len(response))
 		if err != nil {
-			return nil, fmt.Errorf("error parsing message: %w", err)
+			return nil, tools.AnnotateError(err, 0x0E, i, "command", offset)
 		}
-		read += int(responseSize)
-		commandResponse := &tools_domain.CommandResponse{
+		command_responses = append(command_responses, tools_domain.CommandResponse{
 			Response:    message,
 			HexMessage:  hexString,
 			IMEI:        imei,
 			CommandType: responseType,
-		}
-		command_responses = append(command_responses, *commandResponse)
+		})
 	}
-	if err := ensureRead(data, read, 1); err != nil {
+	if err := decodeCommandTrailer(&cursor, data, protocol, 0x0E, numberOfCommands); err != nil {
 		return nil, err
 	}
-	responseType2, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing size of message: %w", err)
-	}
-	read += 1
-	if numberOfCommands != responseType2 {
-		return nil, fmt.Errorf("response type mismatch")
-	}
-	if protocol == "TCP" {
-		tram := append([]byte{0x0E}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
-	}
 	decodedData := &decoder_domain.CodecData{
 		NumberOfRecords: numberOfCommands,
 		Records: []decoder_domain.Record{
@@ -689,6 +619,7 @@ func DecodeCodec14(data []byte, protocol string) (*decoder_domain.CodecData, err
 // Parameters:
 //   - data: decoded frame data without header/CRC
 //   - protocol: "TCP" or "UDP" - determines whether to validate CRC
+//   - options: optional resource limits, the defaults of DecoderOptions when omitted
 //
 // Returns:
 //   - *decoder_domain.CodecData: structure containing command responses
@@ -707,92 +638,61 @@ func DecodeCodec14(data []byte, protocol string) (*decoder_domain.CodecData, err
 //		}
 //	}
 
-func DecodeCodec15(data []byte, protocol string) (*decoder_domain.CodecData, error) {
-	read := 0
-	if err := ensureRead(data, read, 2); err != nil {
-		return nil, err
-	}
-	numberOfCommands, err := strconv.ParseInt(hex.EncodeToString(data[:read+1]), 16, 64)
+func DecodeCodec15(data []byte, protocol string, options ...DecoderOptions) (*decoder_domain.CodecData, error) {
+	limits := decoderOptions(options)
+	cursor := tools.NewCursor(data)
+	numberOfCommands, responseTypeNumber, err := decodeCommandHeader(&cursor, 0x0F, limits)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing number of records: %w", err)
-	}
-	read += 1
-	if err := ensureRead(data, read, 1); err != nil {
 		return nil, err
 	}
-	responseTypeNumber, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing response type: %w", err)
-	}
-	responseType := fmt.Sprintf("%d", responseTypeNumber)
-	read += 1
-	var command_responses []tools_domain.CommandResponse
-	for range int(numberOfCommands) {
-		if err := ensureRead(data, read, 4); err != nil {
-			return nil, err
-		}
-		responseSize, err := strconv.ParseInt(hex.EncodeToString(data[read:read+4]), 16, 64)
+	responseType := strconv.FormatInt(responseTypeNumber, 10)
+	command_responses := make([]tools_domain.CommandResponse, 0, commandCapacity(&cursor, numberOfCommands))
+	for i := range int(numberOfCommands) {
+		offset := cursor.Offset()
+		responseSize, err := decodeCommandSize(&cursor, 0x0F, i, limits)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing size of message: %w", err)
+			return nil, err
 		}
-		read += 4
 		if responseSize < 12 {
-			return nil, fmt.Errorf("response size too small")
+			return nil, &tools.DecodeError{Err: fmt.Errorf("response size too small"), CodecID: 0x0F, Record: i, Field: "command size", Offset: offset}
 		}
-		if err := ensureRead(data, read, 4); err != nil {
-			return nil, err
+		timestampBytes, err := cursor.Bytes(4)
+		if err != nil {
+			return nil, tools.AnnotateError(err, 0x0F, i, "timestamp", -1)
 		}
-		timestamp, err := tools.CalcTimestampSeconds(data[read : read+4])
+		timestamp, err := tools.CalcTimestampSeconds(timestampBytes)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing timestamp: %w", err)
+			err = tools.ShiftErrorOffset(err, cursor.Offset()-4)
+			return nil, tools.AnnotateError(err, 0x0F, i, "timestamp", -1)
 		}
-		read += 4
-		if err := ensureRead(data, read, 8); err != nil {
-			return nil, err
+		imeiBytes, err := cursor.Bytes(8)
+		if err != nil {
+			return nil, tools.AnnotateError(err, 0x0F, i, "IMEI", -1)
 		}
-		imei, err := tools.DecodeIMEI(data[read : read+8])
+		imei, err := tools.DecodeIMEI(imeiBytes)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing IMEI: %w", err)
+			return nil, tools.AnnotateError(err, 0x0F, i, "IMEI", cursor.Offset()-8)
 		}
-		read += 8
 		commandSize := responseSize - 12
-		if commandSize < 0 {
-			return nil, fmt.Errorf("invalid command size")
-		}
-		if err := ensureRead(data, read, int(commandSize)); err != nil {
-			return nil, err
+		offset = cursor.Offset()
+		command, err := cursor.Bytes(commandSize)
+		if err != nil {
+			return nil, tools.AnnotateError(err, 0x0F, i, "command", -1)
 		}
-		hexString, message, err := tools.DecodeToHexThenASCII(data[read:read+int(commandSize)], []byte{}, int(commandSize))
+		hexString, message, err := tools.DecodeToHexThenASCII(command, []byte{}, commandSize)
 		if err != nil {
-			return nil, fmt.Errorf("error parsing message: %w", err)
+			return nil, tools.AnnotateError(err, 0x0F, i, "command", offset)
 		}
-		read += int(commandSize)
-		commandResponse := &tools_domain.CommandResponse{
+		command_responses = append(command_responses, tools_domain.CommandResponse{
 			Timestamp:  timestamp,
 			Response:   message,
 			HexMessage: hexString,
 			IMEI:       imei,
-		}
-		command_responses = append(command_responses, *commandResponse)
+		})
 	}
-	if err := ensureRead(data, read, 1); err != nil {
+	if err := decodeCommandTrailer(&cursor, data, protocol, 0x0F, numberOfCommands); err != nil {
 		return nil, err
 	}
-	responseType2, err := strconv.ParseInt(hex.EncodeToString(data[read:read+1]), 16, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing size of message: %w", err)
-	}
-	read += 1
-	if numberOfCommands != responseType2 {
-		return nil, fmt.Errorf("response type mismatch: %d != %d", numberOfCommands, responseType2)
-	}
-	if protocol == "TCP" {
-		tram := append([]byte{0x0F}, data...)
-		checkCRC := tools.IsValidTram(tram)
-		if !checkCRC {
-			return nil, fmt.Errorf("CRC is not valid")
-		}
-	}
 	decodedData := &decoder_domain.CodecData{
 		NumberOfRecords: numberOfCommands,
 		Records: []decoder_domain.Record{
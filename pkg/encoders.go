@@ -82,7 +82,6 @@ func encodeAVLCodec(codecData *decoder_domain.CodecData, codecID byte) ([]byte,
 			return nil, err
 		}
 		buffer.Write(gpsBytes)
-		buffer.WriteByte(0x00)
 
 		switch codecID {
 		case 0x08:
@@ -118,7 +117,7 @@ func encodeAVLCodec(codecData *decoder_domain.CodecData, codecID byte) ([]byte,
 			if err != nil {
 				return nil, err
 			}
-			ioBytes, _, err := tools.EncodeIOData16(resolveRecordIOs(record), generationType)
+			ioBytes, _, err := tools.EncodeIOData16Generation(resolveRecordIOs(record), generationType)
 			if err != nil {
 				return nil, err
 			}
@@ -225,19 +224,24 @@ func resolveRecordIOs(record decoder_domain.Record) []io_domain.IOData {
 	return *record.IOs
 }
 
-func resolveGenerationType(record decoder_domain.Record) (string, error) {
+// resolveGenerationType returns the Codec 16 generation type of record:
+// its GenerationType, or else the name in its "generation_type" attribute.
+func resolveGenerationType(record decoder_domain.Record) (byte, error) {
+	if record.GenerationType != nil {
+		return byte(*record.GenerationType), nil
+	}
 	if record.Attributes == nil {
-		return "", fmt.Errorf("codec 16 requires generation type in record attributes")
+		return 0, fmt.Errorf("codec 16 requires a record generation type")
 	}
 	value, ok := (*record.Attributes)["generation_type"]
 	if !ok {
-		return "", fmt.Errorf("codec 16 requires generation_type attribute")
+		return 0, fmt.Errorf("codec 16 requires a record generation type")
 	}
 	genType, ok := value.(string)
 	if !ok {
-		return "", fmt.Errorf("generation_type must be a string")
+		return 0, fmt.Errorf("generation_type must be a string")
 	}
-	return genType, nil
+	return tools.EncodeGenerationType(genType)
 }
 
 func resolveCommandResponses(codecData *decoder_domain.CodecData) ([]tools_domain.CommandResponse, string, error) {
@@ -0,0 +1,21 @@
+package teltonika_go
+
+import (
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// Decode failure kinds, shared with the tools package so that errors.Is
+// matches them whichever package produced the error.
+var (
+	ErrTruncated           = tools.ErrTruncated
+	ErrCRCMismatch         = tools.ErrCRCMismatch
+	ErrUnknownCodec        = tools.ErrUnknownCodec
+	ErrRecordCountMismatch = tools.ErrRecordCountMismatch
+	ErrInvalidHeader       = tools.ErrInvalidHeader
+	ErrLengthMismatch      = tools.ErrLengthMismatch
+)
+
+// DecodeError locates a decode failure by codec ID, record index, field
+// name and byte offset. It is the tools.DecodeError type, so errors.As
+// with either name matches errors from both packages.
+type DecodeError = tools.DecodeError
@@ -0,0 +1,137 @@
+package teltonika_go
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// DefaultMaxFrameSize is the frame size limit used by a Framer whose
+// maximum is not set.
+const DefaultMaxFrameSize = 64 * 1024
+
+// ErrFrameTooLarge is returned by a Framer when a frame announces more
+// bytes than its maximum frame size allows.
+var ErrFrameTooLarge = errors.New("frame exceeds maximum frame size")
+
+// readChunkSize is the number of bytes a Framer asks the reader for at once.
+const readChunkSize = 4096
+
+// Framer cuts a TCP byte stream into complete Teltonika frames. Reads from
+// the underlying reader may split a frame or contain several frames; the
+// Framer buffers leftover bytes and returns one complete frame per call.
+//
+// A TCP frame is made of the 4-byte preamble, the 4-byte data length, the
+// data (codec ID, records, record count) and the 4-byte CRC.
+//
+// Example:
+//
+//	framer := NewFramer(conn, 0)
+//	login, err := framer.Login()
+//	for {
+//		frame, err := framer.Next()
+//		if err != nil {
+//			break
+//		}
+//		decoded := TramDecoder(frame)
+//	}
+type Framer struct {
+	reader       io.Reader
+	maxFrameSize int
+	buffer       []byte
+	start        int
+	end          int
+}
+
+// NewFramer returns a Framer reading from reader. A maxFrameSize of zero or
+// less selects DefaultMaxFrameSize.
+func NewFramer(reader io.Reader, maxFrameSize int) *Framer {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+	return &Framer{reader: reader, maxFrameSize: maxFrameSize}
+}
+
+// Login returns the next login packet: the 2-byte IMEI length followed by
+// the IMEI. It must be called before Next on a new connection.
+func (f *Framer) Login() ([]byte, error) {
+	if err := f.fill(2); err != nil {
+		return nil, err
+	}
+	length := int(f.buffer[f.start])<<8 | int(f.buffer[f.start+1])
+	if length == 0 {
+		return nil, tools.NewDecodeError(ErrInvalidHeader, "IMEI length", 0)
+	}
+	return f.take(2 + length)
+}
+
+// Next returns the next complete TCP frame. The returned slice is owned by
+// the caller. At the end of the stream Next returns io.EOF, or
+// io.ErrUnexpectedEOF when the stream stops in the middle of a frame.
+func (f *Framer) Next() ([]byte, error) {
+	if err := f.fill(8); err != nil {
+		return nil, err
+	}
+	header, err := DecodeHeaderTCP(f.buffer[f.start : f.start+8])
+	if err != nil {
+		return nil, err
+	}
+	if header.DataLength < 1 {
+		return nil, tools.NewDecodeError(fmt.Errorf("%w: data length %d", ErrInvalidHeader, header.DataLength), "data length", 4)
+	}
+	return f.take(8 + int(header.DataLength) + 4)
+}
+
+// Buffered returns the bytes read from the stream that do not belong to a
+// returned frame yet.
+func (f *Framer) Buffered() []byte {
+	return f.buffer[f.start:f.end]
+}
+
+// take removes size bytes from the buffer and returns them as a new slice.
+func (f *Framer) take(size int) ([]byte, error) {
+	if size > f.maxFrameSize {
+		return nil, fmt.Errorf("%w: %d > %d", ErrFrameTooLarge, size, f.maxFrameSize)
+	}
+	if err := f.fill(size); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, size)
+	copy(frame, f.buffer[f.start:f.start+size])
+	f.start += size
+	if f.start == f.end {
+		f.start, f.end = 0, 0
+	}
+	return frame, nil
+}
+
+// fill reads from the stream until at least size bytes are buffered.
+func (f *Framer) fill(size int) error {
+	for f.end-f.start < size {
+		if f.start > 0 && len(f.buffer)-f.start < size {
+			f.end = copy(f.buffer, f.buffer[f.start:f.end])
+			f.start = 0
+		}
+		if len(f.buffer)-f.end < readChunkSize && len(f.buffer) < size+readChunkSize {
+			grown := make([]byte, size+readChunkSize)
+			f.end = copy(grown, f.buffer[f.start:f.end])
+			f.start = 0
+			f.buffer = grown
+		}
+
+		n, err := f.reader.Read(f.buffer[f.end:])
+		f.end += n
+		if err != nil {
+			if f.end-f.start >= size {
+				return nil
+			}
+			if errors.Is(err, io.EOF) && f.end > f.start {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
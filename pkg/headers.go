@@ -1,91 +1,61 @@
 package teltonika_go
 
 import (
-	"encoding/hex"
+	"encoding/binary"
 	"fmt"
-	"strconv"
 
 	header_domain "github.com/danieljvsa/teltonika-go/internal/header"
 	tools "github.com/danieljvsa/teltonika-go/tools"
 )
 
 func DecodeHeaderTCP(header []byte) (*header_domain.HeaderDataTCP, error) {
-	read := 0
-	zeroBytes := hex.EncodeToString(header[:read+4])
-	if zeroBytes != "00000000" {
-		return nil, fmt.Errorf("header is not valid")
+	cursor := tools.NewCursor(header)
+	preamble, err := cursor.Uint32()
+	if err != nil {
+		return nil, tools.AnnotateError(err, 0, -1, "preamble", -1)
+	}
+	if preamble != 0 {
+		return nil, tools.NewDecodeError(tools.ErrInvalidHeader, "preamble", 0)
 	}
-	read += 4
 
-	dataLength, err := strconv.ParseInt(hex.EncodeToString(header[read:read+4]), 16, 64)
+	dataLength, err := cursor.Uint32()
 	if err != nil {
-		return nil, err
+		return nil, tools.AnnotateError(err, 0, -1, "data length", -1)
 	}
-	read += 4
 
 	headerData := &header_domain.HeaderDataTCP{
 		Header:     "00000000",
-		DataLength: dataLength,
-		LastByte:   read,
+		DataLength: int64(dataLength),
+		LastByte:   cursor.Offset(),
 	}
 	return headerData, nil
 }
 
 func DecodeHeaderUDP(header []byte) (*header_domain.HeaderDataUDP, error) {
-	read := 0
 	if len(header) < 8 {
-		return nil, fmt.Errorf("header is too small")
-	}
-
-	length, err := strconv.ParseInt(hex.EncodeToString(header[:read+2]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	read += 2
-
-	if len(header) < read+2 {
-		return nil, fmt.Errorf("header length exceeds available data")
-	}
-	packetID, err := strconv.ParseInt(hex.EncodeToString(header[read:read+2]), 16, 64)
-	if err != nil {
-		return nil, err
+		return nil, tools.NewDecodeError(tools.ErrTruncated, "header", len(header))
 	}
-	read += 3
 
-	if len(header) < read+1 {
-		return nil, fmt.Errorf("header length exceeds available data")
-	}
-	avlPacketID, err := strconv.ParseInt(hex.EncodeToString(header[read:read+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	read += 1
+	// The fixed part of the header fits in the 8 bytes checked above.
+	cursor := tools.NewCursor(header)
+	length, _ := cursor.Uint16()
+	packetID, _ := cursor.Uint16()
+	cursor.Skip(1) // packet type
+	avlPacketID, _ := cursor.Uint8()
+	imeiLength, _ := cursor.Uint16()
 
-	if len(header) < read+2 {
-		return nil, fmt.Errorf("header length exceeds available data")
-	}
-	imeiLength, err := strconv.ParseInt(hex.EncodeToString(header[read:read+2]), 16, 64)
+	imei, err := cursor.Bytes(int(imeiLength))
 	if err != nil {
-		return nil, err
-	}
-	read += 2
-
-	if len(header) < read+int(imeiLength) {
-		return nil, fmt.Errorf("header length exceeds available data")
+		return nil, tools.AnnotateError(err, 0, -1, "IMEI", -1)
 	}
-	hexImei := hex.EncodeToString(header[read : read+int(imeiLength)])
-	bytesImei, _ := hex.DecodeString(hexImei)
-	imei := string(bytesImei)
-
-	read += int(imeiLength)
 
 	data := &header_domain.HeaderDataUDP{
-		Length:      length,
-		PacketID:    packetID,
-		AVLPacketID: avlPacketID,
-		IMEILength:  imeiLength,
-		IMEI:        imei,
-		LastByte:    read,
+		Length:      int64(length),
+		PacketID:    int64(packetID),
+		AVLPacketID: int64(avlPacketID),
+		IMEILength:  int64(imeiLength),
+		IMEI:        string(imei),
+		LastByte:    cursor.Offset(),
 	}
 	return data, nil
 }
@@ -135,3 +105,46 @@ func DecodeHeader(header []byte) (*header_domain.HeaderData, error) {
 
 	return headerData, nil
 }
+
+// EncodeHeaderTCP builds the 8-byte TCP header: the zero preamble followed
+// by the data length, which counts the codec ID, the records and the
+// trailing record count but not the CRC.
+func EncodeHeaderTCP(dataLength int64) ([]byte, error) {
+	if dataLength < 1 || dataLength > int64(^uint32(0)) {
+		return nil, fmt.Errorf("data length out of range: %d", dataLength)
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[4:8], uint32(dataLength))
+	return header, nil
+}
+
+// EncodeHeaderUDP builds the UDP header for a body of bodyLength bytes
+// (codec ID and records): length, packet ID, the 0x01 packet type, AVL
+// packet ID, IMEI length and IMEI. The length field is computed from the
+// IMEI and bodyLength; header.Length is ignored.
+func EncodeHeaderUDP(header *header_domain.HeaderDataUDP, bodyLength int) ([]byte, error) {
+	if header == nil {
+		return nil, fmt.Errorf("UDP header data is nil")
+	}
+	if header.IMEI == "" {
+		return nil, fmt.Errorf("IMEI is empty")
+	}
+	if header.PacketID < 0 || header.PacketID > 65535 {
+		return nil, fmt.Errorf("packet ID out of uint16 range: %d", header.PacketID)
+	}
+	if header.AVLPacketID < 0 || header.AVLPacketID > 255 {
+		return nil, fmt.Errorf("AVL packet ID out of uint8 range: %d", header.AVLPacketID)
+	}
+	length := 2 + 1 + 1 + 2 + len(header.IMEI) + bodyLength
+	if length > 65535 {
+		return nil, fmt.Errorf("UDP packet too large: %d bytes", length)
+	}
+
+	data := make([]byte, 8, 8+len(header.IMEI))
+	binary.BigEndian.PutUint16(data[0:2], uint16(length))
+	binary.BigEndian.PutUint16(data[2:4], uint16(header.PacketID))
+	data[4] = 0x01
+	data[5] = byte(header.AVLPacketID)
+	binary.BigEndian.PutUint16(data[6:8], uint16(len(header.IMEI)))
+	return append(data, header.IMEI...), nil
+}
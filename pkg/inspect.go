@@ -0,0 +1,548 @@
+package teltonika_go
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	inspect_domain "github.com/danieljvsa/teltonika-go/internal/inspect"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// Inspect dissects a frame or login packet into a tree of fields, reading
+// it the way TramDecoder and LoginDecoder do: every field has its offset
+// in frame, its length, its raw bytes and its interpreted value, from the
+// header down to each IO element and the CRC.
+//
+// Inspect is meant for frames a decoder rejects, so it goes on where it
+// can. A length, count or CRC mismatch is set as the Error of its field
+// and the walk continues; truncated data ends the walk, and the bytes left
+// are put in a field named "Unparsed". The returned error is the first
+// problem found, a *DecodeError located like those of TramDecoder, and is
+// returned together with the tree.
+//
+// Example:
+//
+//	root, err := Inspect(frame)
+//	root.Walk(func(field *inspect_domain.Field, depth int) {
+//		fmt.Printf("%4d %3d %*s%s: %s\n", field.Offset, field.Length, 2*depth, "", field.Name, field.Value)
+//	})
+func Inspect(frame []byte) (*inspect_domain.Field, error) {
+	in := &inspector{frame: frame, cursor: tools.NewCursor(frame), record: -1}
+	root := &inspect_domain.Field{Name: "UDP frame", Length: len(frame), Raw: frame}
+	var err error
+	switch {
+	case IsLoginPacket(frame):
+		root.Name = "Login packet"
+		err = in.login(root)
+	case len(frame) >= 4 && binary.BigEndian.Uint32(frame) == 0:
+		root.Name = "TCP frame"
+		err = in.tcp(root)
+	default:
+		err = in.udp(root)
+	}
+	in.limit(len(frame))
+	if err != nil {
+		in.unparsed(root, "Unparsed", err)
+	} else if in.cursor.Len() > 0 {
+		in.unparsed(root, "Trailing bytes", tools.NewDecodeError(ErrLengthMismatch, "trailing bytes", in.cursor.Offset()))
+	}
+	return root, in.err
+}
+
+var codecNames = map[byte]string{
+	0x08: "Codec 8",
+	0x8E: "Codec 8 Extended",
+	0x0C: "Codec 12",
+	0x0D: "Codec 13",
+	0x0E: "Codec 14",
+	0x0F: "Codec 15",
+	0x10: "Codec 16",
+}
+
+var priorityNames = [...]string{"Low", "High", "Panic"}
+
+// inspector walks a frame for Inspect. The cursor reads the whole frame,
+// so its offsets are frame offsets; limit hides the CRC from the codec
+// data.
+type inspector struct {
+	frame   []byte
+	cursor  tools.Cursor
+	codecID byte
+	record  int   // index of the record or command being read, -1 outside them
+	err     error // first problem found
+}
+
+// limit makes the cursor stop at end, keeping its offset.
+func (in *inspector) limit(end int) {
+	offset := in.cursor.Offset()
+	in.cursor = tools.NewCursor(in.frame[:max(end, offset)])
+	in.cursor.Skip(offset)
+}
+
+// fail sets err on field and keeps it if it is the first problem.
+func (in *inspector) fail(field *inspect_domain.Field, err error) {
+	if field != nil && field.Error == nil {
+		field.Error = err
+	}
+	if in.err == nil {
+		in.err = err
+	}
+}
+
+// errorAt returns a *DecodeError for err in the current codec and record.
+func (in *inspector) errorAt(err error, field string, offset int) error {
+	return &tools.DecodeError{Err: err, CodecID: in.codecID, Record: in.record, Field: field, Offset: offset}
+}
+
+// open appends an empty group to parent at the cursor. close sets its
+// length and raw bytes once its children are read.
+func (in *inspector) open(parent *inspect_domain.Field, name string) *inspect_domain.Field {
+	group := &inspect_domain.Field{Name: name, Offset: in.cursor.Offset()}
+	parent.Children = append(parent.Children, group)
+	return group
+}
+
+func (in *inspector) close(group *inspect_domain.Field) {
+	group.Length = in.cursor.Offset() - group.Offset
+	group.Raw = in.frame[group.Offset:in.cursor.Offset()]
+}
+
+// field reads the next size bytes into a new field of parent. The caller
+// sets its Value.
+func (in *inspector) field(parent *inspect_domain.Field, name string, size int) (*inspect_domain.Field, error) {
+	offset := in.cursor.Offset()
+	raw, err := in.cursor.Bytes(size)
+	if err != nil {
+		return nil, tools.AnnotateError(err, in.codecID, in.record, strings.ToLower(name), offset)
+	}
+	field := &inspect_domain.Field{Name: name, Offset: offset, Length: size, Raw: raw}
+	parent.Children = append(parent.Children, field)
+	return field, nil
+}
+
+// number reads a big-endian unsigned integer of size bytes into a new
+// field of parent, with its decimal value.
+func (in *inspector) number(parent *inspect_domain.Field, name string, size int) (*inspect_domain.Field, uint64, error) {
+	field, err := in.field(parent, name, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	var value uint64
+	for _, b := range field.Raw {
+		value = value<<8 | uint64(b)
+	}
+	field.Value = strconv.FormatUint(value, 10)
+	return field, value, nil
+}
+
+// unparsed puts the bytes left in a field of root with err.
+func (in *inspector) unparsed(root *inspect_domain.Field, name string, err error) {
+	var field *inspect_domain.Field
+	if in.cursor.Len() > 0 {
+		field, _ = in.field(root, name, in.cursor.Len())
+	}
+	in.fail(field, err)
+}
+
+func (in *inspector) login(root *inspect_domain.Field) error {
+	if _, _, err := in.number(root, "IMEI length", 2); err != nil {
+		return err
+	}
+	imei, err := in.field(root, "IMEI", in.cursor.Len())
+	if err != nil {
+		return err
+	}
+	imei.Value = string(imei.Raw)
+	return nil
+}
+
+func (in *inspector) tcp(root *inspect_domain.Field) error {
+	header := in.open(root, "Header")
+	err := in.tcpHeader(header)
+	in.close(header)
+	if err != nil {
+		return err
+	}
+	// The CRC takes the last 4 bytes of the frame, as for TramDecoder.
+	crcStart := len(in.frame)
+	if len(in.frame) >= 13 {
+		crcStart = len(in.frame) - 4
+	}
+	in.limit(crcStart)
+	if err := in.codec(root); err != nil {
+		return err
+	}
+	if in.cursor.Len() > 0 {
+		in.unparsed(root, "Unparsed", in.errorAt(fmt.Errorf("%d bytes between the data and the CRC", in.cursor.Len()), "data", in.cursor.Offset()))
+	}
+	in.limit(len(in.frame))
+	crc, received, err := in.number(root, "CRC", 4)
+	if err != nil {
+		return err
+	}
+	calculated := tools.Crc16IBM(in.frame[8:crcStart])
+	crc.Value = fmt.Sprintf("0x%04X", uint16(received))
+	if uint16(received) == calculated {
+		crc.Value += " (valid)"
+	} else {
+		crc.Value += fmt.Sprintf(" (calculated 0x%04X)", calculated)
+		in.fail(crc, in.errorAt(ErrCRCMismatch, "CRC", crcStart))
+	}
+	return nil
+}
+
+func (in *inspector) tcpHeader(header *inspect_domain.Field) error {
+	preamble, value, err := in.number(header, "Preamble", 4)
+	if err != nil {
+		return err
+	}
+	if value != 0 {
+		in.fail(preamble, in.errorAt(ErrInvalidHeader, "preamble", 0))
+	}
+	length, value, err := in.number(header, "Data length", 4)
+	if err != nil {
+		return err
+	}
+	if announced := 8 + int(value) + 4; announced != len(in.frame) {
+		length.Value += fmt.Sprintf(" (frame holds %d)", len(in.frame)-12)
+		err := fmt.Errorf("%w: header announces a %d byte frame, got %d bytes", ErrLengthMismatch, announced, len(in.frame))
+		in.fail(length, in.errorAt(err, "data length", 4))
+	}
+	return nil
+}
+
+func (in *inspector) udp(root *inspect_domain.Field) error {
+	header := in.open(root, "Header")
+	err := in.udpHeader(header)
+	in.close(header)
+	if err != nil {
+		return err
+	}
+	return in.codec(root)
+}
+
+func (in *inspector) udpHeader(header *inspect_domain.Field) error {
+	length, value, err := in.number(header, "Length", 2)
+	if err != nil {
+		return err
+	}
+	if announced := 2 + int(value); announced != len(in.frame) {
+		length.Value += fmt.Sprintf(" (frame holds %d)", len(in.frame)-2)
+		err := fmt.Errorf("%w: header announces a %d byte frame, got %d bytes", ErrLengthMismatch, announced, len(in.frame))
+		in.fail(length, in.errorAt(err, "length", 0))
+	}
+	packetID, value, err := in.number(header, "Packet ID", 2)
+	if err != nil {
+		return err
+	}
+	packetID.Value = fmt.Sprintf("0x%04X", value)
+	if _, _, err := in.number(header, "Packet type", 1); err != nil {
+		return err
+	}
+	if _, _, err := in.number(header, "AVL packet ID", 1); err != nil {
+		return err
+	}
+	_, imeiLength, err := in.number(header, "IMEI length", 2)
+	if err != nil {
+		return err
+	}
+	imei, err := in.field(header, "IMEI", int(imeiLength))
+	if err != nil {
+		return err
+	}
+	imei.Value = string(imei.Raw)
+	return nil
+}
+
+// codec reads the codec ID and the codec data after it.
+func (in *inspector) codec(root *inspect_domain.Field) error {
+	field, value, err := in.number(root, "Codec ID", 1)
+	if err != nil {
+		return err
+	}
+	in.codecID = byte(value)
+	field.Value = fmt.Sprintf("0x%02X", value)
+	if name, ok := codecNames[in.codecID]; ok {
+		field.Value += " (" + name + ")"
+	}
+
+	switch in.codecID {
+	case 0x08:
+		return in.avlData(root, 1, ios8Layout)
+	case 0x8E:
+		return in.avlData(root, 2, ios8ExtendedLayout)
+	case 0x10:
+		return in.avlData(root, 2, ios16Layout)
+	case 0x0C, 0x0D, 0x0E, 0x0F:
+		return in.commandData(root)
+	}
+	// Registered codecs are decoded by TramDecoder, but their layout is
+	// unknown here.
+	data, err := in.field(root, "Data", in.cursor.Len())
+	if err != nil {
+		return err
+	}
+	if _, ok := LookupCodec(in.codecID); !ok {
+		in.fail(field, in.errorAt(ErrUnknownCodec, "codec ID", field.Offset))
+	} else {
+		data.Value = "not dissected"
+	}
+	return nil
+}
+
+func (in *inspector) avlData(root *inspect_domain.Field, eventIOSize int, layout ioLayout) error {
+	data := in.open(root, "AVL data")
+	defer in.close(data)
+	_, count, err := in.number(data, "Number of data 1", 1)
+	if err != nil {
+		return err
+	}
+	for i := range int(count) {
+		in.record = i
+		if err := in.avlRecord(data, eventIOSize, layout); err != nil {
+			return err
+		}
+	}
+	in.record = -1
+	return in.trailingCount(data, "Number of data 2", count)
+}
+
+// trailingCount reads the count that ends the codec data and compares it
+// with the count that starts it.
+func (in *inspector) trailingCount(parent *inspect_domain.Field, name string, count uint64) error {
+	field, count2, err := in.number(parent, name, 1)
+	if err != nil {
+		return err
+	}
+	if count2 != count {
+		field.Value += fmt.Sprintf(" (expected %d)", count)
+		in.fail(field, in.errorAt(fmt.Errorf("%w: %d != %d", ErrRecordCountMismatch, count, count2), strings.ToLower(name), field.Offset))
+	}
+	return nil
+}
+
+func (in *inspector) avlRecord(parent *inspect_domain.Field, eventIOSize int, layout ioLayout) error {
+	record := in.open(parent, fmt.Sprintf("Record %d", in.record+1))
+	defer in.close(record)
+
+	timestamp, milliseconds, err := in.number(record, "Timestamp", 8)
+	if err != nil {
+		return err
+	}
+	timestamp.Value = time.UnixMilli(int64(milliseconds)).UTC().Format(time.RFC3339Nano)
+	record.Value = timestamp.Value
+	priority, value, err := in.number(record, "Priority", 1)
+	if err != nil {
+		return err
+	}
+	if int(value) < len(priorityNames) {
+		priority.Value += " (" + priorityNames[value] + ")"
+	}
+	if err := in.gpsElement(record); err != nil {
+		return err
+	}
+	if _, _, err := in.number(record, "Event IO ID", eventIOSize); err != nil {
+		return err
+	}
+	if layout.generationType {
+		generation, value, err := in.number(record, "Generation type", 1)
+		if err != nil {
+			return err
+		}
+		generation.Value += " (" + decoder_domain.GenerationType(value).String() + ")"
+	}
+	if _, _, err := in.number(record, "Number of IOs", layout.countSize); err != nil {
+		return err
+	}
+	for _, valueSize := range layout.valueSizes {
+		if err := in.ioGroup(record, layout, valueSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *inspector) gpsElement(record *inspect_domain.Field) error {
+	gps := in.open(record, "GPS element")
+	defer in.close(gps)
+
+	// The values are read by the decoder; the fields only locate them.
+	var data tool_domain.GPSData
+	if in.cursor.Len() >= tools.GPSElementSize {
+		offset := in.cursor.Offset()
+		tools.ReadGPSData(in.frame[offset:offset+tools.GPSElementSize], &data)
+	}
+	longitude := strconv.FormatFloat(data.Longitude, 'f', -1, 64)
+	latitude := strconv.FormatFloat(data.Latitude, 'f', -1, 64)
+	fields := []struct {
+		name  string
+		size  int
+		value string
+	}{
+		{"Longitude", 4, longitude},
+		{"Latitude", 4, latitude},
+		{"Altitude", 2, fmt.Sprintf("%d m", data.Altitude)},
+		{"Angle", 2, fmt.Sprintf("%d°", data.Angle)},
+		{"Satellites", 1, strconv.FormatInt(data.Satelites, 10)},
+		{"Speed", 2, fmt.Sprintf("%d km/h", data.Speed)},
+	}
+	for _, element := range fields {
+		field, err := in.field(gps, element.name, element.size)
+		if err != nil {
+			return err
+		}
+		field.Value = element.value
+	}
+	gps.Value = latitude + ", " + longitude
+	if !data.Valid {
+		gps.Value += " (no fix)"
+	}
+	return nil
+}
+
+// ioGroup reads the IO elements whose values take valueSize bytes, or
+// carry their own length when valueSize is 0.
+func (in *inspector) ioGroup(record *inspect_domain.Field, layout ioLayout, valueSize int) error {
+	name := fmt.Sprintf("%d-byte IO elements", valueSize)
+	if valueSize == 0 {
+		name = "Variable-length IO elements"
+	}
+	group := in.open(record, name)
+	defer in.close(group)
+
+	_, count, err := in.number(group, "Count", layout.countSize)
+	if err != nil {
+		return err
+	}
+	group.Value = strconv.FormatUint(count, 10)
+	for range count {
+		element := in.open(group, "IO element")
+		err := in.ioElement(element, layout, valueSize)
+		in.close(element)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *inspector) ioElement(element *inspect_domain.Field, layout ioLayout, valueSize int) error {
+	_, id, err := in.number(element, "ID", layout.idSize)
+	if err != nil {
+		return err
+	}
+	element.Name = fmt.Sprintf("IO %d", id)
+	if valueSize == 0 {
+		_, length, err := in.number(element, "Length", 2)
+		if err != nil {
+			return err
+		}
+		valueSize = int(length)
+	}
+	value, err := in.field(element, "Value", valueSize)
+	if err != nil {
+		return err
+	}
+	io := io_domain.IOData{IO: int64(id), Value: hex.EncodeToString(value.Raw)}
+	if valueSize <= 8 {
+		value.Value = strconv.FormatUint(io.Uint64(), 10)
+	} else {
+		value.Value = io.Value
+	}
+	element.Value = value.Value
+	element.IO = &io
+	return nil
+}
+
+func (in *inspector) commandData(root *inspect_domain.Field) error {
+	data := in.open(root, "Command data")
+	defer in.close(data)
+
+	_, count, err := in.number(data, "Number of commands 1", 1)
+	if err != nil {
+		return err
+	}
+	commandType, value, err := in.number(data, "Command type", 1)
+	if err != nil {
+		return err
+	}
+	name := "Command"
+	switch {
+	case value == 5:
+		commandType.Value += " (Command)"
+	case value == 6:
+		commandType.Value += " (Response)"
+		name = "Response"
+	case in.codecID != 0x0F:
+		// Codec 15 keeps any type; the others reject it like
+		// decodeCommandType.
+		in.fail(commandType, in.errorAt(fmt.Errorf("unknown response type: %d", value), "command type", commandType.Offset))
+	}
+	if in.codecID == 0x0D && value == 5 {
+		in.fail(commandType, in.errorAt(fmt.Errorf("codec 13 does not support command type: Command (Only supports Response)"), "command type", commandType.Offset))
+	}
+	for i := range int(count) {
+		in.record = i
+		command := in.open(data, fmt.Sprintf("%s %d", name, i+1))
+		err := in.command(command, name)
+		in.close(command)
+		if err != nil {
+			return err
+		}
+	}
+	in.record = -1
+	return in.trailingCount(data, "Number of commands 2", count)
+}
+
+// command reads one command or response of Codec 12 to 15: its size, the
+// timestamp and IMEI of the codecs that carry them, and its text.
+func (in *inspector) command(command *inspect_domain.Field, name string) error {
+	sizeField, size, err := in.number(command, "Size", 4)
+	if err != nil {
+		return err
+	}
+	remaining := int(size)
+	if in.codecID == 0x0D {
+		timestamp, milliseconds, err := in.number(command, "Timestamp", 8)
+		if err != nil {
+			return err
+		}
+		timestamp.Value = time.UnixMilli(int64(milliseconds)).UTC().Format(time.RFC3339Nano)
+		remaining -= 8
+	}
+	if in.codecID == 0x0F {
+		timestamp, seconds, err := in.number(command, "Timestamp", 4)
+		if err != nil {
+			return err
+		}
+		timestamp.Value = time.Unix(int64(seconds), 0).UTC().Format(time.RFC3339)
+		remaining -= 4
+	}
+	if in.codecID == 0x0E || in.codecID == 0x0F {
+		imei, err := in.field(command, "IMEI", 8)
+		if err != nil {
+			return err
+		}
+		imei.Value, _ = tools.DecodeIMEI(imei.Raw)
+		remaining -= 8
+	}
+	if remaining < 0 {
+		err := in.errorAt(fmt.Errorf("response size too small"), "command size", sizeField.Offset)
+		in.fail(sizeField, err)
+		return err
+	}
+	text, err := in.field(command, name, remaining)
+	if err != nil {
+		return err
+	}
+	text.Value = strconv.Quote(string(text.Raw))
+	command.Value = text.Value
+	return nil
+}
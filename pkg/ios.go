@@ -2,322 +2,153 @@ package teltonika_go
 
 import (
 	"encoding/hex"
-	"strconv"
+	"slices"
 
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
 	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
 	tools "github.com/danieljvsa/teltonika-go/tools"
 )
 
-func DecodeIos8(data []byte, startByte int64) (*io_domain.ResponseDecode, error) {
-	ios_data := []io_domain.IOData{}
-	if len(data) < 4 {
-		return &io_domain.ResponseDecode{IOs: ios_data, NumberOfIOs: 0, LastByte: 0}, nil
-	}
-
-	ios_read := 0
-	byte := startByte
-	ios_number, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-
-	number_ios_one_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-	for range int(number_ios_one_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 1
-		value := hex.EncodeToString(data[byte : byte+1])
-
-		byte += 1
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
-
-	number_ios_two_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-	for range int(number_ios_two_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 1
-		value := hex.EncodeToString(data[byte : byte+2])
-
-		byte += 2
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
-
-	number_ios_four_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-	for range int(number_ios_four_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 1
-		value := hex.EncodeToString(data[byte : byte+4])
-
-		byte += 4
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
+// ioLayout describes the IO element encoding of an AVL codec.
+type ioLayout struct {
+	generationType bool  // a generation type byte precedes the IO count (Codec 16)
+	countSize      int   // size of the total IO count and of each group count
+	idSize         int   // size of each IO ID
+	valueSizes     []int // value size of each group, 0 for the variable-length (NX) group
+}
 
-	number_ios_eight_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
+var (
+	ios8Layout         = ioLayout{countSize: 1, idSize: 1, valueSizes: []int{1, 2, 4, 8}}
+	ios8ExtendedLayout = ioLayout{countSize: 2, idSize: 2, valueSizes: []int{1, 2, 4, 8, 0}}
+	ios16Layout        = ioLayout{generationType: true, countSize: 1, idSize: 2, valueSizes: []int{1, 2, 4, 8}}
+)
 
-	for range int(number_ios_eight_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 1
-		value := hex.EncodeToString(data[byte : byte+8])
+func DecodeIos8(data []byte, startByte int64) (*io_domain.ResponseDecode, error) {
+	return decodeIos(data, startByte, ios8Layout)
+}
 
-		byte += 8
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
+func DecodeIos8Extended(data []byte, startByte int64) (*io_domain.ResponseDecode, error) {
+	return decodeIos(data, startByte, ios8ExtendedLayout)
+}
 
-	return &io_domain.ResponseDecode{IOs: ios_data, NumberOfIOs: ios_number, LastByte: byte}, nil
+func DecodeIos16(data []byte, startByte int64) (*io_domain.ResponseDecode, error) {
+	return decodeIos(data, startByte, ios16Layout)
 }
 
-func DecodeIos8Extended(data []byte, startByte int64) (*io_domain.ResponseDecode, error) {
-	ios_data := []io_domain.IOData{}
+func decodeIos(data []byte, startByte int64, layout ioLayout) (*io_domain.ResponseDecode, error) {
 	if len(data) < 4 {
-		return &io_domain.ResponseDecode{IOs: ios_data, NumberOfIOs: 0}, nil
+		return &io_domain.ResponseDecode{IOs: []io_domain.IOData{}, NumberOfIOs: 0, LastByte: 0}, nil
 	}
 
-	ios_read := 0
-	byte := startByte
-	ios_number, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-	if err != nil {
+	cursor := tools.NewCursor(data)
+	if err := cursor.Skip(int(startByte)); err != nil {
 		return nil, err
 	}
-	byte += 2
-	number_ios_one_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
+	buffer := ioBuffer{ios: []io_domain.IOData{}}
+	ios_number, generation_type, err := buffer.decode(&cursor, layout, DecoderOptions{}.withDefaults())
 	if err != nil {
 		return nil, err
 	}
-	byte += 2
-	for range int(number_ios_one_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 2
-		value := hex.EncodeToString(data[byte : byte+1])
-
-		byte += 1
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
+	buffer.finish()
+	generation_type_name := ""
+	if layout.generationType {
+		generation_type_name = generation_type.String()
 	}
 
-	number_ios_two_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 2
-	for range int(number_ios_two_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 2
-		value := hex.EncodeToString(data[byte : byte+2])
-
-		byte += 2
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
-
-	number_ios_four_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 2
-	for range int(number_ios_four_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 2
-		value := hex.EncodeToString(data[byte : byte+4])
+	return &io_domain.ResponseDecode{IOs: buffer.ios, NumberOfIOs: ios_number, LastByte: int64(cursor.Offset()), GenerationType: generation_type_name}, nil
+}
 
-		byte += 4
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
+// ioBuffer accumulates the IO elements of all records of a frame. The hex
+// values are collected in one byte slice and turned into strings by finish
+// with a single allocation, instead of one string per element.
+type ioBuffer struct {
+	ios    []io_domain.IOData
+	values []byte
+	ends   []int // end of the hex value of each element in values
+}
 
-	number_ios_eight_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 2
-	for range int(number_ios_eight_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
+// decode reads the IO elements of one record and appends them to the
+// buffer. It returns the IO count announced by the record and, for
+// layouts that carry one, the generation type. The element counts and NX
+// value sizes are checked against options before they are used.
+func (buffer *ioBuffer) decode(cursor *tools.Cursor, layout ioLayout, options DecoderOptions) (int64, decoder_domain.GenerationType, error) {
+	var generation_type decoder_domain.GenerationType
+	if layout.generationType {
+		value, err := cursor.Uint8()
 		if err != nil {
-			return nil, err
+			return 0, 0, ioError(err, "generation type", -1)
 		}
-		byte += 2
-		value := hex.EncodeToString(data[byte : byte+8])
-
-		byte += 8
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
+		generation_type = decoder_domain.GenerationType(value)
 	}
 
-	number_ios_x_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
+	offset := cursor.Offset()
+	ios_number, err := cursor.Uint(layout.countSize)
 	if err != nil {
-		return nil, err
+		return 0, 0, ioError(err, "number of IOs", -1)
 	}
-	byte += 2
-	for range int(number_ios_x_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 2
-
-		io_length, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+2]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += 2
-
-		value := hex.EncodeToString(data[byte : byte+io_length])
-
-		byte += io_length
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
+	if err := checkLimit("MaxIOsPerRecord", int(ios_number), options.MaxIOsPerRecord); err != nil {
+		return 0, 0, ioError(err, "number of IOs", offset)
 	}
+	// Reserve room for the announced elements, bounded by what the
+	// remaining bytes can hold so a corrupt count cannot force a large
+	// allocation.
+	reserve := min(int(ios_number), cursor.Len()/(layout.idSize+1))
+	buffer.ios = slices.Grow(buffer.ios, reserve)
+	buffer.ends = slices.Grow(buffer.ends, reserve)
+	buffer.values = slices.Grow(buffer.values, 4*reserve)
 
-	return &io_domain.ResponseDecode{IOs: ios_data, NumberOfIOs: ios_number, LastByte: byte}, nil
-}
-
-func DecodeIos16(data []byte, startByte int64) (*io_domain.ResponseDecode, error) {
 	ios_read := 0
-	ios_id_length := int64(2)
-	byte := startByte
-	ios_data := []io_domain.IOData{}
-
-	if len(data) < 4 {
-		return &io_domain.ResponseDecode{IOs: ios_data, NumberOfIOs: 0, LastByte: 0}, nil
-	}
-
-	generation_type, err := tools.GetGenerationType(data[byte:byte+1], 0, 1)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-
-	ios_number, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-
-	number_ios_one_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-	for range int(number_ios_one_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+ios_id_length]), 16, 64)
+	for _, value_size := range layout.valueSizes {
+		offset := cursor.Offset()
+		count, err := cursor.Uint(layout.countSize)
 		if err != nil {
-			return nil, err
+			return 0, 0, ioError(err, "IO count", -1)
 		}
-		byte += ios_id_length
-		value := hex.EncodeToString(data[byte : byte+1])
-
-		byte += 1
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
-
-	number_ios_two_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-	for range int(number_ios_two_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+ios_id_length]), 16, 64)
-		if err != nil {
-			return nil, err
+		ios_read += int(count)
+		if err := checkLimit("MaxIOsPerRecord", ios_read, options.MaxIOsPerRecord); err != nil {
+			return 0, 0, ioError(err, "IO count", offset)
 		}
-		byte += ios_id_length
-		value := hex.EncodeToString(data[byte : byte+2])
-
-		byte += 2
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
-	}
-
-	number_ios_four_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-	for range int(number_ios_four_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+ios_id_length]), 16, 64)
-		if err != nil {
-			return nil, err
+		for range count {
+			id, err := cursor.Uint(layout.idSize)
+			if err != nil {
+				return 0, 0, ioError(err, "IO ID", -1)
+			}
+			io_length := value_size
+			if io_length == 0 {
+				offset := cursor.Offset()
+				length, err := cursor.Uint16()
+				if err != nil {
+					return 0, 0, ioError(err, "NX length", -1)
+				}
+				io_length = int(length)
+				if err := checkLimit("MaxNXValueSize", io_length, options.MaxNXValueSize); err != nil {
+					return 0, 0, ioError(err, "NX length", offset)
+				}
+			}
+			value, err := cursor.Bytes(io_length)
+			if err != nil {
+				return 0, 0, ioError(err, "IO value", -1)
+			}
+			buffer.ios = append(buffer.ios, io_domain.IOData{IO: int64(id)})
+			buffer.values = hex.AppendEncode(buffer.values, value)
+			buffer.ends = append(buffer.ends, len(buffer.values))
 		}
-		byte += ios_id_length
-		value := hex.EncodeToString(data[byte : byte+4])
-
-		byte += 4
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
 	}
+	return int64(ios_number), generation_type, nil
+}
 
-	number_ios_eight_byte, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+1]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	byte += 1
-
-	for range int(number_ios_eight_byte) {
-		id, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+ios_id_length]), 16, 64)
-		if err != nil {
-			return nil, err
-		}
-		byte += ios_id_length
-		value := hex.EncodeToString(data[byte : byte+8])
+// ioError names the IO field in err; the codec and record are added by
+// the caller.
+func ioError(err error, field string, offset int) error {
+	return tools.AnnotateError(err, 0, -1, field, offset)
+}
 
-		byte += 8
-		io := io_domain.IOData{IO: id, Value: value}
-		ios_data = append(ios_data, io)
-		ios_read += 1
+// finish sets the Value of the decoded elements.
+func (buffer *ioBuffer) finish() {
+	values := string(buffer.values)
+	start := 0
+	for i, end := range buffer.ends {
+		buffer.ios[i].Value = values[start:end]
+		start = end
 	}
-
-	return &io_domain.ResponseDecode{IOs: ios_data, NumberOfIOs: ios_number, LastByte: byte, GenerationType: generation_type}, nil
 }
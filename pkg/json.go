@@ -0,0 +1,29 @@
+package teltonika_go
+
+import (
+	"encoding/json"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+)
+
+// JSONSchemaVersion is the version of the JSON schema of decoded frames.
+// CodecDecoded, CodecHeaderResponse and the types they hold implement
+// json.Marshaler and json.Unmarshaler with this schema; see the README for
+// its fields.
+const JSONSchemaVersion = decoder_domain.JSONSchemaVersion
+
+// TramEncoderJSON encodes a complete frame from the JSON of a decoded
+// frame, the inverse of marshaling the Result of TramDecoder.
+//
+// Example:
+//
+//	decoded := TramDecoder(frame)
+//	document, _ := json.Marshal(decoded.Response.Result)
+//	same, err := TramEncoderJSON(document)
+func TramEncoderJSON(document []byte) ([]byte, error) {
+	var request decoder_domain.CodecHeaderResponse
+	if err := json.Unmarshal(document, &request); err != nil {
+		return nil, err
+	}
+	return TramEncoder(&request)
+}
@@ -0,0 +1,117 @@
+package teltonika_go
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Default decoder limits, used for the DecoderOptions fields left at zero.
+const (
+	DefaultMaxRecords             = 255
+	DefaultMaxIOsPerRecord        = 512
+	DefaultMaxNXValueSize         = 4096
+	DefaultMaxCommandResponseSize = 16 * 1024
+)
+
+// DecoderOptions limits the resources a decoder spends on a frame. Counts
+// and lengths in a frame come from the device and are not trusted: a frame
+// exceeding a limit is rejected with a *LimitError before the decoder
+// allocates or loops for it.
+//
+// The zero value selects the default limits.
+//
+// Example:
+//
+//	options := pkg.DecoderOptions{MaxRecords: 50, MaxNXValueSize: 512}
+//	decoded := pkg.TramDecoder(frame, options)
+//	var limitErr *pkg.LimitError
+//	if errors.As(decoded.Error, &limitErr) {
+//		log.Printf("rejected frame: %v", limitErr)
+//	}
+type DecoderOptions struct {
+	// MaxRecords is the largest number of AVL records or commands in a
+	// frame. Zero selects DefaultMaxRecords.
+	MaxRecords int
+	// MaxIOsPerRecord is the largest number of IO elements in a record.
+	// Zero selects DefaultMaxIOsPerRecord.
+	MaxIOsPerRecord int
+	// MaxNXValueSize is the largest value of a variable-length Codec 8E IO
+	// element. Zero selects DefaultMaxNXValueSize.
+	MaxNXValueSize int
+	// MaxCommandResponseSize is the largest command or response of Codec
+	// 12 to 15. Zero selects DefaultMaxCommandResponseSize.
+	MaxCommandResponseSize int
+	// MaxFrameSize is the largest frame accepted by TramDecoder, and the
+	// largest data accepted by the DecodeCodecX functions. Zero selects
+	// DefaultMaxFrameSize.
+	MaxFrameSize int
+	// Lenient selects the diagnostic mode of the AVL codecs (8, 8E and
+	// 16). Instead of discarding the frame on the first malformed record,
+	// they return the records decoded before it together with the error,
+	// and describe the failure in CodecData.Diagnostics: the failing
+	// offset, whether the CRC matched, and the unparsed bytes in
+	// Diagnostics.Trailing. Limit violations still fail the frame.
+	//
+	// Without Lenient the decoders are strict: a frame whose size
+	// disagrees with its header length, or whose repeated record count
+	// disagrees with the first one, fails with ErrLengthMismatch or
+	// ErrRecordCountMismatch before its CRC is considered. In lenient mode
+	// these mismatches are returned with the decoded data and flagged in
+	// Diagnostics.
+	Lenient bool
+}
+
+// withDefaults returns options with the unset limits set to their default.
+func (options DecoderOptions) withDefaults() DecoderOptions {
+	if options.MaxRecords <= 0 {
+		options.MaxRecords = DefaultMaxRecords
+	}
+	if options.MaxIOsPerRecord <= 0 {
+		options.MaxIOsPerRecord = DefaultMaxIOsPerRecord
+	}
+	if options.MaxNXValueSize <= 0 {
+		options.MaxNXValueSize = DefaultMaxNXValueSize
+	}
+	if options.MaxCommandResponseSize <= 0 {
+		options.MaxCommandResponseSize = DefaultMaxCommandResponseSize
+	}
+	if options.MaxFrameSize <= 0 {
+		options.MaxFrameSize = DefaultMaxFrameSize
+	}
+	return options
+}
+
+// decoderOptions returns the options passed to a variadic decoder, with
+// defaults applied.
+func decoderOptions(options []DecoderOptions) DecoderOptions {
+	if len(options) == 0 {
+		return DecoderOptions{}.withDefaults()
+	}
+	return options[0].withDefaults()
+}
+
+// ErrLimitExceeded is matched by every *LimitError with errors.Is.
+var ErrLimitExceeded = errors.New("decoder limit exceeded")
+
+// LimitError reports a frame exceeding one of the DecoderOptions limits.
+type LimitError struct {
+	Limit string // name of the DecoderOptions field
+	Value int    // count or size announced by the frame
+	Max   int    // configured limit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s is %d, limit is %d", ErrLimitExceeded, e.Limit, e.Value, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// checkLimit returns a *LimitError if value exceeds max.
+func checkLimit(limit string, value int, max int) error {
+	if value > max {
+		return &LimitError{Limit: limit, Value: value, Max: max}
+	}
+	return nil
+}
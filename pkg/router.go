@@ -1,10 +1,11 @@
 package teltonika_go
 
 import (
-	"encoding/hex"
+	"encoding/binary"
 	"fmt"
 
 	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	header_domain "github.com/danieljvsa/teltonika-go/internal/header"
 	tools "github.com/danieljvsa/teltonika-go/tools"
 )
 
@@ -26,59 +27,187 @@ func LoginDecoder(request []byte) *decoder_domain.CodecDecoded {
 	return &decoder_domain.CodecDecoded{Response: nil, Error: fmt.Errorf("login is not valid")}
 }
 
-func TramDecoder(request []byte) *decoder_domain.CodecDecoded {
+// MaxIMEILength bounds the length field of a login packet. Servers and
+// capture readers use it to tell a login packet from other traffic.
+const MaxIMEILength = 64
+
+// IsAVLCodec reports whether codecID is an AVL data codec, whose frames
+// the server acknowledges with a record count. Command codecs are not
+// acknowledged.
+func IsAVLCodec(codecID byte) bool {
+	switch codecID {
+	case 0x08, 0x8E, 0x10:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsLoginPacket reports whether frame is a whole login packet: a length
+// that covers the rest of frame, followed by the IMEI digits. It tells a
+// login packet from a frame, which LoginDecoder alone does not.
+func IsLoginPacket(frame []byte) bool {
+	if len(frame) < 3 || int(binary.BigEndian.Uint16(frame)) != len(frame)-2 {
+		return false
+	}
+	for _, b := range frame[2:] {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// TramDecoder decodes a complete TCP or UDP frame. The header selects the
+// protocol and the codec ID byte selects the Codec registered for it with
+// RegisterCodec.
+//
+// Decode failures are returned as a *DecodeError whose offset counts from
+// the start of request.
+//
+// The TCP data length and the UDP length must match the size of request;
+// a mismatch fails with ErrLengthMismatch unless options select lenient
+// mode.
+//
+// The optional options limit the resources spent on the frame; without
+// them the default limits apply. Codecs that do not implement OptionsCodec
+// only get the frame size limit.
+func TramDecoder(request []byte, options ...DecoderOptions) *decoder_domain.CodecDecoded {
 	read := 0
+	limits := decoderOptions(options)
+	if err := checkLimit("MaxFrameSize", len(request), limits.MaxFrameSize); err != nil {
+		return &decoder_domain.CodecDecoded{Response: nil, Error: tools.AnnotateError(err, 0, -1, "frame", 0)}
+	}
 
 	headerData, err := DecodeHeader(request)
 	if err != nil {
 		return &decoder_domain.CodecDecoded{Response: nil, Error: err}
 	}
 
+	// A frame longer than its header announces is rejected before the
+	// codec reads a CRC from the wrong bytes, or in lenient mode cut to the
+	// announced size. A shorter one is left to the codec, which locates the
+	// truncated field.
+	frameSize, lengthErr := checkFrameLength(request, headerData)
+	if lengthErr != nil && frameSize < len(request) {
+		if !limits.Lenient {
+			return &decoder_domain.CodecDecoded{Response: nil, Error: lengthErr}
+		}
+		request = request[:frameSize]
+	}
+
 	read += headerData.LastByte
-	codec := hex.EncodeToString(request[read : read+1])
+	if len(request) <= read {
+		return &decoder_domain.CodecDecoded{Response: nil, Error: tools.NewDecodeError(tools.ErrTruncated, "codec ID", read)}
+	}
+	codecID := request[read]
 
 	read += 1
 	data := request[read:]
 	response := &decoder_domain.ResponseType{Result: decoder_domain.CodecHeaderResponse{}, Type: "Tram"}
-	switch string(codec) {
-	case "08":
-		res, err := DecodeCodec8(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	case "8e":
-		res, err := DecodeCodec8Ext(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	case "0c":
-		res, err := DecodeCodec12(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	case "0d":
-		//Codec that only serves to send commands to device
-		//err := fmt.Errorf("codec is not supported")
-		res, err := DecodeCodec13(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	case "0e":
-		res, err := DecodeCodec14(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	case "0f":
-		//Codec that only serves to send commands to device
-		res, err := DecodeCodec15(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		//err := fmt.Errorf("codec is not supported")
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	case "10":
-		res, err := DecodeCodec16(data, headerData.Protocol)
-		response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
-		return &decoder_domain.CodecDecoded{Response: response, Error: err}
-	default:
-		return &decoder_domain.CodecDecoded{Response: response, Error: fmt.Errorf("unknown codec: %s", codec)}
+	codec, ok := LookupCodec(codecID)
+	if !ok {
+		return &decoder_domain.CodecDecoded{Response: response, Error: &tools.DecodeError{Err: ErrUnknownCodec, CodecID: codecID, Record: -1, Field: "codec ID", Offset: read - 1}}
+	}
+	var res *decoder_domain.CodecData
+	if optionsCodec, ok := codec.(OptionsCodec); ok {
+		res, err = optionsCodec.DecodeWithOptions(data, headerData.Protocol, limits)
+	} else {
+		res, err = codec.Decode(data, headerData.Protocol)
+	}
+	if res != nil {
+		res.CodecID = codecID
+	}
+	if err != nil {
+		// Offsets of codec errors count from the data after the codec ID.
+		err = tools.AnnotateError(tools.ShiftErrorOffset(err, read), codecID, -1, "", -1)
 	}
+	if res != nil && res.Diagnostics != nil {
+		if res.Diagnostics.FailureOffset >= 0 {
+			res.Diagnostics.FailureOffset += read
+		}
+		res.Diagnostics.LengthMatched = lengthErr == nil
+	}
+	if err == nil {
+		err = lengthErr
+	}
+	response.Result = decoder_domain.CodecHeaderResponse{CodecData: res, HeaderData: headerData}
+	return &decoder_domain.CodecDecoded{Response: response, Error: err}
+}
 
+// checkFrameLength compares the length announced by the header with the
+// size of the frame: the TCP data length counts the bytes between the
+// header and the CRC, the UDP length the bytes after the length field. It
+// returns the frame size announced by the header.
+func checkFrameLength(request []byte, headerData *header_domain.HeaderData) (int, error) {
+	field, offset, frameSize := "", 0, len(request)
+	switch {
+	case headerData.HeaderTCP != nil:
+		field, offset, frameSize = "data length", 4, 8+int(headerData.HeaderTCP.DataLength)+4
+	case headerData.HeaderUDP != nil:
+		field, offset, frameSize = "length", 0, 2+int(headerData.HeaderUDP.Length)
+	}
+	if frameSize == len(request) {
+		return frameSize, nil
+	}
+	err := fmt.Errorf("%w: header announces a %d byte frame, got %d bytes", ErrLengthMismatch, frameSize, len(request))
+	return frameSize, tools.NewDecodeError(err, field, offset)
 }
 
-func TramEncoder(request []byte) *decoder_domain.CodecDecoded {
-	return &decoder_domain.CodecDecoded{Response: nil, Error: nil}
+// TramEncoder encodes a complete frame ready to be written to the wire. It
+// is the inverse of TramDecoder: request.CodecData.CodecID selects the
+// Codec registered for it, and request.HeaderData selects the protocol.
+//
+// TCP frames get the zero preamble, the data length and the CRC. UDP frames
+// get the length, packet ID, AVL packet ID and IMEI taken from
+// request.HeaderData.HeaderUDP, and carry no CRC. A nil HeaderData encodes
+// a TCP frame.
+//
+// Example:
+//
+//	frame, err := TramEncoder(&decoder_domain.CodecHeaderResponse{
+//		CodecData: &decoder_domain.CodecData{CodecID: 0x08, NumberOfRecords: 1, Records: records},
+//	})
+func TramEncoder(request *decoder_domain.CodecHeaderResponse) ([]byte, error) {
+	if request == nil || request.CodecData == nil {
+		return nil, fmt.Errorf("codec data is nil")
+	}
+	codecID := request.CodecData.CodecID
+	codec, ok := LookupCodec(codecID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %02x", ErrUnknownCodec, codecID)
+	}
+	payload, err := codec.Encode(request.CodecData)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("encoded codec data is too short")
+	}
+
+	protocol := "TCP"
+	if request.HeaderData != nil {
+		protocol = request.HeaderData.Protocol
+	}
+	switch protocol {
+	case "TCP":
+		header, err := EncodeHeaderTCP(int64(1 + len(payload) - 4))
+		if err != nil {
+			return nil, err
+		}
+		frame := append(header, codecID)
+		return append(frame, payload...), nil
+	case "UDP":
+		if request.HeaderData.HeaderUDP == nil {
+			return nil, fmt.Errorf("UDP header data is nil")
+		}
+		body := append([]byte{codecID}, payload[:len(payload)-4]...)
+		header, err := EncodeHeaderUDP(request.HeaderData.HeaderUDP, len(body))
+		if err != nil {
+			return nil, err
+		}
+		return append(header, body...), nil
+	default:
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
 }
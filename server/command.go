@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+var (
+	// ErrDeviceNotConnected is returned by SendCommand when no session is
+	// open for the requested IMEI.
+	ErrDeviceNotConnected = errors.New("device is not connected")
+	// ErrSessionClosed is returned by SendCommand when the connection of the
+	// device closes before the response arrives.
+	ErrSessionClosed = errors.New("session closed")
+)
+
+// deliver hands a Codec 12 response to the command waiting for it. Responses
+// nobody waits for are dropped.
+func (session *Session) deliver(data *decoder_domain.CodecData) {
+	for _, record := range data.Records {
+		if record.CommandType == nil || *record.CommandType != "Response" || record.CommandResponses == nil {
+			continue
+		}
+		for _, response := range *record.CommandResponses {
+			response := response
+			if response.CommandType == "" {
+				response.CommandType = *record.CommandType
+			}
+			select {
+			case session.responses <- &response:
+			default:
+			}
+		}
+	}
+}
+
+// SendCommand sends a GPRS command such as "getinfo" or "setdigout 1" to the
+// device identified by imei as a Codec 12 frame and waits for its response.
+//
+// Commands to the same device are sent one at a time; AVL frames received
+// while waiting are handled and acknowledged as usual. The wait ends when
+// ctx is done, in which case ctx.Err() is returned.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	response, err := srv.SendCommand(ctx, "356307042441013", "getinfo")
+//	if err == nil {
+//		fmt.Println(response.Response)
+//	}
+func (s *TCPServer) SendCommand(ctx context.Context, imei string, command string) (*tool_domain.CommandResponse, error) {
+	session, ok := s.registry().Lookup(imei)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotConnected, imei)
+	}
+	if session.Protocol != "TCP" {
+		return nil, fmt.Errorf("commands are not supported over %s", session.Protocol)
+	}
+
+	frame, err := commandFrame(command)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case session.commands <- struct{}{}:
+	case <-session.done:
+		return nil, ErrSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-session.commands }()
+
+	// Drop a late response to an earlier command that timed out.
+	select {
+	case <-session.responses:
+	default:
+	}
+
+	if _, err := session.Write(frame); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-session.responses:
+		return response, nil
+	case <-session.done:
+		return nil, ErrSessionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// commandFrame builds the TCP frame carrying command as a Codec 12 command.
+func commandFrame(command string) ([]byte, error) {
+	commandType := "Command"
+	commands := []tool_domain.CommandResponse{{Response: command}}
+	return pkg.TramEncoder(&decoder_domain.CodecHeaderResponse{
+		CodecData: &decoder_domain.CodecData{
+			CodecID:         0x0C,
+			NumberOfRecords: 1,
+			Records: []decoder_domain.Record{
+				{CommandType: &commandType, CommandResponses: &commands},
+			},
+		},
+	})
+}
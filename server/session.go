@@ -0,0 +1,215 @@
+package server
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+)
+
+// Session is a device logged in to a server. The identity fields are set
+// when the session is created; the activity counters are updated by the
+// server as frames arrive and are read through the accessor methods.
+type Session struct {
+	IMEI        string
+	Protocol    string
+	RemoteAddr  net.Addr
+	ConnectedAt time.Time
+
+	conn       net.Conn
+	packetConn net.PacketConn
+
+	mu             sync.Mutex
+	lastSeen       time.Time
+	framesReceived int64
+	lastRecord     *decoder_domain.Record
+
+	writeMu   sync.Mutex
+	commands  chan struct{}
+	responses chan *tool_domain.CommandResponse
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSession(imei string, protocol string, remoteAddr net.Addr) *Session {
+	now := time.Now()
+	return &Session{
+		IMEI:        imei,
+		Protocol:    protocol,
+		RemoteAddr:  remoteAddr,
+		ConnectedAt: now,
+		lastSeen:    now,
+		commands:    make(chan struct{}, 1),
+		responses:   make(chan *tool_domain.CommandResponse, 1),
+		done:        make(chan struct{}),
+	}
+}
+
+func newTCPSession(imei string, conn net.Conn) *Session {
+	session := newSession(imei, "TCP", conn.RemoteAddr())
+	session.conn = conn
+	return session
+}
+
+func newUDPSession(imei string, conn net.PacketConn, addr net.Addr) *Session {
+	session := newSession(imei, "UDP", addr)
+	session.packetConn = conn
+	return session
+}
+
+// Write sends data to the device: on the connection of a TCP session, or
+// as a datagram to the sender address of a UDP session. Writes on a TCP
+// session are serialized with the ACKs sent by the server.
+func (session *Session) Write(data []byte) (int, error) {
+	session.writeMu.Lock()
+	defer session.writeMu.Unlock()
+	if session.conn != nil {
+		return session.conn.Write(data)
+	}
+	return session.packetConn.WriteTo(data, session.RemoteAddr)
+}
+
+// LastSeen returns when the last packet of the device was received.
+func (session *Session) LastSeen() time.Time {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.lastSeen
+}
+
+// FramesReceived returns the number of frames decoded for the session.
+func (session *Session) FramesReceived() int64 {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.framesReceived
+}
+
+// LastRecord returns the last AVL record decoded for the session, or nil
+// if none was received yet.
+func (session *Session) LastRecord() *decoder_domain.Record {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.lastRecord
+}
+
+// Done returns a channel closed when the session ends: the connection
+// closed, a newer login of the device evicted it, or a UDP device stayed
+// silent for the server's SessionTimeout.
+func (session *Session) Done() <-chan struct{} {
+	return session.done
+}
+
+// Close ends the session. TCP sessions also close their connection.
+func (session *Session) Close() error {
+	var err error
+	session.closeOnce.Do(func() {
+		close(session.done)
+		if session.conn != nil {
+			err = session.conn.Close()
+		}
+	})
+	return err
+}
+
+func (session *Session) closed() bool {
+	select {
+	case <-session.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// touch records a decoded frame.
+func (session *Session) touch(data *decoder_domain.CodecData) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.lastSeen = time.Now()
+	session.framesReceived++
+	if data == nil {
+		return
+	}
+	for i := len(data.Records) - 1; i >= 0; i-- {
+		if data.Records[i].Timestamp != nil {
+			record := data.Records[i]
+			session.lastRecord = &record
+			return
+		}
+	}
+}
+
+// Registry keeps the sessions of online devices by IMEI. It is safe for
+// concurrent use and its zero value is an empty registry. A registry can be
+// shared by several servers to look devices up regardless of transport.
+//
+// Example:
+//
+//	if session, ok := registry.Lookup("356307042441013"); ok {
+//		fmt.Println(session.Protocol, session.RemoteAddr, session.LastSeen())
+//	}
+type Registry struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// Register adds session to the registry. A previous session of the same
+// IMEI is removed and closed, and returned as evicted.
+func (registry *Registry) Register(session *Session) (evicted *Session) {
+	registry.mu.Lock()
+	if registry.sessions == nil {
+		registry.sessions = make(map[string]*Session)
+	}
+	evicted = registry.sessions[session.IMEI]
+	registry.sessions[session.IMEI] = session
+	registry.mu.Unlock()
+
+	if evicted != nil && evicted != session {
+		evicted.Close()
+		return evicted
+	}
+	return nil
+}
+
+// Unregister removes session unless a newer session of the same IMEI
+// replaced it. It reports whether session was removed.
+func (registry *Registry) Unregister(session *Session) bool {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.sessions[session.IMEI] != session {
+		return false
+	}
+	delete(registry.sessions, session.IMEI)
+	return true
+}
+
+// Lookup returns the session of the device identified by imei.
+func (registry *Registry) Lookup(imei string) (*Session, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	session, ok := registry.sessions[imei]
+	return session, ok
+}
+
+// Sessions returns the registered sessions ordered by IMEI.
+func (registry *Registry) Sessions() []*Session {
+	registry.mu.RLock()
+	sessions := make([]*Session, 0, len(registry.sessions))
+	for _, session := range registry.sessions {
+		sessions = append(sessions, session)
+	}
+	registry.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].IMEI < sessions[j].IMEI
+	})
+	return sessions
+}
+
+// Len returns the number of registered sessions.
+func (registry *Registry) Len() int {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return len(registry.sessions)
+}
@@ -0,0 +1,313 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	journal "github.com/danieljvsa/teltonika-go/journal"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Close is called.
+var ErrServerClosed = errors.New("server closed")
+
+// Handler receives every frame decoded from the device identified by imei.
+// Returning a non-nil error makes the server acknowledge zero records, so
+// the device keeps the data and sends it again.
+type Handler func(imei string, data *decoder_domain.CodecData) error
+
+// Authenticator decides whether the device identified by imei is allowed
+// to send data. A nil Authenticator accepts every device.
+type Authenticator func(imei string) bool
+
+// FrameRecorder receives every raw frame a server reads, before it is
+// decoded, so that traffic can be replayed later. (*journal.Writer).Write
+// is a FrameRecorder.
+type FrameRecorder func(entry journal.Entry) error
+
+// TCPServer accepts Teltonika TCP connections, performs the IMEI login
+// handshake and acknowledges every AVL frame with the number of accepted
+// records. Each connection is served on its own goroutine.
+//
+// Example:
+//
+//	srv := &server.TCPServer{
+//		Addr: ":5027",
+//		Handler: func(imei string, data *decoder_domain.CodecData) error {
+//			fmt.Println(imei, data.NumberOfRecords)
+//			return nil
+//		},
+//	}
+//	log.Fatal(srv.ListenAndServe())
+type TCPServer struct {
+	// Addr is the TCP address to listen on, ":5027" if empty.
+	Addr string
+	// Handler is called for every decoded frame.
+	Handler Handler
+	// Authenticate is called once per connection with the login IMEI.
+	Authenticate Authenticator
+	// Journal is called with every frame read after the login. Its errors
+	// are logged.
+	Journal FrameRecorder
+	// ReadTimeout is the maximum idle time between two packets of a
+	// connection. Zero means no timeout.
+	ReadTimeout time.Duration
+	// MaxFrameSize is the largest frame accepted from a device, including
+	// preamble, data length and CRC. Zero selects pkg.DefaultMaxFrameSize.
+	MaxFrameSize int
+	// DecoderOptions limits the resources spent decoding a frame. The
+	// zero value selects the default limits of pkg.DecoderOptions.
+	DecoderOptions pkg.DecoderOptions
+	// Sessions receives the session of every logged-in device. If nil,
+	// the server creates its own registry on first use.
+	Sessions *Registry
+	// ErrorLog receives connection errors. If nil, the log package's
+	// standard logger is used.
+	ErrorLog *log.Logger
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// ListenAndServe listens on s.Addr and then calls Serve.
+func (s *TCPServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":5027"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on listener and serves each of them on a new
+// goroutine. It always returns a non-nil error; after Close it returns
+// ErrServerClosed.
+func (s *TCPServer) Serve(listener net.Listener) error {
+	if !s.trackListener(listener, true) {
+		listener.Close()
+		return ErrServerClosed
+	}
+	defer s.trackListener(listener, false)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.trackConn(conn, true) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops all listeners, closes every open connection and waits for
+// the connection goroutines to return.
+func (s *TCPServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	for listener := range s.listeners {
+		if closeErr := listener.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *TCPServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.trackConn(conn, false)
+	defer conn.Close()
+
+	framer := pkg.NewFramer(conn, s.MaxFrameSize)
+	imei, err := s.login(conn, framer)
+	if err != nil {
+		s.logf("teltonika: login from %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	session := newTCPSession(imei, conn)
+	if evicted := s.registry().Register(session); evicted != nil {
+		s.logf("teltonika: device %s reconnected from %s, closing session from %s", imei, conn.RemoteAddr(), evicted.RemoteAddr)
+	}
+	defer s.registry().Unregister(session)
+	defer session.Close()
+	if _, err := session.Write(tools.EncodeLoginResponse(true)); err != nil {
+		s.logf("teltonika: accepting %s (%s) failed: %v", imei, conn.RemoteAddr(), err)
+		return
+	}
+
+	for {
+		s.setReadDeadline(conn)
+		frame, err := framer.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !s.isClosed() && !session.closed() {
+				s.logf("teltonika: reading frame from %s (%s) failed: %v", imei, conn.RemoteAddr(), err)
+			}
+			return
+		}
+		s.record(journal.Entry{Time: time.Now(), IMEI: imei, Transport: "TCP", RemoteAddr: conn.RemoteAddr().String(), Frame: frame})
+
+		accepted, ack := s.process(imei, session, frame)
+		if !ack {
+			continue
+		}
+		reply, err := tools.EncodeTCPAck(accepted)
+		if err != nil {
+			s.logf("teltonika: encoding ACK for %s failed: %v", imei, err)
+			return
+		}
+		if _, err := session.Write(reply); err != nil {
+			s.logf("teltonika: writing ACK to %s (%s) failed: %v", imei, conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
+
+// login reads the IMEI packet and answers 0x00 when the device is rejected.
+// Accepted devices are answered 0x01 once their session is registered.
+func (s *TCPServer) login(conn net.Conn, framer *pkg.Framer) (string, error) {
+	s.setReadDeadline(conn)
+	packet, err := framer.Login()
+	if err != nil {
+		return "", err
+	}
+	if len(packet)-2 > pkg.MaxIMEILength {
+		return "", fmt.Errorf("invalid IMEI length: %d", len(packet)-2)
+	}
+
+	login, err := tools.Login(packet)
+	if err != nil {
+		return "", err
+	}
+	imei := *login.IMEI
+
+	if s.Authenticate != nil && !s.Authenticate(imei) {
+		conn.Write(tools.EncodeLoginResponse(false))
+		return "", fmt.Errorf("device %s rejected", imei)
+	}
+	return imei, nil
+}
+
+// process decodes a frame, hands command responses to a waiting
+// SendCommand and passes the frame to the handler. It returns the number
+// of accepted records and whether the frame must be acknowledged.
+func (s *TCPServer) process(imei string, session *Session, frame []byte) (int64, bool) {
+	ack := pkg.IsAVLCodec(frame[8])
+
+	decoded := pkg.TramDecoder(frame, s.DecoderOptions)
+	if decoded.Error != nil {
+		s.logf("teltonika: decoding frame from %s failed: %v", imei, decoded.Error)
+		return 0, ack
+	}
+	data := decoded.Response.Result.CodecData
+	session.touch(data)
+	if frame[8] == 0x0C {
+		session.deliver(data)
+	}
+	if s.Handler != nil {
+		if err := s.Handler(imei, data); err != nil {
+			s.logf("teltonika: handler rejected frame from %s: %v", imei, err)
+			return 0, ack
+		}
+	}
+	return data.NumberOfRecords, ack
+}
+
+// record passes a frame to the journal, if any.
+func (s *TCPServer) record(entry journal.Entry) {
+	if s.Journal == nil {
+		return
+	}
+	if err := s.Journal(entry); err != nil {
+		s.logf("teltonika: journaling frame from %s failed: %v", entry.IMEI, err)
+	}
+}
+
+func (s *TCPServer) setReadDeadline(conn net.Conn) {
+	if s.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+	}
+}
+
+func (s *TCPServer) trackListener(listener net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		if s.listeners == nil {
+			s.listeners = make(map[net.Listener]struct{})
+		}
+		s.listeners[listener] = struct{}{}
+		return true
+	}
+	delete(s.listeners, listener)
+	return true
+}
+
+func (s *TCPServer) trackConn(conn net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		if s.conns == nil {
+			s.conns = make(map[net.Conn]struct{})
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		return true
+	}
+	delete(s.conns, conn)
+	return true
+}
+
+// registry returns the session registry, creating it if needed.
+func (s *TCPServer) registry() *Registry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Sessions == nil {
+		s.Sessions = &Registry{}
+	}
+	return s.Sessions
+}
+
+func (s *TCPServer) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *TCPServer) logf(format string, args ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	journal "github.com/danieljvsa/teltonika-go/journal"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// maxDatagramSize is the largest UDP payload the server reads.
+const maxDatagramSize = 65535
+
+// UDPServer receives Teltonika UDP datagrams, decodes them and answers
+// each AVL packet with an acknowledgement carrying the packet ID, the AVL
+// packet ID and the number of accepted records.
+//
+// Example:
+//
+//	srv := &server.UDPServer{Addr: ":5027", Handler: handler}
+//	log.Fatal(srv.ListenAndServe())
+type UDPServer struct {
+	// Addr is the UDP address to listen on, ":5027" if empty.
+	Addr string
+	// Handler is called for every decoded datagram.
+	Handler Handler
+	// Authenticate is called for every datagram with the header IMEI.
+	// Rejected datagrams are acknowledged with zero records.
+	Authenticate Authenticator
+	// Journal is called with every datagram received, including those
+	// without a valid header. Its errors are logged.
+	Journal FrameRecorder
+	// DecoderOptions limits the resources spent decoding a datagram. The
+	// zero value selects the default limits of pkg.DecoderOptions.
+	DecoderOptions pkg.DecoderOptions
+	// Sessions receives a session per device, replaced whenever the device
+	// sends from a new address. If nil, the server creates its own registry
+	// on first use.
+	Sessions *Registry
+	// SessionTimeout is how long a device may stay silent before its
+	// session is unregistered and closed, 5 minutes if zero. UDP has no
+	// disconnect, so without it a device that went offline or moved to a
+	// new NAT mapping would keep a stale session.
+	SessionTimeout time.Duration
+	// ErrorLog receives datagram errors. If nil, the log package's
+	// standard logger is used.
+	ErrorLog *log.Logger
+
+	mu       sync.RWMutex
+	conns    map[net.PacketConn]struct{}
+	sessions map[*Session]*time.Timer // sessions registered by the server, with their expiry timers
+	closed   bool
+}
+
+// ListenAndServe listens on s.Addr and then calls Serve.
+func (s *UDPServer) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":5027"
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(conn)
+}
+
+// Serve reads datagrams from conn until it is closed. It always returns a
+// non-nil error; after Close it returns ErrServerClosed.
+func (s *UDPServer) Serve(conn net.PacketConn) error {
+	if !s.trackConn(conn, true) {
+		conn.Close()
+		return ErrServerClosed
+	}
+	defer s.trackConn(conn, false)
+
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+		received := time.Now()
+		datagram := make([]byte, n)
+		copy(datagram, buffer[:n])
+		s.serveDatagram(conn, addr, datagram, received)
+	}
+}
+
+// Close stops every packet connection passed to Serve, and unregisters
+// and closes the sessions the server registered.
+func (s *UDPServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var err error
+	for conn := range s.conns {
+		if closeErr := conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	for session, timer := range s.sessions {
+		timer.Stop()
+		s.Sessions.Unregister(session)
+		session.Close()
+	}
+	s.sessions = nil
+	return err
+}
+
+// RemoteAddr returns the address the device identified by imei last sent
+// a datagram from. Replies and commands for the device must go there.
+func (s *UDPServer) RemoteAddr(imei string) (net.Addr, bool) {
+	session, ok := s.registry().Lookup(imei)
+	if !ok || session.Protocol != "UDP" {
+		return nil, false
+	}
+	return session.RemoteAddr, true
+}
+
+func (s *UDPServer) serveDatagram(conn net.PacketConn, addr net.Addr, datagram []byte, received time.Time) {
+	headerData, err := pkg.DecodeHeader(datagram)
+	if s.Journal != nil {
+		entry := journal.Entry{Time: received, Transport: "UDP", RemoteAddr: addr.String(), Frame: datagram}
+		if err == nil && headerData.HeaderUDP != nil {
+			entry.IMEI = headerData.HeaderUDP.IMEI
+		}
+		if err := s.Journal(entry); err != nil {
+			s.logf("teltonika: journaling datagram from %s failed: %v", addr, err)
+		}
+	}
+	if err != nil || headerData.HeaderUDP == nil {
+		s.logf("teltonika: invalid UDP header from %s: %v", addr, err)
+		return
+	}
+	header := headerData.HeaderUDP
+	if len(datagram) <= header.LastByte {
+		s.logf("teltonika: UDP datagram from %s has no codec data", addr)
+		return
+	}
+
+	accepted := s.process(header.IMEI, conn, addr, datagram)
+	if !pkg.IsAVLCodec(datagram[header.LastByte]) {
+		return
+	}
+	ack, err := tools.EncodeUDPAck(header.PacketID, header.AVLPacketID, accepted)
+	if err != nil {
+		s.logf("teltonika: encoding ACK for %s failed: %v", header.IMEI, err)
+		return
+	}
+	if _, err := conn.WriteTo(ack, addr); err != nil {
+		s.logf("teltonika: writing ACK to %s (%s) failed: %v", header.IMEI, addr, err)
+	}
+}
+
+// process decodes a datagram, records it in the session of its IMEI and
+// passes the data to the handler. It returns the number of accepted records.
+func (s *UDPServer) process(imei string, conn net.PacketConn, addr net.Addr, datagram []byte) int64 {
+	if s.Authenticate != nil && !s.Authenticate(imei) {
+		s.logf("teltonika: device %s (%s) rejected", imei, addr)
+		return 0
+	}
+
+	decoded := pkg.TramDecoder(datagram, s.DecoderOptions)
+	if decoded.Error != nil {
+		s.logf("teltonika: decoding datagram from %s failed: %v", imei, decoded.Error)
+		return 0
+	}
+	data := decoded.Response.Result.CodecData
+	s.session(imei, conn, addr).touch(data)
+	if s.Handler != nil {
+		if err := s.Handler(imei, data); err != nil {
+			s.logf("teltonika: handler rejected datagram from %s: %v", imei, err)
+			return 0
+		}
+	}
+	return data.NumberOfRecords
+}
+
+// session returns the session of imei, registering a new one when the
+// device is unknown or sends from a different address.
+func (s *UDPServer) session(imei string, conn net.PacketConn, addr net.Addr) *Session {
+	registry := s.registry()
+	session, ok := registry.Lookup(imei)
+	if ok && session.packetConn == conn && session.RemoteAddr.String() == addr.String() {
+		return session
+	}
+	session = newUDPSession(imei, conn, addr)
+	evicted := registry.Register(session)
+	s.trackSession(session, evicted)
+	return session
+}
+
+// trackSession starts the expiry timer of session and stops that of the
+// session it evicted. A session registered after Close is closed at once.
+func (s *UDPServer) trackSession(session, evicted *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if timer, ok := s.sessions[evicted]; ok {
+		timer.Stop()
+		delete(s.sessions, evicted)
+	}
+	if s.closed {
+		s.Sessions.Unregister(session)
+		session.Close()
+		return
+	}
+	if s.sessions == nil {
+		s.sessions = make(map[*Session]*time.Timer)
+	}
+	s.sessions[session] = time.AfterFunc(s.sessionTimeout(), func() { s.expireIdle(session) })
+}
+
+// expireIdle unregisters and closes session once the device has sent
+// nothing for s.SessionTimeout, and otherwise checks again when it could
+// have.
+func (s *UDPServer) expireIdle(session *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	timer, ok := s.sessions[session]
+	if !ok {
+		return
+	}
+	timeout := s.sessionTimeout()
+	if idle := time.Since(session.LastSeen()); idle < timeout && !session.closed() {
+		timer.Reset(timeout - idle)
+		return
+	}
+	delete(s.sessions, session)
+	s.Sessions.Unregister(session)
+	session.Close()
+}
+
+func (s *UDPServer) sessionTimeout() time.Duration {
+	if s.SessionTimeout <= 0 {
+		return 5 * time.Minute
+	}
+	return s.SessionTimeout
+}
+
+// registry returns the session registry, creating it if needed.
+func (s *UDPServer) registry() *Registry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Sessions == nil {
+		s.Sessions = &Registry{}
+	}
+	return s.Sessions
+}
+
+func (s *UDPServer) trackConn(conn net.PacketConn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if add {
+		if s.closed {
+			return false
+		}
+		if s.conns == nil {
+			s.conns = make(map[net.PacketConn]struct{})
+		}
+		s.conns[conn] = struct{}{}
+		return true
+	}
+	delete(s.conns, conn)
+	return true
+}
+
+func (s *UDPServer) isClosed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.closed
+}
+
+func (s *UDPServer) logf(format string, args ...any) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,76 @@
+package simulator
+
+import (
+	"strings"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+// DefaultResponses are the canned answers of a Device whose Responses are
+// not set, keyed by command name.
+var DefaultResponses = map[string]string{
+	"getinfo":   "INI:2019/7/22 7:22 RTC:2019/7/22 7:53 RST:2 ERR:1 SR:0 BR:0 CF:0 FG:0 FL:0 TU:0/0 UT:0 SMS:0 NOGPS:0:30 GPS:1 SAT:0 RS:3 RF:65 SF:1 MD:0",
+	"getver":    "Ver:03.27.07_00 GPS:AXN_5.1.9 Hw:FMB920 Mod:15 IMEI:356307042441013 Init:2019-7-22 7:22 Uptime:1800 MAC:0001C2D3E4F5 SPC:1(0) AXL:0 OBD:0 BL:1.10 BT:4",
+	"getstatus": "Data Link: 1 GPRS: 1 Phone: 0 SIM: 0 OP: 24602 Signal: 5 NewSMS: 0 Roaming: 0 SMSFull: 0 LAC: 1 Cell ID: 3055 NetType: 1 FwUpd:-1",
+	"getgps":    "GPS:1 Sat:10 Lat:54.687200 Long:25.279700 Alt:112 Speed:0 Dir:0 Date: 2019/7/22 Time: 7:53:00",
+	"setdigout": "DOUT1:1 DOUT2:0 DOUT3:0",
+}
+
+// unknownResponse answers commands without a canned response.
+const unknownResponse = "Unknown command"
+
+// respond returns the canned response to command: the response to the
+// whole command, or else to its first word, so that "setdigout 1" is
+// answered by the "setdigout" response.
+func respond(responses map[string]string, command string) string {
+	if responses == nil {
+		responses = DefaultResponses
+	}
+	command = strings.TrimSpace(command)
+	if response, ok := responses[command]; ok {
+		return response
+	}
+	if name, _, ok := strings.Cut(command, " "); ok {
+		if response, ok := responses[name]; ok {
+			return response
+		}
+	}
+	return unknownResponse
+}
+
+// answer decodes a frame sent by the server and, when it carries Codec 12
+// commands, returns the TCP frame with their responses. Other frames get a
+// nil answer.
+func answer(responses map[string]string, frame []byte) ([]byte, int, error) {
+	decoded := pkg.TramDecoder(frame)
+	if decoded.Error != nil {
+		return nil, 0, decoded.Error
+	}
+	data := decoded.Response.Result.CodecData
+	if data.CodecID != 0x0C {
+		return nil, 0, nil
+	}
+	var replies []tool_domain.CommandResponse
+	for _, record := range data.Records {
+		if record.CommandType == nil || *record.CommandType != "Command" || record.CommandResponses == nil {
+			continue
+		}
+		for _, command := range *record.CommandResponses {
+			replies = append(replies, tool_domain.CommandResponse{Response: respond(responses, command.Response)})
+		}
+	}
+	if len(replies) == 0 {
+		return nil, 0, nil
+	}
+	responseType := "Response"
+	reply, err := pkg.TramEncoder(&decoder_domain.CodecHeaderResponse{
+		CodecData: &decoder_domain.CodecData{
+			CodecID:         0x0C,
+			NumberOfRecords: int64(len(replies)),
+			Records:         []decoder_domain.Record{{CommandType: &responseType, CommandResponses: &replies}},
+		},
+	})
+	return reply, len(replies), err
+}
@@ -0,0 +1,110 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+)
+
+// earthRadius is the mean Earth radius in meters.
+const earthRadius = 6371000.0
+
+// Waypoint is a position of a simulated route.
+type Waypoint struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  int64 // meters
+}
+
+// ParseRoute parses waypoints written as "latitude,longitude[,altitude]"
+// and separated by semicolons or newlines. The altitude is in meters,
+// from 0 to 32767.
+//
+// Example:
+//
+//	route, err := ParseRoute("54.6872,25.2797;54.6890,25.2850,120")
+func ParseRoute(text string) ([]Waypoint, error) {
+	var route []Waypoint
+	for _, point := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == '\n' }) {
+		point = strings.TrimSpace(point)
+		if point == "" {
+			continue
+		}
+		parts := strings.Split(point, ",")
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, fmt.Errorf("waypoint %q: expected latitude,longitude[,altitude]", point)
+		}
+		var waypoint Waypoint
+		var err error
+		if waypoint.Latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil || math.Abs(waypoint.Latitude) > 90 {
+			return nil, fmt.Errorf("waypoint %q: invalid latitude", point)
+		}
+		if waypoint.Longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil || math.Abs(waypoint.Longitude) > 180 {
+			return nil, fmt.Errorf("waypoint %q: invalid longitude", point)
+		}
+		if len(parts) == 3 {
+			altitude := strings.TrimSpace(parts[2])
+			if strings.HasPrefix(altitude, "-") {
+				return nil, fmt.Errorf("waypoint %q: negative altitude, the GPS element carries 0 m or more", point)
+			}
+			meters, err := strconv.ParseUint(altitude, 10, 16)
+			if err != nil || meters > math.MaxInt16 {
+				return nil, fmt.Errorf("waypoint %q: invalid altitude, expected 0 to %d m", point, math.MaxInt16)
+			}
+			waypoint.Altitude = int64(meters)
+		}
+		route = append(route, waypoint)
+	}
+	return route, nil
+}
+
+// position returns the GPS element of record sequence: the waypoints are
+// visited in turn, one per record, and the speed and angle are those of
+// the move from the previous waypoint in elapsed seconds. An empty route
+// gives an element without a fix.
+func position(route []Waypoint, sequence int, elapsed float64, satellites int64) *tool_domain.GPSData {
+	if len(route) == 0 {
+		return &tool_domain.GPSData{}
+	}
+	current := route[sequence%len(route)]
+	gps := &tool_domain.GPSData{
+		Latitude:  current.Latitude,
+		Longitude: current.Longitude,
+		Altitude:  current.Altitude,
+		Satelites: satellites,
+	}
+	if sequence == 0 || len(route) == 1 || elapsed <= 0 {
+		return gps
+	}
+	previous := route[(sequence-1)%len(route)]
+	speed := distance(previous, current) / elapsed * 3.6
+	gps.Speed = int64(math.Min(math.Round(speed), math.MaxUint16))
+	gps.Angle = int64(math.Round(bearing(previous, current))) % 360
+	return gps
+}
+
+// distance returns the great-circle distance between from and to in
+// meters.
+func distance(from Waypoint, to Waypoint) float64 {
+	lat1, lat2 := radians(from.Latitude), radians(to.Latitude)
+	dLat, dLon := lat2-lat1, radians(to.Longitude-from.Longitude)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// bearing returns the initial heading from from to to in degrees, 0 to
+// 360 clockwise from north.
+func bearing(from Waypoint, to Waypoint) float64 {
+	lat1, lat2 := radians(from.Latitude), radians(to.Latitude)
+	dLon := radians(to.Longitude - from.Longitude)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
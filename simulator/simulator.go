@@ -0,0 +1,535 @@
+// Package simulator emulates Teltonika devices, such as an FMB tracker,
+// to test ingestion servers without hardware. A Device logs in, streams
+// synthetic AVL records along a route and answers Codec 12 commands.
+package simulator
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	header_domain "github.com/danieljvsa/teltonika-go/internal/header"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+var (
+	// ErrLoginRejected is returned by Run when the server answers the login
+	// packet with 0x00.
+	ErrLoginRejected = errors.New("login rejected")
+	// ErrAckTimeout is returned by Run when a frame is not acknowledged
+	// within the ACK timeout.
+	ErrAckTimeout = errors.New("ACK timeout")
+)
+
+// MaxRecordsPerFrame is the largest RecordsPerFrame: the record count of
+// an AVL frame is one byte.
+const MaxRecordsPerFrame = 255
+
+// commandGrace is how long a Device waiting for an ACK looks for the rest
+// of a command frame after reading four zero bytes, which are either an
+// ACK of zero records or the preamble of a command.
+const commandGrace = 100 * time.Millisecond
+
+// Device is a simulated Teltonika device. The zero value of each optional
+// field selects the default given in its comment. A Device runs one
+// connection at a time; its counters accumulate over calls to Run.
+//
+// Example:
+//
+//	route, _ := simulator.ParseRoute("54.6872,25.2797;54.6890,25.2850")
+//	voltage, _ := tools.NewIOUint(66, 2, 12800)
+//	device := &simulator.Device{
+//		IMEI:     "356307042441013",
+//		Addr:     "localhost:5027",
+//		CodecID:  0x8E,
+//		Route:    route,
+//		IOs:      []io_domain.IOData{voltage},
+//		Interval: 5 * time.Second,
+//	}
+//	err := device.Run(ctx)
+type Device struct {
+	// IMEI identifies the device in the login packet and UDP headers.
+	IMEI string
+	// Addr is the server address.
+	Addr string
+	// Protocol is "TCP" or "UDP", TCP if empty.
+	Protocol string
+	// CodecID selects the AVL codec: 0x08 (default), 0x8E or 0x10.
+	CodecID byte
+	// Route is visited one waypoint per record, in a loop. Without a
+	// route the device reports no fix.
+	Route []Waypoint
+	// Satellites is reported while the route gives a position, 10 if zero.
+	Satellites int64
+	// RecordsPerFrame is the number of records of each frame, 1 if zero,
+	// at most MaxRecordsPerFrame.
+	RecordsPerFrame int
+	// Interval is the time between two frames, 10 seconds if zero. The
+	// timestamps of the records of a frame are spread over it.
+	Interval time.Duration
+	// Frames is the number of frames to send before Run returns; zero
+	// sends frames until the context is done.
+	Frames int
+	// Until stops Run before a frame that would be sent at or after it.
+	// The ACK of the frame sent last is still awaited. Zero sends frames until
+	// Frames are sent or the context is done.
+	Until time.Time
+	// Priority and EventIO are set on every record.
+	Priority int64
+	EventIO  int64
+	// IOs are the IO elements of every record, unless IOFunc is set.
+	IOs []io_domain.IOData
+	// IOFunc returns the IO elements of the record with the given
+	// sequence number, counting from 0 over the life of the Device.
+	IOFunc func(sequence int) []io_domain.IOData
+	// Responses are the canned answers to Codec 12 commands, keyed by
+	// command or command name. Nil selects DefaultResponses.
+	Responses map[string]string
+	// AckTimeout bounds the wait for the login reply and each ACK, 10
+	// seconds if zero.
+	AckTimeout time.Duration
+	// Retries is the number of times an unacknowledged UDP packet is sent
+	// again before Run fails with ErrAckTimeout.
+	Retries int
+	// OnAck is called after each acknowledged frame with the number of
+	// records sent, the number accepted and the time the ACK took.
+	OnAck func(records int, accepted int64, latency time.Duration)
+	// ErrorLog receives frames from the server that cannot be answered. If
+	// nil, the log package's standard logger is used.
+	ErrorLog *log.Logger
+
+	next        time.Time // planned time of the next frame
+	sequence    int
+	packetID    uint16
+	avlPacketID uint8
+	stats       counters
+}
+
+// Stats counts the traffic of a Device.
+type Stats struct {
+	Frames       int64 // frames sent, UDP retries excluded
+	Records      int64 // records sent
+	Acknowledged int64 // records the server acknowledged as accepted
+	Commands     int64 // commands answered
+}
+
+type counters struct {
+	frames, records, acknowledged, commands atomic.Int64
+}
+
+// Next returns the time the next frame is planned for, zero before the
+// first call to Run. Like the counters, it carries over calls to Run.
+func (d *Device) Next() time.Time {
+	return d.next
+}
+
+// Stats returns the traffic counters. It may be called while Run is
+// running.
+func (d *Device) Stats() Stats {
+	return Stats{
+		Frames:       d.stats.frames.Load(),
+		Records:      d.stats.records.Load(),
+		Acknowledged: d.stats.acknowledged.Load(),
+		Commands:     d.stats.commands.Load(),
+	}
+}
+
+// Run connects to the server and streams frames until Frames are sent,
+// Until is reached or ctx is done, in which case it returns nil. Over TCP
+// it first sends the login packet and, while waiting between frames,
+// answers Codec 12 commands. Records the server does not accept are
+// counted in Stats but not sent again. When Run is called again, it keeps
+// the Interval after the last frame of the previous call, waiting for the
+// next frame before it connects.
+func (d *Device) Run(ctx context.Context) error {
+	if err := d.validate(); err != nil {
+		return err
+	}
+	if d.next.IsZero() {
+		d.next = time.Now()
+	}
+	if d.stopped() || sleepUntil(ctx, d.next) != nil {
+		return nil
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, strings.ToLower(d.protocol()), d.Addr)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer conn.Close()
+	// Closing the connection interrupts any read or write in progress.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if d.protocol() == "UDP" {
+		err = d.runUDP(ctx, conn)
+	} else {
+		err = d.runTCP(conn)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (d *Device) validate() error {
+	if d.IMEI == "" {
+		return fmt.Errorf("IMEI is empty")
+	}
+	switch d.protocol() {
+	case "TCP", "UDP":
+	default:
+		return fmt.Errorf("unsupported protocol: %s", d.Protocol)
+	}
+	switch d.codecID() {
+	case 0x08, 0x8E, 0x10:
+	default:
+		return fmt.Errorf("unsupported AVL codec: 0x%02X", d.CodecID)
+	}
+	if d.RecordsPerFrame > MaxRecordsPerFrame {
+		return fmt.Errorf("%d records per frame, at most %d fit in a frame", d.RecordsPerFrame, MaxRecordsPerFrame)
+	}
+	return nil
+}
+
+func (d *Device) runTCP(conn net.Conn) error {
+	reader := bufio.NewReader(conn)
+	if err := d.login(conn, reader); err != nil {
+		return err
+	}
+	for sent := 0; (d.Frames == 0 || sent < d.Frames) && !d.stopped(); sent++ {
+		if err := d.idle(conn, reader, d.next); err != nil {
+			return err
+		}
+		frame, records, err := d.frame(nil)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		conn.SetDeadline(start.Add(d.ackTimeout()))
+		if _, err := conn.Write(frame); err != nil {
+			return err
+		}
+		d.sent(records)
+		accepted, err := d.tcpAck(conn, reader)
+		if err != nil {
+			return err
+		}
+		d.acknowledged(records, accepted, time.Since(start))
+		d.next = nextSend(d.next, d.interval())
+	}
+	return nil
+}
+
+// login sends the login packet and reads the reply.
+func (d *Device) login(conn net.Conn, reader *bufio.Reader) error {
+	packet, err := tools.EncodeLogin(d.IMEI)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(d.ackTimeout()))
+	if _, err := conn.Write(packet); err != nil {
+		return err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(reader, reply); err != nil {
+		return timeoutError(err, "login reply")
+	}
+	accepted, err := tools.DecodeLoginResponse(reply)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return fmt.Errorf("%w: %s", ErrLoginRejected, d.IMEI)
+	}
+	return nil
+}
+
+// idle answers the commands that arrive until the time of the next frame.
+func (d *Device) idle(conn net.Conn, reader *bufio.Reader, until time.Time) error {
+	for time.Now().Before(until) {
+		conn.SetReadDeadline(until)
+		if _, err := reader.Peek(1); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+		conn.SetDeadline(time.Now().Add(d.ackTimeout()))
+		preamble := make([]byte, 4)
+		if _, err := io.ReadFull(reader, preamble); err != nil {
+			return err
+		}
+		if err := d.command(conn, reader, preamble); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tcpAck reads the ACK of a frame, answering a command that arrives
+// before it.
+func (d *Device) tcpAck(conn net.Conn, reader *bufio.Reader) (int64, error) {
+	for {
+		ack := make([]byte, 4)
+		if _, err := io.ReadFull(reader, ack); err != nil {
+			return 0, timeoutError(err, "ACK")
+		}
+		accepted, _ := tools.DecodeTCPAck(ack)
+		if accepted != 0 {
+			return accepted, nil
+		}
+		// Four zero bytes followed by a data length start a command frame;
+		// an ACK of zero records is followed by nothing.
+		conn.SetReadDeadline(time.Now().Add(commandGrace))
+		length, err := reader.Peek(4)
+		conn.SetDeadline(time.Now().Add(d.ackTimeout()))
+		if err != nil || binary.BigEndian.Uint32(length) == 0 {
+			return 0, nil
+		}
+		if err := d.command(conn, reader, ack); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// command reads the rest of the frame starting with preamble and writes
+// the answer to its commands. Frames that cannot be answered are logged
+// and skipped.
+func (d *Device) command(conn net.Conn, reader *bufio.Reader, preamble []byte) error {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(reader, length); err != nil {
+		return err
+	}
+	size := 8 + int(binary.BigEndian.Uint32(length)) + 4
+	if size > pkg.DefaultMaxFrameSize {
+		return fmt.Errorf("%w: %d bytes", pkg.ErrFrameTooLarge, size)
+	}
+	frame := make([]byte, size)
+	copy(frame, preamble)
+	copy(frame[4:], length)
+	if _, err := io.ReadFull(reader, frame[8:]); err != nil {
+		return err
+	}
+	reply, commands, err := answer(d.Responses, frame)
+	if err != nil {
+		d.logf("simulator: %s cannot answer frame %X: %v", d.IMEI, frame, err)
+		return nil
+	}
+	if reply == nil {
+		return nil
+	}
+	if _, err := conn.Write(reply); err != nil {
+		return err
+	}
+	d.stats.commands.Add(int64(commands))
+	return nil
+}
+
+func (d *Device) runUDP(ctx context.Context, conn net.Conn) error {
+	ack := make([]byte, 64)
+	for sent := 0; (d.Frames == 0 || sent < d.Frames) && !d.stopped(); sent++ {
+		if err := sleepUntil(ctx, d.next); err != nil {
+			return nil
+		}
+		d.packetID++
+		header := &header_domain.HeaderDataUDP{PacketID: int64(d.packetID), AVLPacketID: int64(d.avlPacketID), IMEI: d.IMEI}
+		d.avlPacketID++
+		frame, records, err := d.frame(header)
+		if err != nil {
+			return err
+		}
+		d.sent(records)
+		acknowledged := false
+		for attempt := 0; attempt <= d.Retries && !acknowledged; attempt++ {
+			start := time.Now()
+			conn.SetDeadline(start.Add(d.ackTimeout()))
+			if _, err := conn.Write(frame); err != nil {
+				return err
+			}
+			accepted, err := readUDPAck(conn, ack, header)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			acknowledged = true
+			d.acknowledged(records, accepted, time.Since(start))
+		}
+		if !acknowledged {
+			return fmt.Errorf("%w: packet %04X", ErrAckTimeout, header.PacketID)
+		}
+		d.next = nextSend(d.next, d.interval())
+	}
+	return nil
+}
+
+// readUDPAck reads datagrams until the ACK of the packet with header
+// arrives, skipping late ACKs of earlier packets.
+func readUDPAck(conn net.Conn, buffer []byte, header *header_domain.HeaderDataUDP) (int64, error) {
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return 0, err
+		}
+		ack, err := tools.DecodeUDPAck(buffer[:n])
+		if err != nil {
+			continue
+		}
+		if ack.PacketID == header.PacketID && ack.AVLPacketID == header.AVLPacketID {
+			return ack.Accepted, nil
+		}
+	}
+}
+
+// frame encodes the next RecordsPerFrame records as a TCP frame, or as a
+// UDP packet with header.
+func (d *Device) frame(header *header_domain.HeaderDataUDP) ([]byte, int, error) {
+	count := d.recordsPerFrame()
+	spacing := d.interval() / time.Duration(count)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	records := make([]decoder_domain.Record, count)
+	for i := range records {
+		timestamp := now.Add(-time.Duration(count-1-i) * spacing)
+		priority, eventIO := d.Priority, d.EventIO
+		ios := d.IOs
+		if d.IOFunc != nil {
+			ios = d.IOFunc(d.sequence)
+		}
+		ios = append([]io_domain.IOData{}, ios...)
+		records[i] = decoder_domain.Record{
+			Timestamp: &timestamp,
+			Priority:  &priority,
+			GPSData:   position(d.Route, d.sequence, spacing.Seconds(), d.satellites()),
+			EventIO:   &eventIO,
+			IOs:       &ios,
+		}
+		if d.codecID() == 0x10 {
+			generationType := decoder_domain.GenerationPeriodical
+			records[i].GenerationType = &generationType
+		}
+		d.sequence++
+	}
+
+	request := &decoder_domain.CodecHeaderResponse{
+		CodecData: &decoder_domain.CodecData{CodecID: d.codecID(), NumberOfRecords: int64(count), Records: records},
+	}
+	if header != nil {
+		request.HeaderData = &header_domain.HeaderData{Protocol: "UDP", HeaderUDP: header}
+	}
+	frame, err := pkg.TramEncoder(request)
+	return frame, count, err
+}
+
+func (d *Device) sent(records int) {
+	d.stats.frames.Add(1)
+	d.stats.records.Add(int64(records))
+}
+
+func (d *Device) acknowledged(records int, accepted int64, latency time.Duration) {
+	d.stats.acknowledged.Add(accepted)
+	if d.OnAck != nil {
+		d.OnAck(records, accepted, latency)
+	}
+}
+
+// stopped reports whether the next frame would be sent at or after Until.
+func (d *Device) stopped() bool {
+	return !d.Until.IsZero() && (!d.next.Before(d.Until) || !time.Now().Before(d.Until))
+}
+
+// nextSend returns the time of the frame after the one planned at last.
+// A device that fell behind sends at once instead of catching up.
+func nextSend(last time.Time, interval time.Duration) time.Time {
+	next := last.Add(interval)
+	if now := time.Now(); next.Before(now) {
+		return now
+	}
+	return next
+}
+
+// sleepUntil waits until t or until ctx is done, returning ctx.Err() then.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// timeoutError reports a read deadline as ErrAckTimeout.
+func timeoutError(err error, what string) error {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: no %s", ErrAckTimeout, what)
+	}
+	return err
+}
+
+func (d *Device) protocol() string {
+	if d.Protocol == "" {
+		return "TCP"
+	}
+	return strings.ToUpper(d.Protocol)
+}
+
+func (d *Device) codecID() byte {
+	if d.CodecID == 0 {
+		return 0x08
+	}
+	return d.CodecID
+}
+
+func (d *Device) recordsPerFrame() int {
+	if d.RecordsPerFrame <= 0 {
+		return 1
+	}
+	return d.RecordsPerFrame
+}
+
+func (d *Device) interval() time.Duration {
+	if d.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return d.Interval
+}
+
+func (d *Device) satellites() int64 {
+	if d.Satellites == 0 {
+		return 10
+	}
+	return d.Satellites
+}
+
+func (d *Device) ackTimeout() time.Duration {
+	if d.AckTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return d.AckTimeout
+}
+
+func (d *Device) logf(format string, args ...any) {
+	if d.ErrorLog != nil {
+		d.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -1,9 +1,11 @@
 package teltonika_go
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"testing"
 
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
 	"github.com/danieljvsa/teltonika-go/tools"
 )
 
@@ -157,3 +159,67 @@ func BenchmarkParallelCRC(b *testing.B) {
 		}
 	})
 }
+
+// Benchmarks for complete frames
+
+// benchmarkTramDecoder decodes frame with TramDecoder and reports, next to
+// the usual per-operation figures, the allocations per decoded record.
+func benchmarkTramDecoder(b *testing.B, frame []byte) {
+	decoded := pkg.TramDecoder(frame)
+	if decoded.Error != nil {
+		b.Fatalf("TramDecoder failed: %v", decoded.Error)
+	}
+	records := float64(decoded.Response.Result.CodecData.NumberOfRecords)
+	allocs := testing.AllocsPerRun(100, func() {
+		pkg.TramDecoder(frame)
+	})
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pkg.TramDecoder(frame)
+	}
+	b.ReportMetric(records, "records/op")
+	b.ReportMetric(allocs/records, "allocs/record")
+}
+
+// codec8Frame builds a TCP Codec 08 frame repeating the record of the
+// "Valid Codec 08 TCP" vector count times.
+func codec8Frame(count int) []byte {
+	record, _ := hex.DecodeString("0000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000")
+	body := []byte{0x08, byte(count)}
+	for range count {
+		body = append(body, record...)
+	}
+	body = append(body, byte(count))
+
+	frame := make([]byte, 8, 8+len(body)+4)
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(body)))
+	frame = append(frame, body...)
+	return binary.BigEndian.AppendUint32(frame, uint32(tools.Crc16IBM(body)))
+}
+
+// BenchmarkTramDecoderCodec8 benchmarks decoding a one-record Codec 08 frame.
+func BenchmarkTramDecoderCodec8(b *testing.B) {
+	benchmarkTramDecoder(b, codec8Frame(1))
+}
+
+// BenchmarkTramDecoderCodec8Full benchmarks decoding a Codec 08 frame with
+// 255 records, the most a frame can carry. Allocations per record should
+// approach zero as they are made once per frame.
+func BenchmarkTramDecoderCodec8Full(b *testing.B) {
+	benchmarkTramDecoder(b, codec8Frame(255))
+}
+
+// BenchmarkTramDecoderCodec8Ext benchmarks decoding a Codec 8 Extended frame.
+func BenchmarkTramDecoderCodec8Ext(b *testing.B) {
+	frame, _ := hex.DecodeString("000000000000004A8E010000016B412CEE000100000000000000000000000000000000010005000100010100010011001D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A00000100002994")
+	benchmarkTramDecoder(b, frame)
+}
+
+// BenchmarkTramDecoderCodec16 benchmarks decoding a two-record Codec 16 frame.
+func BenchmarkTramDecoderCodec16(b *testing.B) {
+	frame, _ := hex.DecodeString("000000000000005F10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A00000200005FB3")
+	benchmarkTramDecoder(b, frame)
+}
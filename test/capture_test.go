@@ -0,0 +1,335 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	capture "github.com/danieljvsa/teltonika-go/capture"
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+const captureTestCommand = "000000000000000F0C010500000007676574696E666F0100004312"
+
+var captureTestStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// capturedPacket describes a TCP segment or UDP datagram to write into a
+// test capture.
+type capturedPacket struct {
+	source, target netip.AddrPort
+	udp            bool
+	sequence       uint32
+	flags          byte
+	vlan           bool
+	payload        []byte
+}
+
+const (
+	tcpFIN = 0x01
+	tcpSYN = 0x02
+	tcpACK = 0x10
+)
+
+// ethernet builds the Ethernet frame of packet, over IPv4 or IPv6
+// according to its addresses. Checksums are left zero.
+func (packet capturedPacket) ethernet() []byte {
+	var transport []byte
+	protocol := byte(6)
+	if packet.udp {
+		protocol = 17
+		transport = binary.BigEndian.AppendUint16(nil, packet.source.Port())
+		transport = binary.BigEndian.AppendUint16(transport, packet.target.Port())
+		transport = binary.BigEndian.AppendUint16(transport, uint16(8+len(packet.payload)))
+		transport = append(transport, 0, 0)
+	} else {
+		transport = binary.BigEndian.AppendUint16(nil, packet.source.Port())
+		transport = binary.BigEndian.AppendUint16(transport, packet.target.Port())
+		transport = binary.BigEndian.AppendUint32(transport, packet.sequence)
+		transport = append(transport, 0, 0, 0, 0, 0x50, packet.flags|tcpACK, 0xFF, 0xFF, 0, 0, 0, 0)
+	}
+	transport = append(transport, packet.payload...)
+
+	frame := []byte{0x02, 0, 0, 0, 0, 1, 0x02, 0, 0, 0, 0, 2}
+	if packet.vlan {
+		frame = append(frame, 0x81, 0x00, 0x00, 0x64)
+	}
+	if packet.source.Addr().Is4() {
+		frame = append(frame, 0x08, 0x00, 0x45, 0x00)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(20+len(transport)))
+		frame = append(frame, 0, 1, 0x40, 0, 64, protocol, 0, 0)
+		frame = append(frame, packet.source.Addr().AsSlice()...)
+		frame = append(frame, packet.target.Addr().AsSlice()...)
+	} else {
+		frame = append(frame, 0x86, 0xDD, 0x60, 0, 0, 0)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(transport)))
+		frame = append(frame, protocol, 64)
+		frame = append(frame, packet.source.Addr().AsSlice()...)
+		frame = append(frame, packet.target.Addr().AsSlice()...)
+	}
+	return append(frame, transport...)
+}
+
+// writePcap writes packets as a little-endian pcap file with microsecond
+// timestamps, 10 ms apart.
+func writePcap(packets []capturedPacket) []byte {
+	file := binary.LittleEndian.AppendUint32(nil, 0xA1B2C3D4)
+	file = append(file, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xFF, 0, 0, 1, 0, 0, 0)
+	for i, packet := range packets {
+		data := packet.ethernet()
+		at := captureTestStart.Add(time.Duration(i) * 10 * time.Millisecond)
+		file = binary.LittleEndian.AppendUint32(file, uint32(at.Unix()))
+		file = binary.LittleEndian.AppendUint32(file, uint32(at.Nanosecond()/1000))
+		file = binary.LittleEndian.AppendUint32(file, uint32(len(data)))
+		file = binary.LittleEndian.AppendUint32(file, uint32(len(data)))
+		file = append(file, data...)
+	}
+	return file
+}
+
+// writePcapng writes packets as a big-endian pcapng file with nanosecond
+// timestamps, 10 ms apart.
+func writePcapng(packets []capturedPacket) []byte {
+	block := func(file []byte, blockType uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		file = binary.BigEndian.AppendUint32(file, blockType)
+		file = binary.BigEndian.AppendUint32(file, uint32(12+len(body)))
+		file = append(file, body...)
+		return binary.BigEndian.AppendUint32(file, uint32(12+len(body)))
+	}
+	file := block(nil, 0x0A0D0D0A, []byte{0x1A, 0x2B, 0x3C, 0x4D, 0, 1, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+	// Ethernet, snap length 65535, if_tsresol of 10^-9 and end of options
+	file = block(file, 1, []byte{0, 1, 0, 0, 0, 0, 0xFF, 0xFF, 0, 9, 0, 1, 9, 0, 0, 0, 0, 0, 0, 0})
+	for i, packet := range packets {
+		data := packet.ethernet()
+		timestamp := uint64(captureTestStart.Add(time.Duration(i) * 10 * time.Millisecond).UnixNano())
+		body := binary.BigEndian.AppendUint32(nil, 0)
+		body = binary.BigEndian.AppendUint32(body, uint32(timestamp>>32))
+		body = binary.BigEndian.AppendUint32(body, uint32(timestamp))
+		body = binary.BigEndian.AppendUint32(body, uint32(len(data)))
+		body = binary.BigEndian.AppendUint32(body, uint32(len(data)))
+		file = block(file, 6, append(body, data...))
+	}
+	return file
+}
+
+func extractTestCapture(t *testing.T, file []byte, extractor *capture.Extractor) ([]capture.Message, capture.Stats) {
+	t.Helper()
+	reader, err := capture.NewReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	var messages []capture.Message
+	stats, err := extractor.Extract(reader, func(message capture.Message) error {
+		messages = append(messages, message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	return messages, stats
+}
+
+// tcpConversation returns the packets of a TCP connection in which a
+// device logs in, sends a frame split in two segments that arrive out of
+// order and retransmitted, and answers a Codec 12 command.
+func tcpConversation(t *testing.T) []capturedPacket {
+	t.Helper()
+	device := netip.MustParseAddrPort("10.1.0.2:40000")
+	server := netip.MustParseAddrPort("10.1.0.1:5027")
+	login, _ := tools.EncodeLogin(serverTestIMEI)
+	frame, _ := hex.DecodeString(cliTestFrame)
+	command, _ := hex.DecodeString(captureTestCommand)
+	responseType := "Response"
+	response, err := pkg.TramEncoder(&decoder_domain.CodecHeaderResponse{CodecData: &decoder_domain.CodecData{
+		CodecID:         0x0C,
+		NumberOfRecords: 1,
+		Records:         []decoder_domain.Record{{CommandType: &responseType, CommandResponses: &[]tool_domain.CommandResponse{{Response: "INFO"}}}},
+	}})
+	if err != nil {
+		t.Fatalf("encoding the command response failed: %v", err)
+	}
+
+	deviceSequence := uint32(1000) + 1
+	serverSequence := uint32(5000) + 1
+	afterLogin := deviceSequence + uint32(len(login))
+	afterFrame := afterLogin + uint32(len(frame))
+	return []capturedPacket{
+		{source: device, target: server, sequence: 1000, flags: tcpSYN},
+		{source: server, target: device, sequence: 5000, flags: tcpSYN},
+		{source: device, target: server, sequence: deviceSequence, payload: login},
+		{source: server, target: device, sequence: serverSequence, payload: []byte{0x01}},
+		{source: device, target: server, sequence: afterLogin + 30, payload: frame[30:]},
+		{source: device, target: server, sequence: afterLogin, payload: frame[:30]},
+		{source: device, target: server, sequence: afterLogin, payload: frame[:30]},
+		{source: server, target: device, sequence: serverSequence + 1, payload: []byte{0, 0, 0, 1}},
+		{source: server, target: device, sequence: serverSequence + 5, payload: command},
+		{source: device, target: server, sequence: afterFrame, payload: response},
+		{source: device, target: server, sequence: afterFrame + uint32(len(response)), flags: tcpFIN},
+		{source: server, target: device, sequence: serverSequence + 5 + uint32(len(command)), flags: tcpFIN},
+	}
+}
+
+func TestCaptureExtractsTCPConversation(t *testing.T) {
+	for name, write := range map[string]func([]capturedPacket) []byte{"pcap": writePcap, "pcapng": writePcapng} {
+		t.Run(name, func(t *testing.T) {
+			messages, stats := extractTestCapture(t, write(tcpConversation(t)), &capture.Extractor{})
+			if stats.Packets != 12 || stats.Conversations != 1 || stats.Messages != 6 || len(messages) != 6 {
+				t.Fatalf("unexpected stats %+v for messages %+v", stats, messages)
+			}
+			for i, want := range []struct {
+				kind       string
+				fromDevice bool
+				accepted   int64
+			}{
+				{capture.KindLogin, true, 0},
+				{capture.KindLoginResponse, false, 1},
+				{capture.KindFrame, true, 0},
+				{capture.KindAck, false, 1},
+				{capture.KindFrame, false, 0},
+				{capture.KindFrame, true, 0},
+			} {
+				message := messages[i]
+				if message.Kind != want.kind || message.FromDevice != want.fromDevice || message.Accepted != want.accepted || message.IMEI != serverTestIMEI || message.Transport != "TCP" || message.Gap {
+					t.Errorf("message %d: expected %+v, got %+v", i, want, message)
+				}
+				if message.Kind == capture.KindFrame && message.Decoded.Error != nil {
+					t.Errorf("message %d: decoding failed: %v", i, message.Decoded.Error)
+				}
+			}
+			if frame := messages[2]; hex.EncodeToString(frame.Data) != lowerHex(cliTestFrame) || frame.Source.String() != "10.1.0.2:40000" || !frame.Time.Equal(captureTestStart.Add(50*time.Millisecond)) {
+				t.Errorf("unexpected reassembled frame %+v", frame)
+			}
+			if records := messages[2].Decoded.Response.Result.CodecData.Records; len(records) != 1 {
+				t.Errorf("expected 1 record, got %d", len(records))
+			}
+			if responses := *messages[5].Decoded.Response.Result.CodecData.Records[0].CommandResponses; responses[0].Response != "INFO" {
+				t.Errorf("unexpected command response %+v", responses)
+			}
+		})
+	}
+}
+
+func lowerHex(text string) string {
+	data, _ := hex.DecodeString(text)
+	return hex.EncodeToString(data)
+}
+
+func TestCaptureExtractsMidStreamAndUDP(t *testing.T) {
+	frame, _ := hex.DecodeString(cliTestFrame)
+	datagram, _ := hex.DecodeString(journalTestDatagram)
+	device := netip.MustParseAddrPort("[2001:db8::2]:40000")
+	server := netip.MustParseAddrPort("[2001:db8::1]:5027")
+	udpDevice := netip.MustParseAddrPort("10.2.0.2:6000")
+	udpServer := netip.MustParseAddrPort("10.2.0.1:5027")
+	web := netip.MustParseAddrPort("10.3.0.2:50000")
+	webServer := netip.MustParseAddrPort("10.3.0.1:80")
+	ack, _ := tools.EncodeUDPAck(0xCAFE, 0x05, 1)
+
+	packets := []capturedPacket{
+		// A connection already open: an ACK, then a frame split in two.
+		{source: server, target: device, sequence: 7000, payload: []byte{0, 0, 0, 1}},
+		{source: device, target: server, sequence: 300, payload: frame[:20]},
+		{source: device, target: server, sequence: 320, payload: frame[20:]},
+		{source: server, target: device, sequence: 7004, payload: []byte{0, 0, 0, 1}},
+		{source: web, target: webServer, sequence: 1, payload: []byte("GET / HTTP/1.1\r\n\r\n")},
+		{source: webServer, target: web, sequence: 1, payload: []byte("HTTP/1.1 200 OK\r\n\r\n")},
+		{source: udpDevice, target: udpServer, udp: true, vlan: true, payload: datagram},
+		{source: udpServer, target: udpDevice, udp: true, vlan: true, payload: ack},
+		{source: udpDevice, target: udpServer, udp: true, payload: []byte("not teltonika")},
+	}
+	messages, stats := extractTestCapture(t, writePcapng(packets), &capture.Extractor{})
+	if stats.Conversations != 2 || len(messages) != 4 {
+		t.Fatalf("expected 4 messages in 2 conversations, got %+v: %+v", stats, messages)
+	}
+	if message := messages[0]; message.Kind != capture.KindFrame || !message.FromDevice || message.IMEI != "" || message.Source != device || message.Decoded.Error != nil {
+		t.Errorf("unexpected mid-stream frame %+v", message)
+	}
+	if message := messages[1]; message.Kind != capture.KindAck || message.FromDevice || message.Accepted != 1 {
+		t.Errorf("unexpected TCP ACK %+v", message)
+	}
+	if message := messages[2]; message.Kind != capture.KindFrame || message.Transport != "UDP" || message.IMEI != "352093086403655" || !message.FromDevice || message.Decoded.Error != nil {
+		t.Errorf("unexpected UDP frame %+v", message)
+	}
+	if message := messages[3]; message.Kind != capture.KindAck || message.IMEI != "352093086403655" || message.Accepted != 1 || message.Destination != udpDevice {
+		t.Errorf("unexpected UDP ACK %+v", message)
+	}
+
+	entries := 0
+	for _, message := range messages {
+		if entry, ok := message.JournalEntry(); ok {
+			entries++
+			if entry.RemoteAddr != message.Source.String() || entry.Transport != message.Transport {
+				t.Errorf("unexpected journal entry %+v", entry)
+			}
+		}
+	}
+	if entries != 2 {
+		t.Errorf("expected 2 journal entries, got %d", entries)
+	}
+
+	document, err := json.Marshal(messages[3])
+	if err != nil || !bytes.Contains(document, []byte(`"accepted":1`)) || !bytes.Contains(document, []byte(`"source":"10.2.0.1:5027"`)) {
+		t.Errorf("unexpected JSON %s, %v", document, err)
+	}
+
+	if messages, _ := extractTestCapture(t, writePcapng(packets), &capture.Extractor{Ports: []uint16{80}}); len(messages) != 0 {
+		t.Errorf("expected no message on port 80, got %+v", messages)
+	}
+}
+
+func TestCaptureRecoversFromMissingSegments(t *testing.T) {
+	frame, _ := hex.DecodeString(cliTestFrame)
+	login, _ := tools.EncodeLogin(serverTestIMEI)
+	device := netip.MustParseAddrPort("10.1.0.2:40000")
+	server := netip.MustParseAddrPort("10.1.0.1:5027")
+	sequence := uint32(101)
+	packets := []capturedPacket{
+		{source: device, target: server, sequence: 100, flags: tcpSYN},
+		{source: device, target: server, sequence: sequence, payload: login},
+	}
+	sequence += uint32(len(login))
+	for i := range 4 {
+		if i != 1 { // the middle frame is not captured
+			packets = append(packets, capturedPacket{source: device, target: server, sequence: sequence, payload: frame})
+		}
+		sequence += uint32(len(frame))
+	}
+
+	// The frames after the gap wait for the end of the capture, or until
+	// they exceed MaxBuffered.
+	for _, maxBuffered := range []int{0, 100} {
+		messages, _ := extractTestCapture(t, writePcap(packets), &capture.Extractor{MaxBuffered: maxBuffered})
+		if len(messages) != 4 || messages[1].Gap || !messages[2].Gap || messages[3].Gap || messages[2].Kind != capture.KindFrame || messages[2].IMEI != serverTestIMEI {
+			t.Errorf("MaxBuffered %d: expected login, frame and 2 frames after a gap, got %+v", maxBuffered, messages)
+		}
+	}
+}
+
+func TestCaptureRejectsInvalidFiles(t *testing.T) {
+	if _, err := capture.NewReader(bytes.NewReader([]byte("definitely not a capture"))); !errors.Is(err, capture.ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	for name, file := range map[string][]byte{
+		"pcap":   writePcap(tcpConversation(t)),
+		"pcapng": writePcapng(tcpConversation(t)),
+	} {
+		reader, err := capture.NewReader(bytes.NewReader(file[:len(file)-5]))
+		if err != nil {
+			t.Fatalf("%s: NewReader failed: %v", name, err)
+		}
+		_, err = (&capture.Extractor{}).Extract(reader, func(capture.Message) error { return nil })
+		if !errors.Is(err, capture.ErrMalformed) {
+			t.Errorf("%s: expected ErrMalformed for a truncated file, got %v", name, err)
+		}
+	}
+}
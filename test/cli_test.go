@@ -0,0 +1,220 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	server "github.com/danieljvsa/teltonika-go/server"
+)
+
+const cliTestFrame = "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF"
+
+// buildCLI builds the teltonika_go command into a temporary directory.
+func buildCLI(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building the command is skipped in short mode")
+	}
+	binary := filepath.Join(t.TempDir(), "teltonika_go")
+	build := exec.Command("go", "build", "-o", binary, "../cmd/teltonika_go")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, output)
+	}
+	return binary
+}
+
+// runCLI runs the command with stdin and returns its standard output and
+// exit code.
+func runCLI(t *testing.T, binary, stdin string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(binary, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("running %v failed: %v", args, err)
+	}
+	return stdout.String(), 0
+}
+
+func TestCLI(t *testing.T) {
+	binary := buildCLI(t)
+
+	t.Run("Decode and encode", func(t *testing.T) {
+		document, code := runCLI(t, binary, "", "decode", "-names", cliTestFrame)
+		if code != 0 {
+			t.Fatalf("decode exited with %d", code)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(document), &decoded); err != nil {
+			t.Fatalf("decode printed invalid JSON: %v", err)
+		}
+		if decoded["type"] != "Tram" {
+			t.Errorf("expected a Tram, got %v", decoded["type"])
+		}
+
+		frame, code := runCLI(t, binary, document, "encode")
+		if code != 0 {
+			t.Fatalf("encode exited with %d", code)
+		}
+		if len(strings.TrimSpace(frame)) != len(cliTestFrame) {
+			t.Errorf("expected a %d digit frame, got %q", len(cliTestFrame), frame)
+		}
+	})
+
+	t.Run("Decode from stdin", func(t *testing.T) {
+		if _, code := runCLI(t, binary, "0x"+strings.ToLower(cliTestFrame)+"\n", "decode"); code != 0 {
+			t.Errorf("decode exited with %d", code)
+		}
+	})
+
+	t.Run("Inspect", func(t *testing.T) {
+		output, code := runCLI(t, binary, "", "inspect", "-names", cliTestFrame)
+		if code != 0 {
+			t.Fatalf("inspect exited with %d", code)
+		}
+		for _, want := range []string{"CRC: 0xC7CF (valid)", "IO 66: External Voltage: 24.079 V"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected %q in\n%s", want, output)
+			}
+		}
+		corrupt := cliTestFrame[:len(cliTestFrame)-4] + "0000"
+		if output, code := runCLI(t, binary, "", "inspect", corrupt); code != 1 || !strings.Contains(output, "!! ") {
+			t.Errorf("expected exit code 1 and a marked field for a bad CRC, got %d:\n%s", code, output)
+		}
+	})
+
+	t.Run("CRC", func(t *testing.T) {
+		if output, code := runCLI(t, binary, "", "crc", "-verify", cliTestFrame); code != 0 {
+			t.Errorf("crc -verify exited with %d: %s", code, output)
+		}
+		corrupt := cliTestFrame[:len(cliTestFrame)-4] + "0000"
+		if _, code := runCLI(t, binary, "", "crc", "-verify", corrupt); code != 1 {
+			t.Errorf("expected exit code 1 for a bad CRC, got %d", code)
+		}
+		if output, _ := runCLI(t, binary, "", "crc", "08"); strings.TrimSpace(output) == "" {
+			t.Errorf("crc printed nothing")
+		}
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		output, code := runCLI(t, binary, "", "login", "356307042441013")
+		if code != 0 {
+			t.Fatalf("login exited with %d", code)
+		}
+		if got := strings.TrimSpace(output); got != "000F333536333037303432343431303133" {
+			t.Errorf("unexpected login packet %s", got)
+		}
+		if _, code := runCLI(t, binary, "", "login", "35630704244101X"); code == 0 {
+			t.Errorf("expected an invalid IMEI to fail")
+		}
+	})
+
+	t.Run("Simulate", func(t *testing.T) {
+		addr := startTCPServer(t, &server.TCPServer{})
+		output, code := runCLI(t, binary, "", "simulate", "-addr", addr, "-codec", "8E", "-records", "2", "-frames", "2", "-interval", "10ms", "-route", "54.6872,25.2797;54.689,25.285", "-io", "66=12800", "-io", "16=0x10:4")
+		if code != 0 {
+			t.Fatalf("simulate exited with %d", code)
+		}
+		if !strings.Contains(output, "frames 2, records 4, acknowledged 4") {
+			t.Errorf("unexpected output %q", output)
+		}
+		if _, code := runCLI(t, binary, "", "simulate", "-addr", addr, "-io", "66"); code != 2 {
+			t.Errorf("expected exit code 2 for an invalid IO element, got %d", code)
+		}
+		if _, code := runCLI(t, binary, "", "simulate", "-addr", addr, "-records", "300"); code != 2 {
+			t.Errorf("expected exit code 2 for 300 records per frame, got %d", code)
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traffic.tjl")
+		entries := testJournalEntries(t)
+		os.WriteFile(path, writeTestJournal(t, entries), 0o644)
+		output, code := runCLI(t, binary, "", "replay", "-names", path)
+		if code != 1 {
+			t.Errorf("expected exit code 1 with an undecodable frame, got %d", code)
+		}
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		var first struct {
+			Entry   map[string]any `json:"entry"`
+			Decoded map[string]any `json:"decoded"`
+		}
+		if len(lines) != 3 || json.Unmarshal([]byte(lines[0]), &first) != nil || first.Entry["imei"] != serverTestIMEI || first.Decoded["type"] != "Tram" {
+			t.Fatalf("unexpected output %q", output)
+		}
+		if output, _ := runCLI(t, binary, "", "replay", "-failed", path); strings.Count(output, "\n") != 1 || !strings.Contains(output, "dead") {
+			t.Errorf("expected only the failed frame, got %q", output)
+		}
+
+		os.WriteFile(path, writeTestJournal(t, entries[:1]), 0o644)
+		addr := startTCPServer(t, &server.TCPServer{})
+		if output, code := runCLI(t, binary, "", "replay", "-send", addr, path); code != 0 || output != "sent 1 frames\n" {
+			t.Errorf("replay -send exited with %d: %q", code, output)
+		}
+	})
+
+	t.Run("Pcap", func(t *testing.T) {
+		directory := t.TempDir()
+		path := filepath.Join(directory, "capture.pcap")
+		os.WriteFile(path, writePcap(tcpConversation(t)), 0o644)
+		output, code := runCLI(t, binary, "", "pcap", "-ports", "5027", path)
+		if code != 0 {
+			t.Fatalf("pcap exited with %d", code)
+		}
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		var login map[string]any
+		if len(lines) != 6 || json.Unmarshal([]byte(lines[0]), &login) != nil || login["kind"] != "login" || login["imei"] != serverTestIMEI {
+			t.Fatalf("unexpected output %q", output)
+		}
+
+		journalPath := filepath.Join(directory, "traffic.tjl")
+		if _, code := runCLI(t, binary, "", "pcap", "-journal", journalPath, path); code != 0 {
+			t.Fatalf("pcap -journal exited with %d", code)
+		}
+		if output, code := runCLI(t, binary, "", "replay", journalPath); code != 0 || strings.Count(output, "\n") != 2 {
+			t.Errorf("expected the 2 device frames in the journal, got %d: %q", code, output)
+		}
+		if _, code := runCLI(t, binary, "", "pcap", "-ports", "http", path); code != 2 {
+			t.Errorf("expected exit code 2 for an invalid port, got %d", code)
+		}
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		addr := startTCPServer(t, &server.TCPServer{})
+		output, code := runCLI(t, binary, "", "load", "-addr", addr, "-devices", "5", "-codecs", "8=2,16", "-interval", "10ms", "-duration", "300ms", "-report", "0")
+		if code != 0 {
+			t.Fatalf("load exited with %d", code)
+		}
+		if !strings.Contains(output, "devices 5") || !strings.Contains(output, "errors: none") {
+			t.Errorf("unexpected output %q", output)
+		}
+		if _, code := runCLI(t, binary, "", "load", "-addr", addr, "-codecs", "8=x"); code != 2 {
+			t.Errorf("expected exit code 2 for an invalid codec mix, got %d", code)
+		}
+		if _, code := runCLI(t, binary, "", "load", "-addr", addr, "-records", "300"); code != 2 {
+			t.Errorf("expected exit code 2 for 300 records per frame, got %d", code)
+		}
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		if _, code := runCLI(t, binary, ""); code != 2 {
+			t.Errorf("expected exit code 2 without a command, got %d", code)
+		}
+		if _, code := runCLI(t, binary, "", "decode", "-bogus"); code != 2 {
+			t.Errorf("expected exit code 2 for an unknown flag, got %d", code)
+		}
+		if _, code := runCLI(t, binary, "", "frobnicate"); code != 2 {
+			t.Errorf("expected exit code 2 for an unknown command, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package teltonika_go_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+// echoCodec is a vendor codec whose records carry their raw bytes.
+type echoCodec struct {
+	id byte
+}
+
+func (codec echoCodec) ID() byte {
+	return codec.id
+}
+
+func (codec echoCodec) Decode(data []byte, protocol string) (*decoder_domain.CodecData, error) {
+	raw := append([]byte{}, data...)
+	return &decoder_domain.CodecData{
+		NumberOfRecords: 1,
+		Records:         []decoder_domain.Record{{RawData: &raw}},
+	}, nil
+}
+
+func (codec echoCodec) Encode(codecData *decoder_domain.CodecData) ([]byte, error) {
+	if len(codecData.Records) != 1 || codecData.Records[0].RawData == nil {
+		return nil, fmt.Errorf("expected one raw record")
+	}
+	return *codecData.Records[0].RawData, nil
+}
+
+func TestBuiltinCodecsAreRegistered(t *testing.T) {
+	for _, id := range []byte{0x08, 0x8E, 0x0C, 0x0D, 0x0E, 0x0F, 0x10} {
+		codec, ok := pkg.LookupCodec(id)
+		if !ok {
+			t.Errorf("codec %#x is not registered", id)
+			continue
+		}
+		if codec.ID() != id {
+			t.Errorf("codec %#x reports ID %#x", id, codec.ID())
+		}
+	}
+	if _, ok := pkg.LookupCodec(0x98); ok {
+		t.Errorf("codec 0x98 should not be registered")
+	}
+}
+
+func TestTramDecoderUsesRegisteredCodec(t *testing.T) {
+	unknown, _ := hex.DecodeString("0000000000000004980102030000ABCD")
+	if res := pkg.TramDecoder(unknown); res.Error == nil {
+		t.Fatalf("expected unknown codec error")
+	}
+
+	frame, _ := hex.DecodeString("0000000000000004990102030000ABCD")
+	pkg.RegisterCodec(echoCodec{id: 0x99})
+	res := pkg.TramDecoder(frame)
+	if res.Error != nil {
+		t.Fatalf("TramDecoder failed: %v", res.Error)
+	}
+	raw := res.Response.Result.CodecData.Records[0].RawData
+	if raw == nil || hex.EncodeToString(*raw) != "0102030000abcd" {
+		t.Errorf("unexpected raw data %v", raw)
+	}
+}
+
+func TestRegisterCodecOverridesBuiltin(t *testing.T) {
+	builtin, _ := pkg.LookupCodec(0x08)
+	t.Cleanup(func() { pkg.RegisterCodec(builtin) })
+
+	pkg.RegisterCodec(echoCodec{id: 0x08})
+	frame, _ := hex.DecodeString("000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF")
+	res := pkg.TramDecoder(frame)
+	if res.Error != nil {
+		t.Fatalf("TramDecoder failed: %v", res.Error)
+	}
+	if res.Response.Result.CodecData.Records[0].RawData == nil {
+		t.Errorf("expected the overriding codec to decode the frame")
+	}
+}
@@ -0,0 +1,96 @@
+package teltonika_go_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+func TestDecoderOptionsLimits(t *testing.T) {
+	// Codec 8E record announcing one NX element of 65535 bytes.
+	hostileNX, _ := hex.DecodeString("010000016B412CEE000100000000000000000000000000000000010001000000000000000000010001FFFF")
+
+	tests := []struct {
+		name    string
+		decode  func() error
+		limit   string
+		value   int
+		maximum int
+	}{
+		{
+			name: "records",
+			decode: func() error {
+				frame, _ := hex.DecodeString("000000000000005F10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A00000200005FB3")
+				return pkg.TramDecoder(frame, pkg.DecoderOptions{MaxRecords: 1}).Error
+			},
+			limit: "MaxRecords", value: 2, maximum: 1,
+		},
+		{
+			name: "IOs per record",
+			decode: func() error {
+				frame, _ := hex.DecodeString(serverTestCodec8)
+				return pkg.TramDecoder(frame, pkg.DecoderOptions{MaxIOsPerRecord: 4}).Error
+			},
+			limit: "MaxIOsPerRecord", value: 5, maximum: 4,
+		},
+		{
+			name: "NX value size",
+			decode: func() error {
+				_, err := pkg.DecodeCodec8Ext(hostileNX, "UDP")
+				return err
+			},
+			limit: "MaxNXValueSize", value: 65535, maximum: pkg.DefaultMaxNXValueSize,
+		},
+		{
+			name: "command response size",
+			decode: func() error {
+				frame, _ := hex.DecodeString("000000000000000F0C010500000007676574696E666F0100004312")
+				return pkg.TramDecoder(frame, pkg.DecoderOptions{MaxCommandResponseSize: 4}).Error
+			},
+			limit: "MaxCommandResponseSize", value: 7, maximum: 4,
+		},
+		{
+			name: "frame size",
+			decode: func() error {
+				frame, _ := hex.DecodeString(serverTestCodec8)
+				return pkg.TramDecoder(frame, pkg.DecoderOptions{MaxFrameSize: 16}).Error
+			},
+			limit: "MaxFrameSize", value: 66, maximum: 16,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decode()
+			if !errors.Is(err, pkg.ErrLimitExceeded) {
+				t.Fatalf("expected ErrLimitExceeded, got %v", err)
+			}
+			var limitErr *pkg.LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected *LimitError, got %T", err)
+			}
+			if limitErr.Limit != tt.limit || limitErr.Value != tt.value || limitErr.Max != tt.maximum {
+				t.Errorf("unexpected limit error %+v", limitErr)
+			}
+		})
+	}
+}
+
+func TestDecoderOptionsDefaultsDecode(t *testing.T) {
+	frame, _ := hex.DecodeString(serverTestCodec8)
+	for _, options := range [][]pkg.DecoderOptions{nil, {{}}, {{MaxRecords: 1, MaxIOsPerRecord: 5}}} {
+		if decoded := pkg.TramDecoder(frame, options...); decoded.Error != nil {
+			t.Errorf("TramDecoder with options %v failed: %v", options, decoded.Error)
+		}
+	}
+}
+
+func TestDecoderRejectsInflatedRecordCount(t *testing.T) {
+	// One record of data announcing 255 records.
+	data, _ := hex.DecodeString("FF0000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF")
+	if _, err := pkg.DecodeCodec8(data, "TCP"); err == nil {
+		t.Errorf("expected error for inflated record count")
+	}
+}
@@ -0,0 +1,88 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+func TestLenientDecodeKeepsRecordsBeforeFailure(t *testing.T) {
+	frame, _ := hex.DecodeString(errorsTestCodec16)
+	// The second record starts at byte 56 and is cut inside its GPS element.
+	truncated := frame[:70]
+
+	decoded := pkg.TramDecoder(truncated, pkg.DecoderOptions{Lenient: true})
+	if !errors.Is(decoded.Error, pkg.ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", decoded.Error)
+	}
+	codecData := decoded.Response.Result.CodecData
+	if codecData == nil || codecData.Diagnostics == nil {
+		t.Fatalf("expected partial codec data with diagnostics")
+	}
+	if len(codecData.Records) != 1 || codecData.Records[0].Timestamp == nil {
+		t.Fatalf("expected one decoded record, got %+v", codecData.Records)
+	}
+	diagnostics := codecData.Diagnostics
+	if hex.EncodeToString(diagnostics.Trailing) != hex.EncodeToString(truncated[56:]) {
+		t.Errorf("unexpected trailing bytes %x", diagnostics.Trailing)
+	}
+	document, err := json.Marshal(diagnostics)
+	var restored decoder_domain.Diagnostics
+	if err != nil || json.Unmarshal(document, &restored) != nil || !bytes.Equal(restored.Trailing, diagnostics.Trailing) {
+		t.Errorf("trailing bytes did not survive JSON: %s", document)
+	}
+	if diagnostics.Complete || diagnostics.FailureOffset != 65 || !diagnostics.CRCChecked || diagnostics.CRCMatched {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+}
+
+func TestLenientDecodeReportsCRCMismatch(t *testing.T) {
+	frame, _ := hex.DecodeString(serverTestCodec8Bad)
+	decoded := pkg.TramDecoder(frame, pkg.DecoderOptions{Lenient: true})
+	if !errors.Is(decoded.Error, pkg.ErrCRCMismatch) {
+		t.Fatalf("expected ErrCRCMismatch, got %v", decoded.Error)
+	}
+	codecData := decoded.Response.Result.CodecData
+	if codecData == nil || len(codecData.Records) != 1 {
+		t.Fatalf("expected the decoded record, got %+v", codecData)
+	}
+	diagnostics := codecData.Diagnostics
+	if !diagnostics.Complete || diagnostics.FailureOffset != -1 || !diagnostics.CRCChecked || diagnostics.CRCMatched {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+}
+
+func TestStrictDecodeDiscardsPartialRecords(t *testing.T) {
+	frame, _ := hex.DecodeString(errorsTestCodec16)
+	decoded := pkg.TramDecoder(frame[:70])
+	if decoded.Error == nil || decoded.Response.Result.CodecData != nil {
+		t.Errorf("expected no codec data without Lenient, got %+v", decoded.Response)
+	}
+}
+
+func TestLenientDecodeReportsStructuralMismatches(t *testing.T) {
+	countMismatch, _ := hex.DecodeString(errorsTestCodec8CountMismatch)
+	decoded := pkg.TramDecoder(countMismatch, pkg.DecoderOptions{Lenient: true})
+	if !errors.Is(decoded.Error, pkg.ErrRecordCountMismatch) {
+		t.Fatalf("expected ErrRecordCountMismatch, got %v", decoded.Error)
+	}
+	diagnostics := decoded.Response.Result.CodecData.Diagnostics
+	if !diagnostics.Complete || diagnostics.CountMatched || !diagnostics.LengthMatched || !diagnostics.CRCMatched {
+		t.Errorf("unexpected diagnostics %+v", diagnostics)
+	}
+
+	long, _ := hex.DecodeString(serverTestCodec8 + "00")
+	decoded = pkg.TramDecoder(long, pkg.DecoderOptions{Lenient: true})
+	if !errors.Is(decoded.Error, pkg.ErrLengthMismatch) {
+		t.Fatalf("expected ErrLengthMismatch, got %v", decoded.Error)
+	}
+	codecData := decoded.Response.Result.CodecData
+	if len(codecData.Records) != 1 || codecData.Diagnostics.LengthMatched || !codecData.Diagnostics.CountMatched {
+		t.Errorf("unexpected lenient result %+v", codecData.Diagnostics)
+	}
+}
@@ -242,6 +242,9 @@ func TestEncodeDecodeCodec14RoundTrip(t *testing.T) {
 	if (*decoded.Records[0].CommandResponses)[0].HexMessage != expectedHex {
 		t.Errorf("expected hex %s, got %s", expectedHex, (*decoded.Records[0].CommandResponses)[0].HexMessage)
 	}
+	if imei := (*decoded.Records[0].CommandResponses)[0].IMEI; imei != "0123456789abcdef" {
+		t.Errorf("expected IMEI 0123456789abcdef, got %s", imei)
+	}
 }
 
 func TestEncodeDecodeCodec15RoundTrip(t *testing.T) {
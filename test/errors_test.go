@@ -0,0 +1,102 @@
+package teltonika_go_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+const (
+	errorsTestCodec16             = "000000000000005F10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A00000200005FB3"
+	errorsTestCodec8CountMismatch = "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000020000C68F"
+	errorsTestCodec16UDPLong      = "0047CAFE0101000F33353230393430383532333135393210010000015117E40FE80000000000000000000000000000000000EF05050400010000030000B40000EF01010042111A000001"
+)
+
+func TestTramDecoderErrorTaxonomy(t *testing.T) {
+	codec16, _ := hex.DecodeString(errorsTestCodec16)
+	badCRC, _ := hex.DecodeString(serverTestCodec8Bad)
+	unknown, _ := hex.DecodeString("0000000000000004980102030000ABCD")
+	// Codec 12 frame whose trailing command count says 2 instead of 1.
+	countMismatch, _ := hex.DecodeString("000000000000000F0C010500000007676574696E666F0200004312")
+	// Codec 8 frame whose trailing record count says 2, with a valid CRC.
+	avlCountMismatch, _ := hex.DecodeString(errorsTestCodec8CountMismatch)
+	// Codec 8 frame followed by one byte its data length does not cover.
+	longTCP, _ := hex.DecodeString(serverTestCodec8 + "00")
+	// Codec 16 UDP packet announcing 71 bytes after the length field.
+	longUDP, _ := hex.DecodeString(errorsTestCodec16UDPLong)
+
+	tests := []struct {
+		name    string
+		frame   []byte
+		kind    error
+		codecID byte
+		record  int
+		field   string
+		offset  int
+	}{
+		// The second record is cut inside its GPS element, which starts at
+		// byte 65 of the frame.
+		{name: "truncated", frame: codec16[:70], kind: pkg.ErrTruncated, codecID: 0x10, record: 1, field: "GPS element", offset: 65},
+		{name: "CRC mismatch", frame: badCRC, kind: pkg.ErrCRCMismatch, codecID: 0x08, record: -1, field: "CRC", offset: len(badCRC) - 4},
+		{name: "unknown codec", frame: unknown, kind: pkg.ErrUnknownCodec, codecID: 0x98, record: -1, field: "codec ID", offset: 8},
+		{name: "record count mismatch", frame: countMismatch, kind: pkg.ErrRecordCountMismatch, codecID: 0x0C, record: -1, field: "number of commands 2", offset: 22},
+		{name: "AVL record count mismatch", frame: avlCountMismatch, kind: pkg.ErrRecordCountMismatch, codecID: 0x08, record: -1, field: "number of data 2", offset: len(avlCountMismatch) - 5},
+		{name: "TCP length mismatch", frame: longTCP, kind: pkg.ErrLengthMismatch, record: -1, field: "data length", offset: 4},
+		{name: "UDP length mismatch", frame: longUDP, kind: pkg.ErrLengthMismatch, record: -1, field: "length", offset: 0},
+		{name: "truncated header", frame: []byte{0x00, 0x00, 0x00, 0x00, 0x00}, kind: pkg.ErrTruncated, record: -1, field: "data length", offset: 4},
+	}
+
+	kinds := []error{pkg.ErrTruncated, pkg.ErrCRCMismatch, pkg.ErrUnknownCodec, pkg.ErrRecordCountMismatch, pkg.ErrInvalidHeader, pkg.ErrLengthMismatch}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pkg.TramDecoder(tt.frame).Error
+			for _, kind := range kinds {
+				if errors.Is(err, kind) != (kind == tt.kind) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, kind, !(kind == tt.kind))
+				}
+			}
+			var decodeErr *pkg.DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("expected *DecodeError, got %T: %v", err, err)
+			}
+			if decodeErr.CodecID != tt.codecID || decodeErr.Record != tt.record || decodeErr.Field != tt.field || decodeErr.Offset != tt.offset {
+				t.Errorf("unexpected error location %+v", decodeErr)
+			}
+		})
+	}
+}
+
+func TestDecodeCodecErrorOffsetsCountFromData(t *testing.T) {
+	frame, _ := hex.DecodeString(errorsTestCodec16)
+	_, err := pkg.DecodeCodec16(frame[9:70], "TCP")
+
+	var decodeErr *tools.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected *tools.DecodeError, got %T: %v", err, err)
+	}
+	if decodeErr.Offset != 65-9 || decodeErr.Record != 1 {
+		t.Errorf("unexpected error location %+v", decodeErr)
+	}
+}
+
+func TestErrorsMatchAcrossPackages(t *testing.T) {
+	if _, err := tools.Login([]byte{0x00, 0x00, 0x31}); !errors.Is(err, pkg.ErrInvalidHeader) {
+		t.Errorf("expected pkg.ErrInvalidHeader from tools.Login, got %v", err)
+	}
+	if _, err := tools.DecodeGPSData([]byte{0x00}); !errors.Is(err, pkg.ErrTruncated) {
+		t.Errorf("expected pkg.ErrTruncated from tools.DecodeGPSData, got %v", err)
+	}
+	header, _ := hex.DecodeString("0000000100000036")
+	if _, err := pkg.DecodeHeaderTCP(header); !errors.Is(err, tools.ErrInvalidHeader) {
+		t.Errorf("expected tools.ErrInvalidHeader from pkg.DecodeHeaderTCP, got %v", err)
+	}
+
+	_, err := pkg.DecodeCodec8Ext([]byte{0x01}, "UDP")
+	var decodeErr *pkg.DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.CodecID != 0x8E {
+		t.Errorf("expected *DecodeError for codec 8E, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+const (
+	framerTestLogin   = "000F333536333037303432343431303133"
+	framerTestCodec8  = "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF"
+	framerTestCodec12 = "000000000000000F0C010500000007676574696E666F0100004312"
+)
+
+func TestFramerSplitsStream(t *testing.T) {
+	stream, _ := hex.DecodeString(framerTestLogin + framerTestCodec8 + framerTestCodec12 + framerTestCodec8)
+
+	tests := []struct {
+		name   string
+		reader io.Reader
+	}{
+		{name: "Coalesced frames", reader: bytes.NewReader(stream)},
+		{name: "One byte per read", reader: iotest.OneByteReader(bytes.NewReader(stream))},
+		{name: "Half reads", reader: iotest.HalfReader(bytes.NewReader(stream))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			framer := pkg.NewFramer(tt.reader, 0)
+			login, err := framer.Login()
+			if err != nil {
+				t.Fatalf("Login failed: %v", err)
+			}
+			if hex.EncodeToString(login) != "000f333536333037303432343431303133" {
+				t.Errorf("unexpected login packet %x", login)
+			}
+
+			for _, want := range []string{framerTestCodec8, framerTestCodec12, framerTestCodec8} {
+				frame, err := framer.Next()
+				if err != nil {
+					t.Fatalf("Next failed: %v", err)
+				}
+				if !bytes.EqualFold([]byte(hex.EncodeToString(frame)), []byte(want)) {
+					t.Errorf("expected frame %s, got %x", want, frame)
+				}
+				if res := pkg.TramDecoder(frame); res.Error != nil {
+					t.Errorf("TramDecoder failed: %v", res.Error)
+				}
+			}
+
+			if _, err := framer.Next(); err != io.EOF {
+				t.Errorf("expected io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFramerKeepsLeftoverBytes(t *testing.T) {
+	stream, _ := hex.DecodeString(framerTestCodec12 + "00000000")
+	framer := pkg.NewFramer(bytes.NewReader(stream), 0)
+	if _, err := framer.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if _, err := framer.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if hex.EncodeToString(framer.Buffered()) != "00000000" {
+		t.Errorf("expected leftover bytes 00000000, got %x", framer.Buffered())
+	}
+}
+
+func TestFramerRejectsOversizedFrame(t *testing.T) {
+	stream, _ := hex.DecodeString("00000000FFFFFFF0")
+	framer := pkg.NewFramer(bytes.NewReader(stream), 1024)
+	if _, err := framer.Next(); !errors.Is(err, pkg.ErrFrameTooLarge) {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestFramerRejectsInvalidPreamble(t *testing.T) {
+	stream, _ := hex.DecodeString("0000000100000010")
+	framer := pkg.NewFramer(bytes.NewReader(stream), 0)
+	if _, err := framer.Next(); err == nil {
+		t.Errorf("expected error for invalid preamble")
+	}
+}
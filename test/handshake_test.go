@@ -0,0 +1,106 @@
+package teltonika_go_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+func TestEncodeLoginRoundTrip(t *testing.T) {
+	packet, err := tools.EncodeLogin("356307042441013")
+	if err != nil {
+		t.Fatalf("EncodeLogin failed: %v", err)
+	}
+	if got := hex.EncodeToString(packet); got != "000f333536333037303432343431303133" {
+		t.Errorf("unexpected login packet %s", got)
+	}
+
+	login, err := tools.Login(packet)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if *login.IMEI != "356307042441013" || *login.Length != 15 {
+		t.Errorf("expected IMEI 356307042441013 with length 15, got %s with length %d", *login.IMEI, *login.Length)
+	}
+
+	decoded := pkg.LoginDecoder(packet)
+	if decoded.Error != nil || *decoded.Response.Result.IMEI != "356307042441013" {
+		t.Errorf("LoginDecoder failed on encoded packet: %v", decoded.Error)
+	}
+
+	if _, err := tools.EncodeLogin(""); err == nil {
+		t.Errorf("expected error for empty IMEI")
+	}
+}
+
+func TestLoginResponseRoundTrip(t *testing.T) {
+	for _, accepted := range []bool{true, false} {
+		data := tools.EncodeLoginResponse(accepted)
+		got, err := tools.DecodeLoginResponse(data)
+		if err != nil {
+			t.Fatalf("DecodeLoginResponse failed: %v", err)
+		}
+		if got != accepted {
+			t.Errorf("expected %v, got %v", accepted, got)
+		}
+	}
+
+	if _, err := tools.DecodeLoginResponse([]byte{0x02}); err == nil {
+		t.Errorf("expected error for invalid login response")
+	}
+}
+
+func TestTCPAckRoundTrip(t *testing.T) {
+	data, err := tools.EncodeTCPAck(4)
+	if err != nil {
+		t.Fatalf("EncodeTCPAck failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "00000004" {
+		t.Errorf("expected 00000004, got %s", got)
+	}
+	accepted, err := tools.DecodeTCPAck(data)
+	if err != nil || accepted != 4 {
+		t.Errorf("expected 4 accepted records, got %d (%v)", accepted, err)
+	}
+
+	if _, err := tools.EncodeTCPAck(-1); err == nil {
+		t.Errorf("expected error for negative count")
+	}
+	if _, err := tools.DecodeTCPAck([]byte{0x00, 0x01}); err == nil {
+		t.Errorf("expected error for short ACK")
+	}
+}
+
+func TestUDPAckRoundTrip(t *testing.T) {
+	// Acknowledgement of the "Valid Codec 08 UDP" packet.
+	datagram, _ := hex.DecodeString("003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001")
+	header, err := pkg.DecodeHeaderUDP(datagram)
+	if err != nil {
+		t.Fatalf("DecodeHeaderUDP failed: %v", err)
+	}
+
+	data, err := tools.EncodeUDPAck(header.PacketID, header.AVLPacketID, 1)
+	if err != nil {
+		t.Fatalf("EncodeUDPAck failed: %v", err)
+	}
+	if got := hex.EncodeToString(data); got != "0005cafe010501" {
+		t.Errorf("expected 0005cafe010501, got %s", got)
+	}
+
+	ack, err := tools.DecodeUDPAck(data)
+	if err != nil {
+		t.Fatalf("DecodeUDPAck failed: %v", err)
+	}
+	if ack.PacketID != header.PacketID || ack.AVLPacketID != header.AVLPacketID || ack.PacketType != 1 || ack.Accepted != 1 {
+		t.Errorf("unexpected UDP ACK %+v", ack)
+	}
+
+	if _, err := tools.EncodeUDPAck(0x10000, 0, 0); err == nil {
+		t.Errorf("expected error for packet ID out of range")
+	}
+	if _, err := tools.DecodeUDPAck([]byte{0x00, 0x04, 0xCA, 0xFE, 0x01, 0x05, 0x01}); err == nil {
+		t.Errorf("expected error for invalid length field")
+	}
+}
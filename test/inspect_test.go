@@ -0,0 +1,451 @@
+package teltonika_go_test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	inspect_domain "github.com/danieljvsa/teltonika-go/internal/inspect"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+const inspectTestFrame = "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF"
+
+// findField returns the first field named name below root.
+func findField(root *inspect_domain.Field, name string) *inspect_domain.Field {
+	var found *inspect_domain.Field
+	root.Walk(func(field *inspect_domain.Field, depth int) {
+		if found == nil && field.Name == name {
+			found = field
+		}
+	})
+	return found
+}
+
+func inspectHex(t *testing.T, input string) (*inspect_domain.Field, error) {
+	t.Helper()
+	frame, err := hex.DecodeString(input)
+	if err != nil {
+		t.Fatalf("invalid test input: %v", err)
+	}
+	return pkg.Inspect(frame)
+}
+
+func TestInspectTCPFrame(t *testing.T) {
+	root, err := inspectHex(t, inspectTestFrame)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if root.Name != "TCP frame" || root.Length != 66 {
+		t.Fatalf("unexpected root %s of %d bytes", root.Name, root.Length)
+	}
+
+	tests := []struct {
+		name   string
+		offset int
+		length int
+		raw    string
+		value  string
+	}{
+		{name: "Data length", offset: 4, length: 4, raw: "00000036", value: "54"},
+		{name: "Codec ID", offset: 8, length: 1, raw: "08", value: "0x08 (Codec 8)"},
+		{name: "Record 1", offset: 10, length: 51, value: "2019-06-10T10:04:46Z"},
+		{name: "Priority", offset: 18, length: 1, raw: "01", value: "1 (High)"},
+		{name: "GPS element", offset: 19, length: 15, value: "0, 0 (no fix)"},
+		{name: "Event IO ID", offset: 34, length: 1, raw: "01", value: "1"},
+		{name: "IO 66", offset: 42, length: 3, raw: "425e0f", value: "24079"},
+		{name: "Number of data 2", offset: 61, length: 1, raw: "01", value: "1"},
+		{name: "CRC", offset: 62, length: 4, raw: "0000c7cf", value: "0xC7CF (valid)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := findField(root, tt.name)
+			if field == nil {
+				t.Fatalf("field not found")
+			}
+			if field.Offset != tt.offset || field.Length != tt.length || len(field.Raw) != tt.length {
+				t.Errorf("expected %d bytes at %d, got %d (%d raw) at %d", tt.length, tt.offset, field.Length, len(field.Raw), field.Offset)
+			}
+			if tt.raw != "" && hex.EncodeToString(field.Raw) != tt.raw {
+				t.Errorf("expected raw %s, got %x", tt.raw, field.Raw)
+			}
+			if field.Value != tt.value {
+				t.Errorf("expected value %q, got %q", tt.value, field.Value)
+			}
+		})
+	}
+
+	io := findField(root, "IO 66").IO
+	if io == nil || io.IO != 66 || io.Value != "5e0f" {
+		t.Errorf("unexpected IO element %+v", io)
+	}
+}
+
+func TestInspectOtherFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		root  string
+		field string
+		value string
+	}{
+		{
+			name:  "Login packet",
+			input: "000F333536333037303432343431303133",
+			root:  "Login packet",
+			field: "IMEI",
+			value: "356307042441013",
+		},
+		{
+			name:  "Codec 16 UDP",
+			input: "0048CAFE0101000F33353230393430383532333135393210010000015117E40FE80000000000000000000000000000000000EF05050400010000030000B40000EF01010042111A000001",
+			root:  "UDP frame",
+			field: "Generation type",
+			value: "5 (On Change)",
+		},
+		{
+			name:  "Codec 13 TCP",
+			input: "00000000000000170D01060000000F0000016C0A81C320676574696E666F0100005B66",
+			root:  "TCP frame",
+			field: "Response",
+			value: `"getinfo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := inspectHex(t, tt.input)
+			if err != nil {
+				t.Fatalf("Inspect failed: %v", err)
+			}
+			if root.Name != tt.root {
+				t.Errorf("expected %s, got %s", tt.root, root.Name)
+			}
+			field := findField(root, tt.field)
+			if field == nil || field.Value != tt.value {
+				t.Errorf("expected %s %q, got %+v", tt.field, tt.value, field)
+			}
+			last := root.Children[len(root.Children)-1]
+			if last.Offset+last.Length != root.Length {
+				t.Errorf("fields end at %d, frame has %d bytes", last.Offset+last.Length, root.Length)
+			}
+		})
+	}
+}
+
+func TestInspectReportsProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		err    error
+		field  string
+		offset int
+	}{
+		{
+			name:   "CRC mismatch",
+			input:  inspectTestFrame[:len(inspectTestFrame)-4] + "0000",
+			err:    pkg.ErrCRCMismatch,
+			field:  "CRC",
+			offset: 62,
+		},
+		{
+			name:   "Count mismatch",
+			input:  errorsTestCodec8CountMismatch,
+			err:    pkg.ErrRecordCountMismatch,
+			field:  "Number of data 2",
+			offset: 61,
+		},
+		{
+			name:   "Unknown codec",
+			input:  "000000000000000F42010500000007676574696E666F0100004312",
+			err:    pkg.ErrUnknownCodec,
+			field:  "Codec ID",
+			offset: 8,
+		},
+		{
+			name:   "Truncated record",
+			input:  "000000000000001C08010000016B40D8EA3001000000000000000000000000000000010502150301",
+			err:    pkg.ErrTruncated,
+			field:  "Unparsed",
+			offset: 36,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := inspectHex(t, tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			var decodeErr *pkg.DecodeError
+			if !errors.As(err, &decodeErr) || decodeErr.Offset != tt.offset {
+				t.Errorf("expected a DecodeError at offset %d, got %v", tt.offset, err)
+			}
+			field := findField(root, tt.field)
+			if field == nil || field.Offset != tt.offset || !errors.Is(field.Error, tt.err) {
+				t.Errorf("expected %s at %d to carry the error, got %+v", tt.field, tt.offset, field)
+			}
+		})
+	}
+}
+
+func TestInspectJSON(t *testing.T) {
+	root, err := inspectHex(t, inspectTestFrame)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	document, err := json.Marshal(root)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{`"name":"CRC","offset":62,"length":4,"raw":"0000c7cf"`, `"io":{"id":66,"value":"5e0f"}`} {
+		if !strings.Contains(string(document), want) {
+			t.Errorf("expected %s in %s", want, document)
+		}
+	}
+}
+
+// inspectAgreementFrames are valid frames of every codec Inspect
+// dissects, taken from the other tests of the package.
+var inspectAgreementFrames = []string{
+	serverTestCodec8,
+	errorsTestCodec16,
+	framerTestCodec12,
+	"000000000000004A8E010000016B412CEE000100000000000000000000000000000000010005000100010100010011001D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A00000100002994",
+	"00000000000000170D01060000000F0000016C0A81C320676574696E666F0100005B66",
+	"00000000000000AB0E0106000000A303520930814522515665723A30332E31382E31345F3034204750533A41584E5F352E31305F333333332048773A464D42313230204D6F643A313520494D45493A33353230393330383134353232353120496E69743A323031382D31312D323220373A313320557074696D653A3137323334204D41433A363042444430303136323631205350433A312830292041584C3A30204F42443A3020424C3A312E362042543A340100007AAE",
+	"000000000000001b0f010b00000013654b65a4012345678912345648656c6c6f210a01000093d6",
+	"003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001",
+	"0048CAFE0101000F33353230393430383532333135393210010000015117E40FE80000000000000000000000000000000000EF05050400010000030000B40000EF01010042111A000001",
+	"005FCAFE0107000F3335323039333038363430333635358E010000016B4F831C680100000000000000000000000000000000010005000100010100010011009D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A000001",
+}
+
+// encodedAgreementFrames builds AVL frames whose values exercise the edges
+// of the layout: the highest altitude, a speed above 255 km/h,
+// negative coordinates, 8-byte and variable-length IO elements and a
+// generation type.
+func encodedAgreementFrames(t *testing.T) [][]byte {
+	t.Helper()
+	timestamp := time.Date(2024, 5, 1, 12, 30, 15, 250e6, time.UTC)
+	priority, eventIO := int64(2), int64(239)
+	gps := tool_domain.GPSData{Latitude: -33.8688197, Longitude: -70.6692655, Altitude: 32767, Angle: 359, Satelites: 12, Speed: 300}
+	generation := decoder_domain.GenerationOnChange
+	ios := []io_domain.IOData{
+		{IO: 239, Value: "01"},
+		{IO: 66, Value: "3071"},
+		{IO: 72, Value: "ffffff97"},
+		{IO: 16, Value: "0102030405060708"},
+	}
+	withNX := append(append([]io_domain.IOData{}, ios...), io_domain.IOData{IO: 385, Value: "0a0b0c0d0e0f101112"})
+
+	var frames [][]byte
+	for _, codec := range []struct {
+		id  byte
+		ios []io_domain.IOData
+	}{{0x08, ios}, {0x8E, withNX}, {0x10, ios}} {
+		record := decoder_domain.Record{Timestamp: &timestamp, Priority: &priority, GPSData: &gps, EventIO: &eventIO, IOs: &codec.ios}
+		if codec.id == 0x10 {
+			record.GenerationType = &generation
+		}
+		frame, err := pkg.TramEncoder(&decoder_domain.CodecHeaderResponse{CodecData: &decoder_domain.CodecData{
+			CodecID:         codec.id,
+			NumberOfRecords: 2,
+			Records:         []decoder_domain.Record{record, record},
+		}})
+		if err != nil {
+			t.Fatalf("encoding codec 0x%02X failed: %v", codec.id, err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// TestInspectAgreesWithDecoder keeps the layout Inspect walks in line with
+// the decoders: every value Inspect shows for a valid frame must be the
+// one TramDecoder decodes.
+func TestInspectAgreesWithDecoder(t *testing.T) {
+	frames := encodedAgreementFrames(t)
+	for _, input := range inspectAgreementFrames {
+		frame, _ := hex.DecodeString(input)
+		frames = append(frames, frame)
+	}
+	for _, frame := range frames {
+		t.Run(hex.EncodeToString(frame[:min(len(frame), 24)]), func(t *testing.T) {
+			decoded := pkg.TramDecoder(frame)
+			if decoded.Error != nil {
+				t.Fatalf("TramDecoder failed: %v", decoded.Error)
+			}
+			root, err := pkg.Inspect(frame)
+			if err != nil {
+				t.Fatalf("Inspect failed: %v", err)
+			}
+			codecData := decoded.Response.Result.CodecData
+			if data := findField(root, "AVL data"); data != nil {
+				checkInspectedRecords(t, data, codecData)
+			} else if data := findField(root, "Command data"); data != nil {
+				checkInspectedCommands(t, data, codecData)
+			} else {
+				t.Fatalf("no codec data in the field tree")
+			}
+		})
+	}
+}
+
+// TestInspectAgreesWithDecoderOnCommandType checks that Inspect rejects
+// the command types TramDecoder rejects, with the same error at the same
+// offset, and keeps the types of Codec 15.
+func TestInspectAgreesWithDecoderOnCommandType(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		commandType byte
+	}{
+		{name: "Codec 12 type 7", input: framerTestCodec12, commandType: 7},
+		{name: "Codec 13 command", input: inspectAgreementFrames[4], commandType: 5},
+		{name: "Codec 14 type 0", input: inspectAgreementFrames[5], commandType: 0},
+		{name: "Codec 15 type 7", input: inspectAgreementFrames[6], commandType: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, _ := hex.DecodeString(tt.input)
+			frame[10] = tt.commandType
+			binary.BigEndian.PutUint32(frame[len(frame)-4:], uint32(tools.Crc16IBM(frame[8:len(frame)-4])))
+
+			decoded := pkg.TramDecoder(frame)
+			root, err := pkg.Inspect(frame)
+			if decoded.Error == nil {
+				if err != nil {
+					t.Errorf("TramDecoder accepted the frame, Inspect failed: %v", err)
+				}
+				return
+			}
+			var decodeErr, inspectErr *pkg.DecodeError
+			if !errors.As(decoded.Error, &decodeErr) || !errors.As(err, &inspectErr) || inspectErr.Error() != decodeErr.Error() {
+				t.Fatalf("TramDecoder failed with %v, Inspect with %v", decoded.Error, err)
+			}
+			field := findField(root, "Command type")
+			if field == nil || field.Offset != decodeErr.Offset || field.Error == nil || field.Error.Error() != decodeErr.Error() {
+				t.Errorf("expected the command type at %d to carry %v, got %+v", decodeErr.Offset, decodeErr, field)
+			}
+		})
+	}
+}
+
+// childValue returns the value of the child of parent named name, and
+// fails the test if there is none.
+func childValue(t *testing.T, parent *inspect_domain.Field, name string) string {
+	t.Helper()
+	for _, child := range parent.Children {
+		if child.Name == name {
+			return child.Value
+		}
+	}
+	t.Fatalf("%s has no field %s", parent.Name, name)
+	return ""
+}
+
+// number returns the leading integer of an Inspect value such as
+// "1 (High)".
+func number(value string) string {
+	return strings.Fields(value)[0]
+}
+
+func checkInspectedRecords(t *testing.T, data *inspect_domain.Field, codecData *decoder_domain.CodecData) {
+	t.Helper()
+	if got := childValue(t, data, "Number of data 1"); got != strconv.FormatInt(codecData.NumberOfRecords, 10) {
+		t.Errorf("number of data %s, decoded %d", got, codecData.NumberOfRecords)
+	}
+	for i, record := range codecData.Records {
+		fields := findField(data, fmt.Sprintf("Record %d", i+1))
+		if fields == nil {
+			t.Fatalf("record %d not found", i+1)
+		}
+		gps := findField(fields, "GPS element")
+		want := map[string]string{
+			"Timestamp":   record.Timestamp.Format(time.RFC3339Nano),
+			"Priority":    strconv.FormatInt(*record.Priority, 10),
+			"Event IO ID": strconv.FormatInt(*record.EventIO, 10),
+		}
+		got := map[string]string{
+			"Timestamp":   childValue(t, fields, "Timestamp"),
+			"Priority":    number(childValue(t, fields, "Priority")),
+			"Event IO ID": childValue(t, fields, "Event IO ID"),
+		}
+		if record.GenerationType != nil {
+			want["Generation type"] = strconv.Itoa(int(*record.GenerationType))
+			got["Generation type"] = number(childValue(t, fields, "Generation type"))
+		}
+		for name, value := range map[string]string{
+			"Longitude":  strconv.FormatFloat(record.GPSData.Longitude, 'f', -1, 64),
+			"Latitude":   strconv.FormatFloat(record.GPSData.Latitude, 'f', -1, 64),
+			"Altitude":   strconv.FormatInt(record.GPSData.Altitude, 10),
+			"Angle":      strconv.FormatInt(record.GPSData.Angle, 10),
+			"Satellites": strconv.FormatInt(record.GPSData.Satelites, 10),
+			"Speed":      strconv.FormatInt(record.GPSData.Speed, 10),
+		} {
+			want[name] = value
+			got[name] = strings.TrimSuffix(number(childValue(t, gps, name)), "°")
+		}
+		for name, value := range want {
+			if got[name] != value {
+				t.Errorf("record %d: %s %q, decoded %q", i+1, name, got[name], value)
+			}
+		}
+
+		var inspected []io_domain.IOData
+		fields.Walk(func(field *inspect_domain.Field, depth int) {
+			if field.IO != nil {
+				inspected = append(inspected, *field.IO)
+			}
+		})
+		if !reflect.DeepEqual(inspected, *record.IOs) {
+			t.Errorf("record %d: IO elements %v, decoded %v", i+1, inspected, *record.IOs)
+		}
+	}
+}
+
+func checkInspectedCommands(t *testing.T, data *inspect_domain.Field, codecData *decoder_domain.CodecData) {
+	t.Helper()
+	responses := *codecData.Records[0].CommandResponses
+	for i, response := range responses {
+		var command *inspect_domain.Field
+		for _, name := range []string{"Command", "Response"} {
+			if command == nil {
+				command = findField(data, fmt.Sprintf("%s %d", name, i+1))
+			}
+		}
+		if command == nil {
+			t.Fatalf("command %d not found", i+1)
+		}
+		message := command.Children[len(command.Children)-1].Raw
+		if codecData.CodecID == 0x0E {
+			// The HexMessage of Codec 14 keeps the IMEI before the text.
+			message = command.Raw[4:]
+		}
+		if !strings.EqualFold(hex.EncodeToString(message), response.HexMessage) {
+			t.Errorf("command %d: text %x, decoded %s", i+1, message, response.HexMessage)
+		}
+		if response.IMEI != "" {
+			if got := childValue(t, command, "IMEI"); got != response.IMEI {
+				t.Errorf("command %d: IMEI %s, decoded %s", i+1, got, response.IMEI)
+			}
+		}
+		if response.Timestamp != nil {
+			got, err := time.Parse(time.RFC3339Nano, childValue(t, command, "Timestamp"))
+			if err != nil || !got.Equal(*response.Timestamp) {
+				t.Errorf("command %d: timestamp %v, decoded %v", i+1, got, response.Timestamp)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	"github.com/danieljvsa/teltonika-go/iodict"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+func TestIODataAccessors(t *testing.T) {
+	tests := []struct {
+		io       io_domain.IOData
+		unsigned uint64
+		signed   int64
+		boolean  bool
+	}{
+		{io: io_domain.IOData{IO: 1, Value: "ff"}, unsigned: 255, signed: -1, boolean: true},
+		{io: io_domain.IOData{IO: 2, Value: "00ff"}, unsigned: 255, signed: 255, boolean: true},
+		{io: io_domain.IOData{IO: 3, Value: "FFFFFF9C"}, unsigned: 0xFFFFFF9C, signed: -100, boolean: true},
+		{io: io_domain.IOData{IO: 4, Value: "0000000000000000"}},
+	}
+	for _, tt := range tests {
+		if got := tt.io.Uint64(); got != tt.unsigned {
+			t.Errorf("%+v.Uint64() = %d, want %d", tt.io, got, tt.unsigned)
+		}
+		if got := tt.io.Int64(); got != tt.signed {
+			t.Errorf("%+v.Int64() = %d, want %d", tt.io, got, tt.signed)
+		}
+		if got := tt.io.Bool(); got != tt.boolean {
+			t.Errorf("%+v.Bool() = %v, want %v", tt.io, got, tt.boolean)
+		}
+	}
+
+	temperature, _ := iodict.Builtin().Lookup(72)
+	if got := tests[2].io.Float(temperature); got != -10 {
+		t.Errorf("Float(Dallas Temperature 1) = %v, want -10", got)
+	}
+	if got := tests[1].io.Float(nil); got != 255 {
+		t.Errorf("Float(nil) = %v, want 255", got)
+	}
+	if got := (io_domain.IOData{Value: "zz"}).Bytes(); got != nil {
+		t.Errorf("expected nil bytes for invalid hex, got %x", got)
+	}
+	if invalid := (io_domain.IOData{Value: "0g"}); invalid.Uint64() != 0 || invalid.Int64() != 0 || invalid.Bool() {
+		t.Errorf("expected zero accessors for invalid hex")
+	}
+	if long := (io_domain.IOData{Value: "010000000000000000"}); long.Uint64() != 0 || !long.Bool() {
+		t.Errorf("expected the last 8 bytes and a true Bool for a 9-byte value")
+	}
+}
+
+func TestDecodedIODataBytes(t *testing.T) {
+	data := []byte{0x02, 0x01, 0x02, 0x4A, 0x01, 0x42, 0x30, 0x71, 0x00, 0x00}
+	decoded, err := pkg.DecodeIos8(data, 0)
+	if err != nil {
+		t.Fatalf("DecodeIos8 failed: %v", err)
+	}
+	if len(decoded.IOs) != 2 || !bytes.Equal(decoded.IOs[0].Bytes(), []byte{0x4A}) || decoded.IOs[1].Uint64() != 12401 {
+		t.Errorf("unexpected decoded IOs %+v", decoded.IOs)
+	}
+}
+
+func TestIODataBuilders(t *testing.T) {
+	voltage, _ := iodict.Builtin().Lookup(66)
+	temperature, _ := iodict.Builtin().Lookup(72)
+
+	ignition := tools.NewIOBool(239, true)
+	external, err := tools.NewIOFloat(66, 2, 12.401, voltage)
+	if err != nil {
+		t.Fatalf("NewIOFloat failed: %v", err)
+	}
+	dallas, err := tools.NewIOFloat(72, 4, -10.5, temperature)
+	if err != nil {
+		t.Fatalf("NewIOFloat failed: %v", err)
+	}
+	odometer, err := tools.NewIOUint(16, 4, 123456)
+	if err != nil {
+		t.Fatalf("NewIOUint failed: %v", err)
+	}
+	if external.Value != "3071" || dallas.Value != "ffffff97" || ignition.Value != "01" {
+		t.Errorf("unexpected built values %+v %+v %+v", external, dallas, ignition)
+	}
+
+	encoded, count, err := tools.EncodeIOData8([]io_domain.IOData{ignition, external, dallas, odometer})
+	if err != nil || count != 4 {
+		t.Fatalf("EncodeIOData8 failed: %v", err)
+	}
+	decoded, err := pkg.DecodeIos8(append(encoded, 0x00, 0x00, 0x00), 0)
+	if err != nil {
+		t.Fatalf("DecodeIos8 failed: %v", err)
+	}
+	values := map[int64]float64{}
+	for _, io := range decoded.IOs {
+		entry, _ := iodict.Builtin().Lookup(io.IO)
+		values[io.IO] = io.Float(entry)
+	}
+	if values[239] != 1 || values[66] != 12.401 || values[72] != -10.5 || values[16] != 123456 {
+		t.Errorf("unexpected round trip values %v", values)
+	}
+
+	if _, err := tools.NewIOUint(1, 1, 256); err == nil {
+		t.Errorf("expected error for a value wider than its size")
+	}
+	if _, err := tools.NewIOInt(1, 1, -129); err == nil {
+		t.Errorf("expected error for a value wider than its size")
+	}
+	if _, err := tools.NewIOFloat(66, 2, -1, voltage); err == nil {
+		t.Errorf("expected error for a negative unsigned value")
+	}
+	if _, err := tools.NewIOUint(1, 3, 1); err == nil {
+		t.Errorf("expected error for an invalid size")
+	}
+}
+
+func TestEditedIODataIsEncoded(t *testing.T) {
+	frame, _ := hex.DecodeString(serverTestCodec8)
+	decoded := pkg.TramDecoder(frame)
+	if decoded.Error != nil {
+		t.Fatalf("decode failed: %v", decoded.Error)
+	}
+	ios := *decoded.Response.Result.CodecData.Records[0].IOs
+	edited := -1
+	for i, io := range ios {
+		if len(io.Bytes()) == 2 {
+			edited = i
+			break
+		}
+	}
+	if edited < 0 {
+		t.Fatalf("expected a two-byte IO element in %+v", ios)
+	}
+	ios[edited].Value = "1234"
+
+	encoded, err := pkg.TramEncoder(&decoded.Response.Result)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	again := pkg.TramDecoder(encoded)
+	if again.Error != nil {
+		t.Fatalf("decode of the edited frame failed: %v", again.Error)
+	}
+	if io := (*again.Response.Result.CodecData.Records[0].IOs)[edited]; io.Uint64() != 0x1234 {
+		t.Errorf("edited value was not encoded: %+v", io)
+	}
+}
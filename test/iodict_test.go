@@ -0,0 +1,163 @@
+package teltonika_go_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	"github.com/danieljvsa/teltonika-go/iodict"
+)
+
+func TestIODictResolve(t *testing.T) {
+	dictionary := iodict.Builtin()
+	tests := []struct {
+		io     io_domain.IOData
+		number float64
+		label  string
+		text   string
+	}{
+		{io: io_domain.IOData{IO: 66, Value: "3071"}, number: 12.401, text: "External Voltage: 12.401 V"},
+		{io: io_domain.IOData{IO: 239, Value: "01"}, number: 1, label: "On", text: "Ignition: On"},
+		{io: io_domain.IOData{IO: 72, Value: "FFFFFF9C"}, number: -10, text: "Dallas Temperature 1: -10 °C"},
+		{io: io_domain.IOData{IO: 17, Value: "FC18"}, number: -1000, text: "Axis X: -1000 mG"},
+		{io: io_domain.IOData{IO: 16, Value: "0001E240"}, number: 123456, text: "Total Odometer: 123456 m"},
+	}
+	for _, tt := range tests {
+		value, err := dictionary.Resolve(tt.io)
+		if err != nil {
+			t.Fatalf("Resolve(%+v) failed: %v", tt.io, err)
+		}
+		if value.Number != tt.number || value.Label != tt.label || value.String() != tt.text {
+			t.Errorf("Resolve(%+v) = %v (%v, %q)", tt.io, value, value.Number, value.Label)
+		}
+	}
+}
+
+func TestIODictErrors(t *testing.T) {
+	dictionary := iodict.Builtin()
+	if _, err := dictionary.Resolve(io_domain.IOData{IO: 9999, Value: "00"}); !errors.Is(err, iodict.ErrUnknownID) {
+		t.Errorf("expected ErrUnknownID, got %v", err)
+	}
+	if _, err := dictionary.Resolve(io_domain.IOData{IO: 66, Value: "01"}); !errors.Is(err, iodict.ErrValueSize) {
+		t.Errorf("expected ErrValueSize, got %v", err)
+	}
+}
+
+func TestIODictRegisterIsPerDictionary(t *testing.T) {
+	custom := iodict.Builtin()
+	custom.Register(iodict.Entry{ID: 66, Name: "Supply", Size: 2, Multiplier: 0.01, Unit: "V"})
+
+	value, err := custom.Resolve(io_domain.IOData{IO: 66, Value: "04D2"})
+	if err != nil || value.String() != "Supply: 12.34 V" {
+		t.Errorf("unexpected custom value %v, %v", value, err)
+	}
+	if entry, _ := iodict.Builtin().Lookup(66); entry.Name != "External Voltage" {
+		t.Errorf("Register changed the built-in table: %+v", entry)
+	}
+}
+
+func TestIODictZeroValue(t *testing.T) {
+	var dictionary iodict.Dictionary
+	if _, ok := dictionary.Lookup(66); ok || len(dictionary.Entries()) != 0 {
+		t.Errorf("expected an empty dictionary")
+	}
+	dictionary.Register(iodict.Entry{ID: 66, Name: "Supply", Size: 2, Multiplier: 0.001, Unit: "V"})
+	if value, err := dictionary.Resolve(io_domain.IOData{IO: 66, Value: "3071"}); err != nil || value.String() != "Supply: 12.401 V" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+}
+
+func TestIODictProfilesZeroValue(t *testing.T) {
+	var profiles iodict.Profiles
+	fallback := profiles.ForIMEI("356307042441013")
+	if fallback == nil || len(fallback.Entries()) != 0 || profiles.ForModel("FMB640") != fallback {
+		t.Fatalf("expected one empty fallback dictionary, got %v", fallback)
+	}
+	profiles.SetModel("FMB640", iodict.New(iodict.Entry{ID: 66, Name: "CAN Supply", Size: 2}))
+	profiles.SetIMEI("356307042441014", iodict.New(iodict.Entry{ID: 66, Name: "Bench Supply", Size: 2}))
+	profiles.AssignModel("356307042441013", "fmb640")
+	for imei, name := range map[string]string{"356307042441013": "CAN Supply", "356307042441014": "Bench Supply"} {
+		if entry, _ := profiles.ForIMEI(imei).Lookup(66); entry.Name != name {
+			t.Errorf("ForIMEI(%s) resolved IO 66 as %q, want %q", imei, entry.Name, name)
+		}
+	}
+}
+
+func TestIODictLoadJSON(t *testing.T) {
+	dictionary := iodict.New()
+	table := `{"entries": [{"id": 10800, "name": "Fuel Level", "size": 2, "multiplier": 0.1, "unit": "l"},
+		{"id": 10801, "name": "Door", "size": 1, "values": {"0": "Closed", "1": "Open"}}]}`
+	if err := dictionary.LoadJSON(strings.NewReader(table)); err != nil {
+		t.Fatalf("LoadJSON failed: %v", err)
+	}
+	value, err := dictionary.Resolve(io_domain.IOData{IO: 10800, Value: "01F4"})
+	if err != nil || value.String() != "Fuel Level: 50 l" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	value, err = dictionary.Resolve(io_domain.IOData{IO: 10801, Value: "01"})
+	if err != nil || value.Label != "Open" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	if err := dictionary.LoadJSON(strings.NewReader(`{"entries": 1}`)); !errors.Is(err, iodict.ErrInvalidTable) {
+		t.Errorf("expected ErrInvalidTable, got %v", err)
+	}
+}
+
+func TestIODictLoadTeltonikaCSV(t *testing.T) {
+	table := "Property ID in AVL packet,Property Name,Bytes,Type,Value range Min,Value range Max,Multiplier,Units,Description,HW Support,Parameter Group\n" +
+		"72,Dallas Temperature 1,4,Signed,-550,1150,0.1,°C,Degrees ( °C ),FMB920,Permanent I/O elements\n" +
+		"\"200\",Sleep Mode,1,Unsigned,0,4,-,-,\"0 - No Sleep\n1 - GPS Sleep\n2 - Deep Sleep\",FMB920,Permanent I/O elements\n" +
+		"385,Beacon,Variable,HEX,-,-,-,-,List of Beacons,FMB920,Permanent I/O elements\n"
+	dictionary := iodict.New()
+	if err := dictionary.LoadCSV(strings.NewReader(table)); err != nil {
+		t.Fatalf("LoadCSV failed: %v", err)
+	}
+
+	value, err := dictionary.Resolve(io_domain.IOData{IO: 72, Value: "000000FA"})
+	if err != nil || value.String() != "Dallas Temperature 1: 25 °C" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	value, err = dictionary.Resolve(io_domain.IOData{IO: 200, Value: "02"})
+	if err != nil || value.Label != "Deep Sleep" {
+		t.Errorf("unexpected value %v, %v", value, err)
+	}
+	if entry, ok := dictionary.Lookup(385); !ok || entry.Size != 0 || entry.Values != nil {
+		t.Errorf("unexpected variable-length entry %+v", entry)
+	}
+	if err := iodict.New().LoadCSV(strings.NewReader("Name,Unit\nSpeed,km/h\n")); !errors.Is(err, iodict.ErrInvalidTable) {
+		t.Errorf("expected ErrInvalidTable without an ID column, got %v", err)
+	}
+}
+
+func TestIODictProfiles(t *testing.T) {
+	dir := t.TempDir()
+	fmb640 := "ID,Name,Size,Multiplier,Unit\n66,CAN Supply,2,0.01,V\n"
+	if err := os.WriteFile(filepath.Join(dir, "FMB640.csv"), []byte(fmb640), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profiles := iodict.NewProfiles(iodict.Builtin())
+	if err := profiles.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	custom := iodict.New(iodict.Entry{ID: 66, Name: "Bench Supply", Size: 2})
+	profiles.AssignModel("356307042441013", "fmb640")
+	profiles.SetIMEI("356307042441014", custom)
+
+	names := map[string]string{
+		"356307042441013": "CAN Supply",
+		"356307042441014": "Bench Supply",
+		"356307042441015": "External Voltage",
+	}
+	for imei, name := range names {
+		if entry, _ := profiles.ForIMEI(imei).Lookup(66); entry.Name != name {
+			t.Errorf("ForIMEI(%s) resolved IO 66 as %q, want %q", imei, entry.Name, name)
+		}
+	}
+	// The profile keeps the fallback entries it does not override.
+	if _, ok := profiles.ForModel("FMB640").Lookup(239); !ok {
+		t.Errorf("expected the FMB640 profile to include the built-in entries")
+	}
+}
@@ -0,0 +1,272 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	journal "github.com/danieljvsa/teltonika-go/journal"
+	server "github.com/danieljvsa/teltonika-go/server"
+	simulator "github.com/danieljvsa/teltonika-go/simulator"
+)
+
+const journalTestDatagram = "003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001"
+
+// lockedBuffer is a bytes.Buffer safe for a server's concurrent writes.
+type lockedBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buffer.Bytes())
+}
+
+func testJournalEntries(t *testing.T) []journal.Entry {
+	t.Helper()
+	frame, _ := hex.DecodeString(cliTestFrame)
+	datagram, _ := hex.DecodeString(journalTestDatagram)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []journal.Entry{
+		{Time: start, IMEI: serverTestIMEI, Transport: "TCP", RemoteAddr: "10.0.0.7:40112", Frame: frame},
+		{Time: start.Add(40 * time.Millisecond), IMEI: "352093086403655", Transport: "UDP", RemoteAddr: "10.0.0.8:5027", Frame: datagram},
+		{Time: start.Add(80 * time.Millisecond), Transport: "UDP", RemoteAddr: "10.0.0.9:5027", Frame: []byte{0xDE, 0xAD}},
+	}
+}
+
+func writeTestJournal(t *testing.T, entries []journal.Entry) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer, err := journal.NewWriter(&buffer)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	for _, entry := range entries {
+		if err := writer.Write(entry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func readTestJournal(t *testing.T, data []byte) []journal.Entry {
+	t.Helper()
+	reader, err := journal.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	var entries []journal.Entry
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	entries := testJournalEntries(t)
+	read := readTestJournal(t, writeTestJournal(t, entries))
+	if len(read) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(read))
+	}
+	for i := range entries {
+		want, got := entries[i], read[i]
+		if !got.Time.Equal(want.Time) || got.IMEI != want.IMEI || got.Transport != want.Transport || got.RemoteAddr != want.RemoteAddr || !bytes.Equal(got.Frame, want.Frame) {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	document, err := json.Marshal(entries[0])
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded journal.Entry
+	if err := json.Unmarshal(document, &decoded); err != nil || !bytes.Equal(decoded.Frame, entries[0].Frame) || decoded.RemoteAddr != "10.0.0.7:40112" {
+		t.Errorf("JSON round trip of %s gave %+v, %v", document, decoded, err)
+	}
+
+	var buffer bytes.Buffer
+	writer, _ := journal.NewWriter(&buffer)
+	if err := writer.Write(journal.Entry{Transport: "SCTP"}); err == nil {
+		t.Error("expected an unknown transport to be rejected")
+	}
+}
+
+func TestJournalDetectsCorruption(t *testing.T) {
+	if _, err := journal.NewReader(bytes.NewReader([]byte("not a journal"))); !errors.Is(err, journal.ErrNotJournal) {
+		t.Errorf("expected ErrNotJournal, got %v", err)
+	}
+
+	data := writeTestJournal(t, testJournalEntries(t))
+	data[len(data)-7] ^= 0xFF
+	reader, _ := journal.NewReader(bytes.NewReader(data))
+	reader.Next()
+	reader.Next()
+	if _, err := reader.Next(); !errors.Is(err, journal.ErrCorrupt) || errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected a CRC error, got %v", err)
+	}
+
+	reader, _ = journal.NewReader(bytes.NewReader(data[:len(data)-3]))
+	reader.Next()
+	reader.Next()
+	if _, err := reader.Next(); !errors.Is(err, journal.ErrCorrupt) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected a truncated entry, got %v", err)
+	}
+}
+
+func TestJournalOpenFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.tjl")
+	entries := testJournalEntries(t)
+	for _, entry := range entries[:2] {
+		writer, err := journal.OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile failed: %v", err)
+		}
+		if err := writer.Write(entry); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		writer.Close()
+	}
+
+	// An entry cut short by a crash is dropped before appending.
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	file.Write([]byte{0x00, 0x00, 0x01})
+	file.Close()
+	writer, err := journal.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile of a torn journal failed: %v", err)
+	}
+	writer.Write(entries[2])
+	writer.Close()
+
+	data, _ := os.ReadFile(path)
+	if read := readTestJournal(t, data); len(read) != 3 || read[1].IMEI != entries[1].IMEI || !bytes.Equal(read[2].Frame, entries[2].Frame) {
+		t.Errorf("expected the 3 entries, got %+v", read)
+	}
+
+	os.WriteFile(path, []byte("garbage!"), 0o644)
+	if _, err := journal.OpenFile(path); !errors.Is(err, journal.ErrNotJournal) {
+		t.Errorf("expected ErrNotJournal, got %v", err)
+	}
+}
+
+func TestReplayerDecodesAtSpeed(t *testing.T) {
+	data := writeTestJournal(t, testJournalEntries(t))
+	for _, tt := range []struct {
+		speed   float64
+		minimum time.Duration
+	}{{0, 0}, {2, 40 * time.Millisecond}} {
+		reader, _ := journal.NewReader(bytes.NewReader(data))
+		var results []error
+		start := time.Now()
+		err := (&journal.Replayer{Speed: tt.speed}).Decode(context.Background(), reader, func(entry journal.Entry, decoded *decoder_domain.CodecDecoded) error {
+			results = append(results, decoded.Error)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if len(results) != 3 || results[0] != nil || results[1] != nil || results[2] == nil {
+			t.Errorf("expected the third frame only to fail, got %v", results)
+		}
+		if elapsed := time.Since(start); elapsed < tt.minimum {
+			t.Errorf("speed %v: replay took %v, expected at least %v", tt.speed, elapsed, tt.minimum)
+		}
+	}
+
+	reader, _ := journal.NewReader(bytes.NewReader(data))
+	stop := errors.New("stop")
+	err := (&journal.Replayer{}).Decode(context.Background(), reader, func(journal.Entry, *decoder_domain.CodecDecoded) error { return stop })
+	if err != stop {
+		t.Errorf("expected the callback error, got %v", err)
+	}
+}
+
+func TestServerJournalIsReplayed(t *testing.T) {
+	var recorded lockedBuffer
+	writer, _ := journal.NewWriter(&recorded)
+	addr := startTCPServer(t, &server.TCPServer{Journal: writer.Write})
+	device := &simulator.Device{IMEI: serverTestIMEI, Addr: addr, CodecID: 0x8E, RecordsPerFrame: 2, Interval: 10 * time.Millisecond, Frames: 3}
+	if err := device.Run(simulatorTestContext(t)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	udp := &server.UDPServer{ErrorLog: log.New(io.Discard, "", 0), Journal: writer.Write}
+	go udp.Serve(conn)
+	t.Cleanup(func() { udp.Close() })
+	client, _ := net.Dial("udp", conn.LocalAddr().String())
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(serverTestIOTimeout))
+	datagram, _ := hex.DecodeString(journalTestDatagram)
+	client.Write(datagram)
+	client.Read(make([]byte, 16))
+
+	entries := readTestJournal(t, recorded.Bytes())
+	if len(entries) != 4 {
+		t.Fatalf("expected 3 TCP frames and 1 datagram, got %d entries", len(entries))
+	}
+	if entry := entries[0]; entry.IMEI != serverTestIMEI || entry.Transport != "TCP" || entry.RemoteAddr == "" || entry.Frame[8] != 0x8E {
+		t.Errorf("unexpected TCP entry %+v", entry)
+	}
+	if entry := entries[3]; entry.IMEI != "352093086403655" || entry.Transport != "UDP" || entry.RemoteAddr != client.LocalAddr().String() {
+		t.Errorf("unexpected UDP entry %+v", entry)
+	}
+
+	collector := &recordCollector{}
+	target := startTCPServer(t, &server.TCPServer{Handler: collector.handle})
+	reader, _ := journal.NewReader(bytes.NewReader(writeTestJournal(t, entries[:3])))
+	sent, err := (&journal.Replayer{}).Send(simulatorTestContext(t), reader, target)
+	if err != nil || sent != 3 {
+		t.Fatalf("Send returned %d, %v", sent, err)
+	}
+	if imeis, records := collector.snapshot(); len(records) != 6 || imeis[0] != serverTestIMEI {
+		t.Errorf("expected 6 replayed records from %s, got %d from %v", serverTestIMEI, len(records), imeis)
+	}
+
+	udpTarget, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	udpCollector := &recordCollector{}
+	replayed := &server.UDPServer{ErrorLog: log.New(io.Discard, "", 0), Handler: udpCollector.handle}
+	go replayed.Serve(udpTarget)
+	t.Cleanup(func() { replayed.Close() })
+	reader, _ = journal.NewReader(bytes.NewReader(writeTestJournal(t, entries[3:])))
+	if sent, err := (&journal.Replayer{}).Send(simulatorTestContext(t), reader, udpTarget.LocalAddr().String()); err != nil || sent != 1 {
+		t.Errorf("Send of the datagram returned %d, %v", sent, err)
+	}
+	if imeis, records := udpCollector.snapshot(); len(records) != 1 || imeis[0] != "352093086403655" {
+		t.Errorf("expected the replayed datagram, got %d records from %v", len(records), imeis)
+	}
+
+	rejecting := startTCPServer(t, &server.TCPServer{Authenticate: func(string) bool { return false }})
+	reader, _ = journal.NewReader(bytes.NewReader(recorded.Bytes()))
+	if _, err := (&journal.Replayer{}).Send(simulatorTestContext(t), reader, rejecting); !errors.Is(err, journal.ErrLoginRejected) {
+		t.Errorf("expected ErrLoginRejected, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	"github.com/danieljvsa/teltonika-go/iodict"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+)
+
+func TestJSONRoundTripReencodesFrames(t *testing.T) {
+	frames := []string{
+		errorsTestCodec16,
+		serverTestCodec8,
+		"005FCAFE0107000F3335323039333038363430333635358E010000016B4F831C680100000000000000000000000000000000010005000100010100010011009D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A000001",
+		"00000000000000170D01060000000F0000016C0A81C320676574696E666F0100005B66",
+	}
+	for _, input := range frames {
+		frame, _ := hex.DecodeString(input)
+		decoded := pkg.TramDecoder(frame)
+		if decoded.Error != nil {
+			t.Fatalf("TramDecoder failed: %v", decoded.Error)
+		}
+		document, err := json.Marshal(decoded.Response.Result)
+		if err != nil {
+			t.Fatalf("json.Marshal failed: %v", err)
+		}
+		encoded, err := pkg.TramEncoderJSON(document)
+		if err != nil {
+			t.Fatalf("TramEncoderJSON failed: %v\n%s", err, document)
+		}
+		// The Codec 8 frame has unsorted IOs, which the encoder sorts.
+		if input != serverTestCodec8 && !bytes.Equal(encoded, frame) {
+			t.Errorf("expected %X, got %X\n%s", frame, encoded, document)
+		}
+		if len(encoded) != len(frame) {
+			t.Errorf("expected %d bytes, got %d", len(frame), len(encoded))
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	frame, _ := hex.DecodeString(errorsTestCodec16)
+	decoded := pkg.TramDecoder(frame)
+	iodict.Builtin().Annotate(decoded.Response.Result.CodecData)
+
+	document, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	var fields struct {
+		Schema    int    `json:"schema"`
+		Type      string `json:"type"`
+		Header    map[string]any
+		CodecData struct {
+			CodecID string `json:"codec_id"`
+			Records []map[string]any
+		} `json:"codec_data"`
+	}
+	if err := json.Unmarshal(document, &fields); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if fields.Schema != pkg.JSONSchemaVersion || fields.Type != "Tram" || fields.Header["protocol"] != "TCP" || fields.CodecData.CodecID != "10" {
+		t.Errorf("unexpected document %s", document)
+	}
+	record := fields.CodecData.Records[0]
+	if record["timestamp"] != "2019-07-10T12:06:54Z" || record["generation_type_name"] != "On Change" {
+		t.Errorf("unexpected record %v", record)
+	}
+	for _, want := range []string{`"satellites":0`, `"name":"External Voltage"`, `"value":"563a"`} {
+		if !strings.Contains(string(document), want) {
+			t.Errorf("expected %s in %s", want, document)
+		}
+	}
+	if strings.Contains(string(document), "null") || strings.Contains(string(document), "Satelites") {
+		t.Errorf("unexpected null or Go field name in %s", document)
+	}
+
+	var restored decoder_domain.CodecDecoded
+	if err := json.Unmarshal(document, &restored); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if restored.Response == nil || len(restored.Response.Result.CodecData.Records) != 2 {
+		t.Errorf("unexpected restored frame %+v", restored)
+	}
+
+	if err := json.Unmarshal([]byte(`{"schema": 99}`), &restored); err == nil {
+		t.Errorf("expected error for a newer schema version")
+	}
+}
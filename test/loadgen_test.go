@@ -0,0 +1,88 @@
+package teltonika_go_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	loadgen "github.com/danieljvsa/teltonika-go/loadgen"
+	server "github.com/danieljvsa/teltonika-go/server"
+)
+
+func TestGeneratorReportsThroughputAndLatency(t *testing.T) {
+	collector := &recordCollector{}
+	addr := startTCPServer(t, &server.TCPServer{Handler: collector.handle})
+
+	generator := &loadgen.Generator{
+		Addr:            addr,
+		Devices:         20,
+		IMEIBase:        356307042441000,
+		RecordsPerFrame: 2,
+		Interval:        10 * time.Millisecond,
+		Codecs:          []loadgen.CodecShare{{CodecID: 0x08, Weight: 2}, {CodecID: 0x8E, Weight: 1}, {CodecID: 0x10, Weight: 1}},
+		Ramp:            50 * time.Millisecond,
+		Duration:        400 * time.Millisecond,
+		ReconnectAfter:  3,
+	}
+	report, err := generator.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.Devices != 20 || report.Active != 0 || len(report.Errors) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if report.Frames < 20 || report.Records != 2*report.Frames || report.Acknowledged != report.Records {
+		t.Errorf("expected every device to send acknowledged frames, got %+v", report)
+	}
+	// Reconnects keep the interval: no device sends more than one frame
+	// per Interval of the run, plus the first.
+	if report.Frames > 20*(400/10+1) {
+		t.Errorf("expected at most %d frames at one per interval, got %d", 20*(400/10+1), report.Frames)
+	}
+	if report.Connections <= 20 {
+		t.Errorf("expected devices to reconnect after 3 frames, got %d connections", report.Connections)
+	}
+	if latency := report.Latency; latency.Samples != report.Frames || latency.P50 <= 0 || latency.P50 > latency.P99 || latency.P99 > latency.Max {
+		t.Errorf("unexpected latency %+v", latency)
+	}
+	if report.FramesPerSecond <= 0 || !strings.Contains(report.String(), "errors: none") {
+		t.Errorf("unexpected report %s", report)
+	}
+
+	imeis, _ := collector.snapshot()
+	devices := map[string]bool{}
+	for _, imei := range imeis {
+		devices[imei] = true
+	}
+	if len(devices) != 20 || !devices["356307042441000"] || !devices["356307042441019"] {
+		t.Errorf("expected records from 20 consecutive IMEIs, got %v", devices)
+	}
+}
+
+func TestGeneratorCountsErrors(t *testing.T) {
+	addr := startTCPServer(t, &server.TCPServer{Authenticate: func(imei string) bool { return false }})
+	generator := &loadgen.Generator{Addr: addr, Devices: 5, Duration: time.Second}
+	report, err := generator.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Errors["login rejected"] != 5 || report.Connections != 5 || report.Frames != 0 {
+		t.Errorf("expected 5 rejected logins without reconnects, got %+v", report)
+	}
+
+	generator = &loadgen.Generator{Addr: addr, Devices: 2, Duration: 300 * time.Millisecond, Reconnect: true, ReconnectDelay: 20 * time.Millisecond}
+	if report, _ = generator.Run(context.Background()); report.Errors["login rejected"] <= 2 || report.Connections <= 2 {
+		t.Errorf("expected rejected devices to reconnect, got %+v", report)
+	}
+
+	for _, codecs := range [][]loadgen.CodecShare{{{CodecID: 0x0C, Weight: 1}}, {{CodecID: 0x08, Weight: 0}}, {{CodecID: 0x08, Weight: -1}}} {
+		if _, err := (&loadgen.Generator{Addr: addr, Codecs: codecs}).Run(context.Background()); err == nil {
+			t.Errorf("expected codec mix %+v to be rejected", codecs)
+		}
+	}
+	if _, err := (&loadgen.Generator{Addr: addr, RecordsPerFrame: 300}).Run(context.Background()); err == nil {
+		t.Errorf("expected 300 records per frame to be rejected")
+	}
+}
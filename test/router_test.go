@@ -66,7 +66,7 @@ func TestTramDecoder(t *testing.T) {
 		},
 		{
 			name:    "Valid Codec 16 UDP",
-			input:   "015BCAFE0101000F33353230393430383532333135393210010000015117E40FE80000000000000000000000000000000000EF05050400010000030000B40000EF01010042111A000001",
+			input:   "0048CAFE0101000F33353230393430383532333135393210010000015117E40FE80000000000000000000000000000000000EF05050400010000030000B40000EF01010042111A000001",
 			wantErr: false,
 		},
 		{
@@ -0,0 +1,456 @@
+package teltonika_go_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+	pkg "github.com/danieljvsa/teltonika-go/pkg"
+	server "github.com/danieljvsa/teltonika-go/server"
+)
+
+const (
+	serverTestLogin      = "000F333536333037303432343431303133"
+	serverTestCodec8     = "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF"
+	serverTestCodec8Bad  = "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CE"
+	serverTestIMEI       = "356307042441013"
+	serverTestIOTimeout  = 2 * time.Second
+	serverTestAckSuccess = "00000001"
+	serverTestAckFailure = "00000000"
+)
+
+func startTCPServer(t *testing.T, srv *server.TCPServer) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	if srv.ErrorLog == nil {
+		srv.ErrorLog = log.New(io.Discard, "", 0)
+	}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+	return listener.Addr().String()
+}
+
+func dialAndLogin(t *testing.T, addr string) (net.Conn, byte) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(serverTestIOTimeout))
+
+	login, _ := hex.DecodeString(serverTestLogin)
+	if _, err := conn.Write(login); err != nil {
+		t.Fatalf("write login failed: %v", err)
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("read login reply failed: %v", err)
+	}
+	return conn, reply[0]
+}
+
+func sendFrame(t *testing.T, conn net.Conn, frameHex string) string {
+	t.Helper()
+	frame, _ := hex.DecodeString(frameHex)
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write frame failed: %v", err)
+	}
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil {
+		t.Fatalf("read ACK failed: %v", err)
+	}
+	return hex.EncodeToString(ack)
+}
+
+func TestTCPServerAcknowledgesRecords(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	srv := &server.TCPServer{
+		Handler: func(imei string, data *decoder_domain.CodecData) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, imei)
+			if data.NumberOfRecords != 1 {
+				t.Errorf("expected 1 record, got %d", data.NumberOfRecords)
+			}
+			return nil
+		},
+	}
+	addr := startTCPServer(t, srv)
+
+	conn, reply := dialAndLogin(t, addr)
+	if reply != 0x01 {
+		t.Fatalf("expected login accepted, got %#x", reply)
+	}
+	for i := 0; i < 2; i++ {
+		if ack := sendFrame(t, conn, serverTestCodec8); ack != serverTestAckSuccess {
+			t.Fatalf("expected ACK %s, got %s", serverTestAckSuccess, ack)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 || received[0] != serverTestIMEI {
+		t.Errorf("expected 2 frames from %s, got %v", serverTestIMEI, received)
+	}
+}
+
+func TestTCPServerRejectsUnknownDevice(t *testing.T) {
+	srv := &server.TCPServer{
+		Authenticate: func(imei string) bool { return false },
+	}
+	addr := startTCPServer(t, srv)
+
+	conn, reply := dialAndLogin(t, addr)
+	if reply != 0x00 {
+		t.Fatalf("expected login rejected, got %#x", reply)
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected connection to be closed after rejection")
+	}
+}
+
+func TestTCPServerAcknowledgesZeroOnFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   string
+		handler server.Handler
+	}{
+		{
+			name:  "CRC mismatch",
+			frame: serverTestCodec8Bad,
+		},
+		{
+			name:  "Handler error",
+			frame: serverTestCodec8,
+			handler: func(imei string, data *decoder_domain.CodecData) error {
+				return io.ErrUnexpectedEOF
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startTCPServer(t, &server.TCPServer{Handler: tt.handler})
+			conn, _ := dialAndLogin(t, addr)
+			if ack := sendFrame(t, conn, tt.frame); ack != serverTestAckFailure {
+				t.Errorf("expected ACK %s, got %s", serverTestAckFailure, ack)
+			}
+		})
+	}
+}
+
+func TestTCPServerCloseStopsServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	srv := &server.TCPServer{ErrorLog: log.New(&bytes.Buffer{}, "", 0)}
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(listener) }()
+
+	conn, _ := dialAndLogin(t, listener.Addr().String())
+	if err := srv.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != server.ErrServerClosed {
+			t.Errorf("expected ErrServerClosed, got %v", err)
+		}
+	case <-time.After(serverTestIOTimeout):
+		t.Fatal("Serve did not return after Close")
+	}
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected connection to be closed")
+	}
+}
+
+func TestUDPServerAcknowledgesDatagram(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	var mu sync.Mutex
+	var received []string
+	srv := &server.UDPServer{
+		ErrorLog: log.New(io.Discard, "", 0),
+		Handler: func(imei string, data *decoder_domain.CodecData) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, imei)
+			return nil
+		},
+	}
+	go srv.Serve(conn)
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(serverTestIOTimeout))
+
+	datagram, _ := hex.DecodeString("003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001")
+	if _, err := client.Write(datagram); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	ack := make([]byte, 16)
+	n, err := client.Read(ack)
+	if err != nil {
+		t.Fatalf("read ACK failed: %v", err)
+	}
+	if got := hex.EncodeToString(ack[:n]); got != "0005cafe010501" {
+		t.Errorf("expected ACK 0005cafe010501, got %s", got)
+	}
+
+	mu.Lock()
+	if len(received) != 1 || received[0] != "352093086403655" {
+		t.Errorf("expected one datagram from 352093086403655, got %v", received)
+	}
+	mu.Unlock()
+	addr, ok := srv.RemoteAddr("352093086403655")
+	if !ok || addr.String() != client.LocalAddr().String() {
+		t.Errorf("expected sender address %s, got %v", client.LocalAddr(), addr)
+	}
+}
+
+func TestUDPServerExpiresIdleSessions(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	registry := &server.Registry{}
+	srv := &server.UDPServer{ErrorLog: log.New(io.Discard, "", 0), Sessions: registry, SessionTimeout: 100 * time.Millisecond}
+	go srv.Serve(conn)
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(serverTestIOTimeout))
+
+	datagram, _ := hex.DecodeString("003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001")
+	send := func() {
+		t.Helper()
+		if _, err := client.Write(datagram); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if _, err := client.Read(make([]byte, 16)); err != nil {
+			t.Fatalf("read ACK failed: %v", err)
+		}
+	}
+	send()
+	session, ok := registry.Lookup("352093086403655")
+	if !ok {
+		t.Fatal("expected a session after the first datagram")
+	}
+
+	// Datagrams within the timeout keep the session alive.
+	for range 3 {
+		time.Sleep(60 * time.Millisecond)
+		send()
+	}
+	if current, ok := registry.Lookup("352093086403655"); !ok || current != session {
+		t.Fatal("expected the session to stay registered while the device sends")
+	}
+
+	select {
+	case <-session.Done():
+	case <-time.After(serverTestIOTimeout):
+		t.Fatal("idle session was not closed")
+	}
+	if _, ok := registry.Lookup("352093086403655"); ok {
+		t.Error("expected the idle session to be unregistered")
+	}
+	if _, ok := srv.RemoteAddr("352093086403655"); ok {
+		t.Error("expected no sender address for an expired session")
+	}
+}
+
+func TestUDPServerCloseEndsSessions(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	registry := &server.Registry{}
+	srv := &server.UDPServer{ErrorLog: log.New(io.Discard, "", 0), Sessions: registry}
+	go srv.Serve(conn)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(serverTestIOTimeout))
+	datagram, _ := hex.DecodeString("003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001")
+	if _, err := client.Write(datagram); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := client.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("read ACK failed: %v", err)
+	}
+	session, ok := registry.Lookup("352093086403655")
+	if !ok {
+		t.Fatal("expected a session after the first datagram")
+	}
+
+	srv.Close()
+	select {
+	case <-session.Done():
+	default:
+		t.Error("expected Close to close the session")
+	}
+	if registry.Len() != 0 {
+		t.Errorf("expected Close to unregister the session, %d left", registry.Len())
+	}
+}
+
+func TestTCPServerHandlesCoalescedFrames(t *testing.T) {
+	addr := startTCPServer(t, &server.TCPServer{})
+	conn, _ := dialAndLogin(t, addr)
+
+	frames, _ := hex.DecodeString(serverTestCodec8 + serverTestCodec8)
+	if _, err := conn.Write(frames); err != nil {
+		t.Fatalf("write frames failed: %v", err)
+	}
+	acks := make([]byte, 8)
+	if _, err := io.ReadFull(conn, acks); err != nil {
+		t.Fatalf("read ACKs failed: %v", err)
+	}
+	if got := hex.EncodeToString(acks); got != serverTestAckSuccess+serverTestAckSuccess {
+		t.Errorf("expected two ACKs, got %s", got)
+	}
+}
+
+func TestTCPServerSendCommand(t *testing.T) {
+	srv := &server.TCPServer{}
+	addr := startTCPServer(t, srv)
+	conn, _ := dialAndLogin(t, addr)
+
+	type result struct {
+		response *tool_domain.CommandResponse
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), serverTestIOTimeout)
+		defer cancel()
+		response, err := srv.SendCommand(ctx, serverTestIMEI, "getinfo")
+		results <- result{response, err}
+	}()
+
+	frame, err := pkg.NewFramer(conn, 0).Next()
+	if err != nil {
+		t.Fatalf("reading command failed: %v", err)
+	}
+	command := pkg.TramDecoder(frame)
+	if command.Error != nil {
+		t.Fatalf("decoding command failed: %v", command.Error)
+	}
+	record := command.Response.Result.CodecData.Records[0]
+	if *record.CommandType != "Command" || (*record.CommandResponses)[0].Response != "getinfo" {
+		t.Fatalf("unexpected command %+v", (*record.CommandResponses)[0])
+	}
+
+	// An AVL frame arriving before the response is still acknowledged.
+	if ack := sendFrame(t, conn, serverTestCodec8); ack != serverTestAckSuccess {
+		t.Fatalf("expected ACK %s, got %s", serverTestAckSuccess, ack)
+	}
+
+	response, _ := hex.DecodeString("00000000000000900C010600000088494E493A323031392F372F323220373A3232205254433A323031392F372F323220373A3533205253543A32204552523A312053523A302042523A302043463A302046473A3020464C3A302054553A302F302055543A3020534D533A30204E4F4750533A303A3330204750533A31205341543A302052533A332052463A36352053463A31204D443A30010000C78F")
+	if _, err := conn.Write(response); err != nil {
+		t.Fatalf("write response failed: %v", err)
+	}
+
+	res := <-results
+	if res.err != nil {
+		t.Fatalf("SendCommand failed: %v", res.err)
+	}
+	if res.response.CommandType != "Response" || !strings.HasPrefix(res.response.Response, "INI:2019/7/22") {
+		t.Errorf("unexpected response %+v", res.response)
+	}
+}
+
+func TestTCPServerSendCommandErrors(t *testing.T) {
+	srv := &server.TCPServer{}
+	addr := startTCPServer(t, srv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := srv.SendCommand(ctx, serverTestIMEI, "getinfo"); !errors.Is(err, server.ErrDeviceNotConnected) {
+		t.Errorf("expected ErrDeviceNotConnected, got %v", err)
+	}
+
+	dialAndLogin(t, addr)
+	if _, err := srv.SendCommand(ctx, serverTestIMEI, "getinfo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestTCPServerRegistersSessions(t *testing.T) {
+	registry := &server.Registry{}
+	addr := startTCPServer(t, &server.TCPServer{Sessions: registry})
+
+	first, _ := dialAndLogin(t, addr)
+	sendFrame(t, first, serverTestCodec8)
+
+	session, ok := registry.Lookup(serverTestIMEI)
+	if !ok {
+		t.Fatalf("expected session for %s", serverTestIMEI)
+	}
+	if session.Protocol != "TCP" || session.RemoteAddr.String() != first.LocalAddr().String() {
+		t.Errorf("unexpected session %s %v", session.Protocol, session.RemoteAddr)
+	}
+	if session.FramesReceived() != 1 || session.LastRecord() == nil || session.LastRecord().Timestamp == nil {
+		t.Errorf("expected one frame with a record, got %d frames, record %v", session.FramesReceived(), session.LastRecord())
+	}
+	if session.LastSeen().Before(session.ConnectedAt) {
+		t.Errorf("last seen %v before connect time %v", session.LastSeen(), session.ConnectedAt)
+	}
+
+	// A new login of the same device evicts the previous session.
+	second, _ := dialAndLogin(t, addr)
+	select {
+	case <-session.Done():
+	case <-time.After(serverTestIOTimeout):
+		t.Fatal("previous session was not closed")
+	}
+	if _, err := first.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected previous connection to be closed")
+	}
+	current, ok := registry.Lookup(serverTestIMEI)
+	if !ok || current == session || current.RemoteAddr.String() != second.LocalAddr().String() {
+		t.Errorf("expected the new session to be registered")
+	}
+	if registry.Len() != 1 || len(registry.Sessions()) != 1 {
+		t.Errorf("expected one session, got %d", registry.Len())
+	}
+
+	// Closing the connection removes the session.
+	second.Close()
+	deadline := time.Now().Add(serverTestIOTimeout)
+	for registry.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if registry.Len() != 0 {
+		t.Errorf("expected session to be removed after disconnect")
+	}
+}
@@ -0,0 +1,238 @@
+package teltonika_go_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
+	server "github.com/danieljvsa/teltonika-go/server"
+	simulator "github.com/danieljvsa/teltonika-go/simulator"
+	tools "github.com/danieljvsa/teltonika-go/tools"
+)
+
+// recordCollector is a server Handler that keeps the records it receives.
+type recordCollector struct {
+	mu      sync.Mutex
+	imeis   []string
+	records []decoder_domain.Record
+}
+
+func (c *recordCollector) handle(imei string, data *decoder_domain.CodecData) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.imeis = append(c.imeis, imei)
+	c.records = append(c.records, data.Records...)
+	return nil
+}
+
+func (c *recordCollector) snapshot() ([]string, []decoder_domain.Record) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.imeis...), append([]decoder_domain.Record{}, c.records...)
+}
+
+func simulatorTestContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestSimulatorStreamsTCPRecords(t *testing.T) {
+	collector := &recordCollector{}
+	addr := startTCPServer(t, &server.TCPServer{Handler: collector.handle})
+
+	route, err := simulator.ParseRoute("54.6872,25.2797;54.6890,25.2850,120")
+	if err != nil {
+		t.Fatalf("ParseRoute failed: %v", err)
+	}
+	voltage, _ := tools.NewIOUint(66, 2, 12800)
+	var acks int
+	device := &simulator.Device{
+		IMEI:            serverTestIMEI,
+		Addr:            addr,
+		CodecID:         0x8E,
+		Route:           route,
+		RecordsPerFrame: 2,
+		Interval:        20 * time.Millisecond,
+		Frames:          3,
+		IOs:             []io_domain.IOData{voltage},
+		OnAck:           func(records int, accepted int64, latency time.Duration) { acks++ },
+	}
+	if err := device.Run(simulatorTestContext(t)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if stats := device.Stats(); stats != (simulator.Stats{Frames: 3, Records: 6, Acknowledged: 6}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if acks != 3 {
+		t.Errorf("expected 3 ACK callbacks, got %d", acks)
+	}
+	imeis, records := collector.snapshot()
+	if len(imeis) != 3 || imeis[0] != serverTestIMEI || len(records) != 6 {
+		t.Fatalf("expected 6 records in 3 frames from %s, got %d from %v", serverTestIMEI, len(records), imeis)
+	}
+	second := records[1]
+	if second.GPSData.Latitude != 54.689 || second.GPSData.Altitude != 120 || second.GPSData.Speed == 0 || !second.GPSData.Valid {
+		t.Errorf("unexpected GPS element %+v", second.GPSData)
+	}
+	if ios := *second.IOs; len(ios) != 1 || ios[0].IO != 66 || ios[0].Uint64() != 12800 {
+		t.Errorf("unexpected IO elements %+v", ios)
+	}
+	if !records[0].Timestamp.Before(*records[1].Timestamp) {
+		t.Errorf("expected increasing timestamps, got %v and %v", records[0].Timestamp, records[1].Timestamp)
+	}
+}
+
+func TestSimulatorAnswersCommands(t *testing.T) {
+	srv := &server.TCPServer{}
+	addr := startTCPServer(t, srv)
+	device := &simulator.Device{
+		IMEI:      serverTestIMEI,
+		Addr:      addr,
+		Interval:  50 * time.Millisecond,
+		Responses: map[string]string{"getinfo": "INFO", "setdigout": "DOUT1:1"},
+	}
+	ctx, cancel := context.WithCancel(simulatorTestContext(t))
+	done := make(chan error, 1)
+	go func() { done <- device.Run(ctx) }()
+
+	for _, tt := range []struct{ command, response string }{
+		{"getinfo", "INFO"},
+		{"setdigout 1", "DOUT1:1"},
+		{"getver", "Unknown command"},
+	} {
+		var response string
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			commandCtx, commandCancel := context.WithTimeout(ctx, time.Second)
+			reply, err := srv.SendCommand(commandCtx, serverTestIMEI, tt.command)
+			commandCancel()
+			if errors.Is(err, server.ErrDeviceNotConnected) {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			if err != nil {
+				t.Fatalf("SendCommand %q failed: %v", tt.command, err)
+			}
+			response = reply.Response
+			break
+		}
+		if response != tt.response {
+			t.Errorf("expected %q to be answered %q, got %q", tt.command, tt.response, response)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run failed: %v", err)
+	}
+	if stats := device.Stats(); stats.Commands != 3 || stats.Acknowledged != stats.Records {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSimulatorStreamsUDPRecords(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	collector := &recordCollector{}
+	srv := &server.UDPServer{ErrorLog: log.New(io.Discard, "", 0), Handler: collector.handle}
+	go srv.Serve(conn)
+	t.Cleanup(func() { srv.Close() })
+
+	device := &simulator.Device{
+		IMEI:     "352093086403655",
+		Addr:     conn.LocalAddr().String(),
+		Protocol: "UDP",
+		CodecID:  0x10,
+		Interval: 10 * time.Millisecond,
+		Frames:   2,
+		IOs:      []io_domain.IOData{tools.NewIOBool(239, true)},
+	}
+	if err := device.Run(simulatorTestContext(t)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stats := device.Stats(); stats.Frames != 2 || stats.Acknowledged != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	imeis, records := collector.snapshot()
+	if len(records) != 2 || imeis[0] != "352093086403655" {
+		t.Fatalf("expected 2 records from 352093086403655, got %d from %v", len(records), imeis)
+	}
+	if generationType := records[0].GenerationType; generationType == nil || *generationType != decoder_domain.GenerationPeriodical {
+		t.Errorf("unexpected generation type %v", generationType)
+	}
+	if records[0].GPSData.Valid {
+		t.Errorf("expected no fix without a route, got %+v", records[0].GPSData)
+	}
+}
+
+func TestSimulatorKeepsIntervalAcrossRuns(t *testing.T) {
+	addr := startTCPServer(t, &server.TCPServer{})
+	device := &simulator.Device{IMEI: serverTestIMEI, Addr: addr, Interval: 150 * time.Millisecond, Frames: 1}
+	start := time.Now()
+	for range 3 {
+		if err := device.Run(simulatorTestContext(t)); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("expected 3 frames to take 2 intervals over 3 runs, took %v", elapsed)
+	}
+	if next := device.Next(); time.Until(next) <= 0 {
+		t.Errorf("expected the next frame to be planned after now, got %v", next)
+	}
+
+	device = &simulator.Device{IMEI: serverTestIMEI, Addr: addr, Interval: 100 * time.Millisecond, Until: time.Now().Add(250 * time.Millisecond)}
+	if err := device.Run(simulatorTestContext(t)); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if stats := device.Stats(); stats.Frames != 3 || stats.Acknowledged != 3 {
+		t.Errorf("expected 3 acknowledged frames before Until, got %+v", stats)
+	}
+	if err := device.Run(simulatorTestContext(t)); err != nil || device.Stats().Frames != 3 {
+		t.Errorf("expected no frame after Until, got %v and %+v", err, device.Stats())
+	}
+}
+
+func TestSimulatorErrors(t *testing.T) {
+	addr := startTCPServer(t, &server.TCPServer{Authenticate: func(imei string) bool { return false }})
+	device := &simulator.Device{IMEI: serverTestIMEI, Addr: addr, Frames: 1}
+	if err := device.Run(simulatorTestContext(t)); !errors.Is(err, simulator.ErrLoginRejected) {
+		t.Errorf("expected ErrLoginRejected, got %v", err)
+	}
+
+	for _, device := range []*simulator.Device{
+		{Addr: addr},
+		{IMEI: serverTestIMEI, Addr: addr, Protocol: "SCTP"},
+		{IMEI: serverTestIMEI, Addr: addr, CodecID: 0x0C},
+		{IMEI: serverTestIMEI, Addr: addr, RecordsPerFrame: simulator.MaxRecordsPerFrame + 1},
+	} {
+		if err := device.Run(simulatorTestContext(t)); err == nil {
+			t.Errorf("expected %+v to be rejected", device)
+		}
+	}
+
+	for _, route := range []string{"54.6872", "91,0", "0,181", "1,2,x", "1,2,-5", "1,2,40000", "1,2,70000"} {
+		if _, err := simulator.ParseRoute(route); err == nil {
+			t.Errorf("expected route %q to be rejected", route)
+		}
+	}
+	if _, err := simulator.ParseRoute("1,2,-5"); err == nil || !strings.Contains(err.Error(), "negative altitude") {
+		t.Errorf("expected a negative altitude error, got %v", err)
+	}
+	if route, err := simulator.ParseRoute("1,2,32767"); err != nil || route[0].Altitude != 32767 {
+		t.Errorf("expected an altitude of 32767 m, got %v, %v", route, err)
+	}
+}
@@ -12,7 +12,7 @@ import (
 
 func TestDecodeGPSData(t *testing.T) {
 	// Example valid data (hex representation of values)
-	data, _ := hex.DecodeString("F0A9AFC0209CCA800123456789ab")
+	data, _ := hex.DecodeString("F0A9AFC0209CCA800123456789abcd")
 
 	gps, err := tools.DecodeGPSData(data)
 	if err != nil {
@@ -34,7 +34,7 @@ func TestDecodeGPSData(t *testing.T) {
 	expectedAltitude := int64(0x0123)
 	expectedAngle := int64(0x4567)
 	expectedSatelites := int64(0x89)
-	expectedSpeed := int64(0xab)
+	expectedSpeed := int64(0xabcd)
 
 	if gps.Latitude != expectedLatitude {
 		t.Errorf("Latitude: expected %v, got %v", expectedLatitude, gps.Latitude)
@@ -81,6 +81,41 @@ func TestEncodeGPSDataRoundTrip(t *testing.T) {
 	}
 }
 
+func TestGPSDataSpeedAltitudeAndValidity(t *testing.T) {
+	gps := &tool_domain.GPSData{Latitude: 54.6872, Longitude: 25.2797, Altitude: 1250, Angle: 359, Satelites: 9, Speed: 300}
+	encoded, err := tools.EncodeGPSData(gps)
+	if err != nil {
+		t.Fatalf("EncodeGPSData failed: %v", err)
+	}
+	if len(encoded) != tools.GPSElementSize {
+		t.Fatalf("expected %d bytes, got %d", tools.GPSElementSize, len(encoded))
+	}
+	decoded, err := tools.DecodeGPSData(encoded)
+	if err != nil {
+		t.Fatalf("DecodeGPSData failed: %v", err)
+	}
+	if decoded.Speed != 300 || decoded.Altitude != 1250 || !decoded.Valid {
+		t.Errorf("unexpected decoded GPS data %+v", decoded)
+	}
+
+	// No fix: 0 satellites and speed 0, with the last known position.
+	noFix, _ := hex.DecodeString("0F0EA8501FE3C8B0001E00B4000000")
+	decoded, err = tools.DecodeGPSData(noFix)
+	if err != nil {
+		t.Fatalf("DecodeGPSData failed: %v", err)
+	}
+	if decoded.Valid || decoded.Angle != 180 || decoded.Altitude != 30 {
+		t.Errorf("expected an invalid fix keeping angle and altitude, got %+v", decoded)
+	}
+
+	if _, err := tools.DecodeGPSData(encoded[:14]); err == nil {
+		t.Errorf("expected error for a 14-byte GPS element")
+	}
+	if _, err := tools.EncodeGPSData(&tool_domain.GPSData{Speed: 65536}); err == nil {
+		t.Errorf("expected error for speed out of uint16 range")
+	}
+}
+
 func TestCalcTimestamp(t *testing.T) {
 	// Example timestamp: 1711765200000 (Unix timestamp in milliseconds)
 	// This corresponds to 2024-04-01 12:00:00 UTC.
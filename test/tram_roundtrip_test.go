@@ -1,18 +1,22 @@
 package teltonika_go_test
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/hex"
 	"testing"
 	"time"
 
 	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
+	header_domain "github.com/danieljvsa/teltonika-go/internal/header"
 	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
 	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
 	pkg "github.com/danieljvsa/teltonika-go/pkg"
 )
 
 func buildTCPTram(codecID byte, payload []byte) []byte {
-	dataLength := uint32(len(payload) + 1)
+	// The data length covers the codec ID and the payload without its CRC.
+	dataLength := uint32(len(payload) + 1 - 4)
 	header := make([]byte, 8)
 	binary.BigEndian.PutUint32(header[4:8], dataLength)
 	tram := append(header, codecID)
@@ -78,3 +82,185 @@ func TestTramEncodeDecodeRoundTripCodec8(t *testing.T) {
 		t.Errorf("expected event IO %d, got %v", eventIO, decodedRecord.EventIO)
 	}
 }
+
+func TestTramEncoderReproducesDecodedFrames(t *testing.T) {
+	// The encoder sorts IO elements by ID inside each size group, so frames
+	// whose IOs are not sorted only match after a second decode.
+	tests := []struct {
+		name     string
+		input    string
+		reorders bool
+	}{
+		{
+			name:     "Codec 08 TCP",
+			input:    "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF",
+			reorders: true,
+		},
+		{
+			name:  "Codec 8E TCP",
+			input: "000000000000004A8E010000016B412CEE000100000000000000000000000000000000010005000100010100010011001D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A00000100002994",
+		},
+		{
+			name:     "Codec 08 UDP",
+			input:    "003DCAFE0105000F33353230393330383634303336353508010000016B4F815B30010000000000000000000000000000000103021503010101425DBC000001",
+			reorders: true,
+		},
+		{
+			name:  "Codec 8E UDP",
+			input: "005FCAFE0107000F3335323039333038363430333635358E010000016B4F831C680100000000000000000000000000000000010005000100010100010011009D00010010015E2C880002000B000000003544C87A000E000000001DD7E06A000001",
+		},
+		{
+			name:  "Codec 16 TCP",
+			input: "000000000000005F10020000016BDBC7833000000000000000000000000000000000000B05040200010000030002000B00270042563A00000000016BDBC7871800000000000000000000000000000000000B05040200010000030002000B00260042563A00000200005FB3",
+		},
+		{
+			name:  "Codec 16 UDP",
+			input: "0048CAFE0101000F33353230393430383532333135393210010000015117E40FE80000000000000000000000000000000000EF05050400010000030000B40000EF01010042111A000001",
+		},
+		{
+			name:  "Codec 12 TCP",
+			input: "000000000000000F0C010500000007676574696E666F0100004312",
+		},
+		{
+			name:  "Codec 13 TCP",
+			input: "00000000000000170D01060000000F0000016C0A81C320676574696E666F0100005B66",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.input)
+			if err != nil {
+				t.Fatalf("invalid test input: %v", err)
+			}
+			decoded := pkg.TramDecoder(data)
+			if decoded.Error != nil {
+				t.Fatalf("TramDecoder failed: %v", decoded.Error)
+			}
+
+			encoded, err := pkg.TramEncoder(&decoded.Response.Result)
+			if err != nil {
+				t.Fatalf("TramEncoder failed: %v", err)
+			}
+			if tt.reorders {
+				if len(encoded) != len(data) {
+					t.Errorf("expected %d bytes, got %d", len(data), len(encoded))
+				}
+				if res := pkg.TramDecoder(encoded); res.Error != nil {
+					t.Errorf("decoding encoded frame failed: %v", res.Error)
+				}
+				return
+			}
+			if !bytes.Equal(encoded, data) {
+				t.Errorf("expected %X, got %X", data, encoded)
+			}
+		})
+	}
+}
+
+func TestTramEncoderBuildsUDPFrame(t *testing.T) {
+	timestamp := time.Unix(1701000000, 0).UTC()
+	priority := int64(0)
+	eventIO := int64(0)
+	gps := &tool_domain.GPSData{Latitude: 54.6872, Longitude: 25.2797, Satelites: 12, Speed: 40}
+	ios := []io_domain.IOData{{IO: 239, Value: "01"}}
+	request := &decoder_domain.CodecHeaderResponse{
+		HeaderData: &header_domain.HeaderData{
+			Protocol:  "UDP",
+			HeaderUDP: &header_domain.HeaderDataUDP{PacketID: 0xCAFE, AVLPacketID: 0x2A, IMEI: "352093086403655"},
+		},
+		CodecData: &decoder_domain.CodecData{
+			CodecID:         0x8E,
+			NumberOfRecords: 1,
+			Records: []decoder_domain.Record{
+				{Timestamp: &timestamp, Priority: &priority, GPSData: gps, EventIO: &eventIO, IOs: &ios},
+			},
+		},
+	}
+
+	frame, err := pkg.TramEncoder(request)
+	if err != nil {
+		t.Fatalf("TramEncoder failed: %v", err)
+	}
+	if got := int(binary.BigEndian.Uint16(frame[:2])); got != len(frame)-2 {
+		t.Errorf("expected length field %d, got %d", len(frame)-2, got)
+	}
+
+	decoded := pkg.TramDecoder(frame)
+	if decoded.Error != nil {
+		t.Fatalf("TramDecoder failed: %v", decoded.Error)
+	}
+	header := decoded.Response.Result.HeaderData.HeaderUDP
+	if header.PacketID != 0xCAFE || header.AVLPacketID != 0x2A || header.IMEI != "352093086403655" {
+		t.Errorf("unexpected UDP header %+v", header)
+	}
+	if decoded.Response.Result.CodecData.CodecID != 0x8E {
+		t.Errorf("expected codec 8E, got %#x", decoded.Response.Result.CodecData.CodecID)
+	}
+}
+
+func TestTramEncoderRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *decoder_domain.CodecHeaderResponse
+	}{
+		{name: "Nil request", request: nil},
+		{name: "Missing codec data", request: &decoder_domain.CodecHeaderResponse{}},
+		{
+			name:    "Unknown codec",
+			request: &decoder_domain.CodecHeaderResponse{CodecData: &decoder_domain.CodecData{CodecID: 0x42}},
+		},
+		{
+			name: "UDP without header",
+			request: &decoder_domain.CodecHeaderResponse{
+				HeaderData: &header_domain.HeaderData{Protocol: "UDP"},
+				CodecData:  &decoder_domain.CodecData{CodecID: 0x0C, Records: commandRecords("getinfo")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pkg.TramEncoder(tt.request); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func commandRecords(command string) []decoder_domain.Record {
+	commandType := "Command"
+	commands := []tool_domain.CommandResponse{{Response: command}}
+	return []decoder_domain.Record{{CommandType: &commandType, CommandResponses: &commands}}
+}
+
+func TestCodec16GenerationTypeRoundTrip(t *testing.T) {
+	timestamp := time.UnixMilli(1560166592000).UTC()
+	priority, eventIO := int64(0), int64(11)
+	ios := []io_domain.IOData{{IO: 1, Value: "00"}}
+	for _, generationType := range []decoder_domain.GenerationType{decoder_domain.GenerationOnChange, 9} {
+		record := decoder_domain.Record{
+			Timestamp:      &timestamp,
+			Priority:       &priority,
+			GPSData:        &tool_domain.GPSData{},
+			EventIO:        &eventIO,
+			IOs:            &ios,
+			GenerationType: &generationType,
+		}
+		payload, err := pkg.EncodeCodec16(&decoder_domain.CodecData{NumberOfRecords: 1, Records: []decoder_domain.Record{record}})
+		if err != nil {
+			t.Fatalf("EncodeCodec16 failed: %v", err)
+		}
+		decoded, err := pkg.DecodeCodec16(payload, "TCP")
+		if err != nil {
+			t.Fatalf("DecodeCodec16 failed: %v", err)
+		}
+		got := decoded.Records[0].GenerationType
+		if got == nil || *got != generationType {
+			t.Errorf("expected generation type %v, got %v", generationType, got)
+		}
+	}
+	if name := decoder_domain.GenerationType(9).String(); name != "Unknown generation type" {
+		t.Errorf("unexpected name %q", name)
+	}
+}
@@ -18,12 +18,5 @@ func DecodeToHexThenASCII(data []byte, message []byte, size int) (string, string
 	}
 
 	segment := src[:size]
-	hexStr := hex.EncodeToString(segment)
-
-	decodedBytes, err := hex.DecodeString(hexStr)
-	if err != nil {
-		return hexStr, "", fmt.Errorf("hex decode error: %w", err)
-	}
-
-	return hexStr, string(decodedBytes), nil
+	return hex.EncodeToString(segment), string(segment), nil
 }
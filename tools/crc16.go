@@ -8,8 +8,8 @@ import (
 // for the given byte slice. This is commonly used in Teltonika protocol for
 // frame validation.
 //
-// The CRC-16 IBM algorithm uses polynomial 0xA001 and processes each bit
-// of every byte in the input.
+// The CRC-16 IBM algorithm uses the reflected polynomial 0xA001 and is
+// computed a byte at a time with a precomputed table.
 //
 // Parameters:
 //   - data: byte slice to compute checksum for
@@ -22,9 +22,13 @@ import (
 //	data := []byte{0x01, 0x02, 0x03}
 //	crc := Crc16IBM(data)
 func Crc16IBM(data []byte) uint16 {
-	var crc uint16 = 0x0000
-	for _, b := range data {
-		crc ^= uint16(b)
+	return crc16IBMUpdate(0x0000, data)
+}
+
+// crc16IBMTable holds the CRC of every byte value.
+var crc16IBMTable = func() (table [256]uint16) {
+	for n := range table {
+		crc := uint16(n)
 		for i := 0; i < 8; i++ {
 			if crc&0x0001 != 0 {
 				crc = (crc >> 1) ^ 0xA001
@@ -32,6 +36,14 @@ func Crc16IBM(data []byte) uint16 {
 				crc >>= 1
 			}
 		}
+		table[n] = crc
+	}
+	return table
+}()
+
+func crc16IBMUpdate(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc = (crc >> 8) ^ crc16IBMTable[byte(crc)^b]
 	}
 	return crc
 }
@@ -62,3 +74,22 @@ func IsValidTram(tram []byte) bool {
 	calculatedCRC := Crc16IBM(data)
 	return receivedCRC == calculatedCRC
 }
+
+// IsValidCodecData validates the CRC of the data part of a TCP frame, as
+// passed to the DecodeCodecX functions: the bytes after the codec ID, up to
+// and including the 4-byte CRC. The codec ID is included in the checksum
+// without copying the frame.
+//
+// Example:
+//
+//	isValid := IsValidCodecData(0x08, frame[9:])
+func IsValidCodecData(codecID byte, data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	length := len(data)
+	crc := crc16IBMUpdate(0x0000, []byte{codecID})
+	calculatedCRC := crc16IBMUpdate(crc, data[:length-4])
+	receivedCRC := uint16(binary.BigEndian.Uint32(data[length-4:]))
+	return receivedCRC == calculatedCRC
+}
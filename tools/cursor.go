@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Cursor reads big-endian fields from a byte slice and tracks the offset
+// of the next unread byte. Reads never copy or allocate: Bytes returns a
+// sub-slice of the underlying data.
+//
+// A Cursor is used by value as a local variable so that decoding a frame
+// does not allocate for the cursor itself.
+//
+// Example:
+//
+//	cursor := NewCursor(data)
+//	count, err := cursor.Uint8()
+//	timestamp, err := cursor.Uint64()
+type Cursor struct {
+	data   []byte
+	offset int
+}
+
+// NewCursor returns a Cursor positioned at the first byte of data.
+func NewCursor(data []byte) Cursor {
+	return Cursor{data: data}
+}
+
+// Offset returns the number of bytes read so far.
+func (c *Cursor) Offset() int {
+	return c.offset
+}
+
+// Len returns the number of unread bytes.
+func (c *Cursor) Len() int {
+	return len(c.data) - c.offset
+}
+
+// Remaining returns the unread bytes without consuming them.
+func (c *Cursor) Remaining() []byte {
+	return c.data[c.offset:]
+}
+
+// Data returns the whole slice the cursor reads from.
+func (c *Cursor) Data() []byte {
+	return c.data
+}
+
+// Skip advances the cursor by n bytes.
+func (c *Cursor) Skip(n int) error {
+	if err := c.need(n); err != nil {
+		return err
+	}
+	c.offset += n
+	return nil
+}
+
+// Bytes returns the next n bytes as a sub-slice of the data.
+func (c *Cursor) Bytes(n int) ([]byte, error) {
+	if err := c.need(n); err != nil {
+		return nil, err
+	}
+	value := c.data[c.offset : c.offset+n : c.offset+n]
+	c.offset += n
+	return value, nil
+}
+
+// Uint8 reads one byte.
+func (c *Cursor) Uint8() (uint8, error) {
+	if err := c.need(1); err != nil {
+		return 0, err
+	}
+	value := c.data[c.offset]
+	c.offset++
+	return value, nil
+}
+
+// Uint16 reads a big-endian 2-byte integer.
+func (c *Cursor) Uint16() (uint16, error) {
+	if err := c.need(2); err != nil {
+		return 0, err
+	}
+	value := binary.BigEndian.Uint16(c.data[c.offset:])
+	c.offset += 2
+	return value, nil
+}
+
+// Uint32 reads a big-endian 4-byte integer.
+func (c *Cursor) Uint32() (uint32, error) {
+	if err := c.need(4); err != nil {
+		return 0, err
+	}
+	value := binary.BigEndian.Uint32(c.data[c.offset:])
+	c.offset += 4
+	return value, nil
+}
+
+// Uint64 reads a big-endian 8-byte integer.
+func (c *Cursor) Uint64() (uint64, error) {
+	if err := c.need(8); err != nil {
+		return 0, err
+	}
+	value := binary.BigEndian.Uint64(c.data[c.offset:])
+	c.offset += 8
+	return value, nil
+}
+
+// Uint reads a big-endian unsigned integer of 1 to 8 bytes.
+func (c *Cursor) Uint(size int) (uint64, error) {
+	switch size {
+	case 1:
+		value, err := c.Uint8()
+		return uint64(value), err
+	case 2:
+		value, err := c.Uint16()
+		return uint64(value), err
+	case 4:
+		value, err := c.Uint32()
+		return uint64(value), err
+	case 8:
+		return c.Uint64()
+	}
+	if size < 1 || size > 8 {
+		return 0, NewDecodeError(errors.New("invalid integer size"), "", c.offset)
+	}
+	if err := c.need(size); err != nil {
+		return 0, err
+	}
+	var value uint64
+	for _, b := range c.data[c.offset : c.offset+size] {
+		value = value<<8 | uint64(b)
+	}
+	c.offset += size
+	return value, nil
+}
+
+func (c *Cursor) need(n int) error {
+	if n < 0 {
+		return NewDecodeError(errors.New("invalid length requested"), "", c.offset)
+	}
+	if n > len(c.data)-c.offset {
+		return NewDecodeError(ErrTruncated, "", c.offset)
+	}
+	return nil
+}
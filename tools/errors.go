@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Decode failure kinds. Decoders in this package and in pkg return them
+// wrapped in a *DecodeError, so they are matched with errors.Is.
+var (
+	// ErrTruncated reports data ending before a field it announces.
+	ErrTruncated = errors.New("data length too short")
+	// ErrCRCMismatch reports a TCP frame whose CRC does not match its data.
+	ErrCRCMismatch = errors.New("CRC is not valid")
+	// ErrUnknownCodec reports a codec ID without a registered codec.
+	ErrUnknownCodec = errors.New("unknown codec")
+	// ErrRecordCountMismatch reports a record or command count that
+	// disagrees with the count repeated at the end of the data.
+	ErrRecordCountMismatch = errors.New("record count mismatch")
+	// ErrLengthMismatch reports a frame whose size disagrees with the length
+	// announced by its header.
+	ErrLengthMismatch = errors.New("length mismatch")
+	// ErrInvalidHeader reports a frame or login packet with a malformed
+	// header.
+	ErrInvalidHeader = errors.New("header is not valid")
+)
+
+// DecodeError locates a decode failure. Err is the cause, usually one of
+// the sentinel errors above, and is returned by Unwrap.
+//
+// Offset counts from the start of the slice given to the failing decoder:
+// the frame for TramDecoder, the data after the codec ID for the
+// DecodeCodecX functions.
+//
+// Example:
+//
+//	var decodeErr *tools.DecodeError
+//	if errors.As(err, &decodeErr) && errors.Is(err, tools.ErrTruncated) {
+//		fmt.Println(decodeErr.Record, decodeErr.Field, decodeErr.Offset)
+//	}
+type DecodeError struct {
+	Err     error
+	CodecID byte   // codec of the frame, 0 if not known yet
+	Record  int    // index of the record or command, -1 outside records
+	Field   string // name of the field being decoded, empty if unknown
+	Offset  int    // byte offset of the field, -1 if unknown
+}
+
+func (e *DecodeError) Error() string {
+	var message strings.Builder
+	if e.CodecID != 0 {
+		fmt.Fprintf(&message, "codec %02x: ", e.CodecID)
+	}
+	if e.Record >= 0 {
+		fmt.Fprintf(&message, "record %d: ", e.Record)
+	}
+	if e.Field != "" {
+		message.WriteString(e.Field)
+		if e.Offset >= 0 {
+			fmt.Fprintf(&message, " at offset %d", e.Offset)
+		}
+		message.WriteString(": ")
+	} else if e.Offset >= 0 {
+		fmt.Fprintf(&message, "offset %d: ", e.Offset)
+	}
+	message.WriteString(e.Err.Error())
+	return message.String()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// NewDecodeError returns a *DecodeError for err at offset, outside any
+// record and without a codec.
+func NewDecodeError(err error, field string, offset int) *DecodeError {
+	return &DecodeError{Err: err, Record: -1, Field: field, Offset: offset}
+}
+
+// AnnotateError adds the codec, record and field to the *DecodeError in
+// err, keeping the values it already has, or wraps err in a new
+// *DecodeError. A nil err is returned unchanged.
+//
+// Example:
+//
+//	timestamp, err := cursor.Uint64()
+//	if err != nil {
+//		return tools.AnnotateError(err, 0x08, i, "timestamp", -1)
+//	}
+func AnnotateError(err error, codecID byte, record int, field string, offset int) error {
+	if err == nil {
+		return nil
+	}
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		return &DecodeError{Err: err, CodecID: codecID, Record: record, Field: field, Offset: offset}
+	}
+	if decodeErr.CodecID == 0 {
+		decodeErr.CodecID = codecID
+	}
+	if decodeErr.Record < 0 {
+		decodeErr.Record = record
+	}
+	if decodeErr.Field == "" {
+		decodeErr.Field = field
+	}
+	if decodeErr.Offset < 0 {
+		decodeErr.Offset = offset
+	}
+	return err
+}
+
+// ShiftErrorOffset moves the offset of the *DecodeError in err by delta,
+// for decoders that hand a sub-slice of their input to another decoder.
+func ShiftErrorOffset(err error, delta int) error {
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) && decodeErr.Offset >= 0 {
+		decodeErr.Offset += delta
+	}
+	return err
+}
@@ -1,26 +1,31 @@
 package tools
 
 import (
-	"encoding/hex"
-	"fmt"
-	"strconv"
+	"encoding/binary"
 
 	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
 )
 
-// DecodeGPSData parses a 14-byte binary GPS data block from Teltonika protocol
-// and extracts latitude, longitude, altitude, angle, satellite count, and speed.
+// GPSElementSize is the size of the GPS element of an AVL record.
+const GPSElementSize = 15
+
+// DecodeGPSData parses the 15-byte GPS element of an AVL record and
+// extracts latitude, longitude, altitude, angle, satellite count, speed
+// and whether the position is a valid fix.
 //
-// Data format (14 bytes):
+// Data format (15 bytes):
 //   - Bytes 0-3: Longitude (int32, degrees * 10^7)
 //   - Bytes 4-7: Latitude (int32, degrees * 10^7)
 //   - Bytes 8-9: Altitude (int16, meters)
-//   - Bytes 10-11: Angle (int16, degrees 0-359)
+//   - Bytes 10-11: Angle (uint16, degrees 0-359)
 //   - Byte 12: Number of satellites
-//   - Byte 13: Speed (1 byte)
+//   - Bytes 13-14: Speed (uint16, km/h)
+//
+// A device without a fix sends 0 satellites and speed 0, and repeats the
+// last known position, altitude and angle; Valid is false for it.
 //
 // Parameters:
-//   - data: exactly 14 bytes of GPS data
+//   - data: the 15 bytes of the GPS element
 //
 // Returns:
 //   - *tool_domain.GPSData: pointer to decoded GPS information
@@ -29,50 +34,43 @@ import (
 // Example:
 //
 //	gpsData, err := DecodeGPSData(rawGPSBytes)
-//	if err == nil {
+//	if err == nil && gpsData.Valid {
 //		fmt.Printf("Location: %f, %f\n", gpsData.Latitude, gpsData.Longitude)
 //	}
 func DecodeGPSData(data []byte) (*tool_domain.GPSData, error) {
-	if len(data) < 14 {
-		return nil, fmt.Errorf("invalid data length %d", len(data))
-	}
-
-	lat, err := strconv.ParseUint(hex.EncodeToString(data[4:8]), 16, 32)
-	if err != nil {
-		return nil, err
-	}
-
-	long, err := strconv.ParseUint(hex.EncodeToString(data[:4]), 16, 32)
-	if err != nil {
-		return nil, err
-	}
-	altitude, err := strconv.ParseInt(hex.EncodeToString(data[8:10]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	angle, err := strconv.ParseInt(hex.EncodeToString(data[10:12]), 16, 64)
-	if err != nil {
-		return nil, err
-	}
-	satelites, err := strconv.ParseInt(hex.EncodeToString(data[12:13]), 16, 64)
-	if err != nil {
+	gpsData := &tool_domain.GPSData{}
+	if err := ReadGPSData(data, gpsData); err != nil {
 		return nil, err
 	}
+	return gpsData, nil
+}
 
-	speed, err := strconv.ParseInt(hex.EncodeToString(data[13:14]), 16, 64)
-	if err != nil {
-		return nil, err
+// ReadGPSData decodes a GPS data block like DecodeGPSData into gpsData
+// instead of allocating a new value, so that decoders can store the GPS
+// data of all records of a frame in one slice.
+//
+// Example:
+//
+//	gps := make([]tool_domain.GPSData, numberOfRecords)
+//	err := ReadGPSData(rawGPSBytes, &gps[i])
+func ReadGPSData(data []byte, gpsData *tool_domain.GPSData) error {
+	if len(data) < GPSElementSize {
+		return NewDecodeError(ErrTruncated, "GPS element", len(data))
 	}
 
-	longitude := float64(int32(long)) / 10000000.0
-	latitude := float64(int32(lat)) / 10000000.0
+	longitude := int32(binary.BigEndian.Uint32(data[0:4]))
+	latitude := int32(binary.BigEndian.Uint32(data[4:8]))
+	satellites := int64(data[12])
+	speed := int64(binary.BigEndian.Uint16(data[13:15]))
 
-	return &tool_domain.GPSData{
-		Latitude:  latitude,
-		Longitude: longitude,
-		Altitude:  altitude,
-		Angle:     angle,
-		Satelites: satelites,
+	*gpsData = tool_domain.GPSData{
+		Latitude:  float64(latitude) / 10000000.0,
+		Longitude: float64(longitude) / 10000000.0,
+		Altitude:  int64(binary.BigEndian.Uint16(data[8:10])),
+		Angle:     int64(binary.BigEndian.Uint16(data[10:12])),
+		Satelites: satellites,
 		Speed:     speed,
-	}, nil
+		Valid:     satellites != 0 || speed != 0,
+	}
+	return nil
 }
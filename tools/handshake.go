@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
+)
+
+// EncodeLogin builds the login packet a device sends when it connects:
+// the 2-byte IMEI length followed by the IMEI as ASCII. It is the inverse
+// of Login.
+//
+// Example:
+//
+//	packet, err := EncodeLogin("356307042441013")
+//	// packet: 000F333536333037303432343431303133
+func EncodeLogin(imei string) ([]byte, error) {
+	if imei == "" {
+		return nil, fmt.Errorf("IMEI is empty")
+	}
+	if len(imei) > 65535 {
+		return nil, fmt.Errorf("IMEI too long: %d bytes", len(imei))
+	}
+	data := make([]byte, 2, 2+len(imei))
+	binary.BigEndian.PutUint16(data, uint16(len(imei)))
+	return append(data, imei...), nil
+}
+
+// EncodeLoginResponse builds the server reply to a login packet: 0x01 when
+// the device is accepted, 0x00 when it is rejected.
+func EncodeLoginResponse(accepted bool) []byte {
+	if accepted {
+		return []byte{0x01}
+	}
+	return []byte{0x00}
+}
+
+// DecodeLoginResponse parses the server reply to a login packet and reports
+// whether the device was accepted.
+func DecodeLoginResponse(data []byte) (bool, error) {
+	if len(data) != 1 {
+		return false, fmt.Errorf("invalid login response length: %d", len(data))
+	}
+	switch data[0] {
+	case 0x01:
+		return true, nil
+	case 0x00:
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid login response: 0x%02X", data[0])
+	}
+}
+
+// EncodeTCPAck builds the 4-byte big-endian record count the server sends
+// after each TCP AVL frame.
+func EncodeTCPAck(accepted int64) ([]byte, error) {
+	if accepted < 0 || accepted > int64(^uint32(0)) {
+		return nil, fmt.Errorf("accepted records out of uint32 range: %d", accepted)
+	}
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, uint32(accepted))
+	return data, nil
+}
+
+// DecodeTCPAck parses a TCP acknowledgement and returns the number of
+// records the server accepted.
+func DecodeTCPAck(data []byte) (int64, error) {
+	if len(data) != 4 {
+		return 0, fmt.Errorf("invalid TCP ACK length: %d", len(data))
+	}
+	return int64(binary.BigEndian.Uint32(data)), nil
+}
+
+// EncodeUDPAck builds the acknowledgement the server sends after each UDP
+// AVL packet.
+//
+// ACK format (7 bytes):
+//   - Bytes 0-1: Length of the rest of the packet (always 5)
+//   - Bytes 2-3: Packet ID, copied from the AVL packet
+//   - Byte 4: Packet type (0x01)
+//   - Byte 5: AVL packet ID, copied from the AVL packet
+//   - Byte 6: Number of accepted records
+func EncodeUDPAck(packetID int64, avlPacketID int64, accepted int64) ([]byte, error) {
+	if packetID < 0 || packetID > 65535 {
+		return nil, fmt.Errorf("packet ID out of uint16 range: %d", packetID)
+	}
+	if avlPacketID < 0 || avlPacketID > 255 {
+		return nil, fmt.Errorf("AVL packet ID out of uint8 range: %d", avlPacketID)
+	}
+	if accepted < 0 || accepted > 255 {
+		return nil, fmt.Errorf("accepted records out of uint8 range: %d", accepted)
+	}
+	data := make([]byte, 7)
+	binary.BigEndian.PutUint16(data[0:2], 5)
+	binary.BigEndian.PutUint16(data[2:4], uint16(packetID))
+	data[4] = 0x01
+	data[5] = byte(avlPacketID)
+	data[6] = byte(accepted)
+	return data, nil
+}
+
+// DecodeUDPAck parses a UDP acknowledgement.
+func DecodeUDPAck(data []byte) (*tool_domain.UDPAckData, error) {
+	if len(data) != 7 {
+		return nil, fmt.Errorf("invalid UDP ACK length: %d", len(data))
+	}
+	length := int64(binary.BigEndian.Uint16(data[0:2]))
+	if length != 5 {
+		return nil, fmt.Errorf("invalid UDP ACK length field: %d", length)
+	}
+	return &tool_domain.UDPAckData{
+		Length:      length,
+		PacketID:    int64(binary.BigEndian.Uint16(data[2:4])),
+		PacketType:  int64(data[4]),
+		AVLPacketID: int64(data[5]),
+		Accepted:    int64(data[6]),
+	}, nil
+}
@@ -1,9 +1,9 @@
 package tools
 
 import (
-	"encoding/hex"
 	"fmt"
-	"strconv"
+
+	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
 )
 
 var generationTypeEncoding = map[string]byte{
@@ -25,39 +25,19 @@ func GetGenerationType(data []byte, startByte int64, length int64) (string, erro
 		return generation_type_translation, fmt.Errorf("data or length is too small")
 	}
 
-	generation_type, err := strconv.ParseInt(hex.EncodeToString(data[byte:byte+length]), 16, 64)
+	if byte < 0 || len(data) < int(byte+length) {
+		return generation_type_translation, fmt.Errorf("data or length is too small")
+	}
+	cursor := NewCursor(data[byte : byte+length])
+	generation_type, err := cursor.Uint(int(length))
 	if err != nil {
 		return generation_type_translation, err
 	}
 
-	switch generation_type {
-	case 0:
-		generation_type_translation = "On Exit"
-		return generation_type_translation, nil
-	case 1:
-		generation_type_translation = "On Entrance"
-		return generation_type_translation, nil
-	case 2:
-		generation_type_translation = "On Both"
-		return generation_type_translation, nil
-	case 3:
-		generation_type_translation = "Reserved"
-		return generation_type_translation, nil
-	case 4:
-		generation_type_translation = "Hysteresis"
-		return generation_type_translation, nil
-	case 5:
-		generation_type_translation = "On Change"
-		return generation_type_translation, nil
-	case 6:
-		generation_type_translation = "Eventual"
-		return generation_type_translation, nil
-	case 7:
-		generation_type_translation = "Periodical"
-		return generation_type_translation, nil
-	default:
+	if generation_type > 0xFF {
 		return generation_type_translation, nil
 	}
+	return decoder_domain.GenerationType(generation_type).String(), nil
 }
 
 // EncodeGenerationType converts a generation type name to its byte value.
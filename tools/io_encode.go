@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"sort"
 
 	io_domain "github.com/danieljvsa/teltonika-go/internal/io"
@@ -24,6 +25,93 @@ func encodeIOValue(value string) ([]byte, error) {
 	return data, nil
 }
 
+// newIOData returns an IOData holding value.
+func newIOData(id int64, value []byte) io_domain.IOData {
+	return io_domain.IOData{IO: id, Value: hex.EncodeToString(value)}
+}
+
+// checkIOSize validates the size of a fixed-width IO value.
+func checkIOSize(size int) error {
+	switch size {
+	case 1, 2, 4, 8:
+		return nil
+	}
+	return fmt.Errorf("invalid IO value length: %d bytes", size)
+}
+
+// NewIOUint builds an IO element holding value as a big-endian unsigned
+// integer of size bytes (1, 2, 4 or 8).
+//
+// Example:
+//
+//	io, err := tools.NewIOUint(66, 2, 12401) // External Voltage, 12.401 V
+func NewIOUint(id int64, size int, value uint64) (io_domain.IOData, error) {
+	if err := checkIOSize(size); err != nil {
+		return io_domain.IOData{}, err
+	}
+	if size < 8 && value>>(8*size) != 0 {
+		return io_domain.IOData{}, fmt.Errorf("IO %d value %d does not fit in %d bytes", id, value, size)
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	return newIOData(id, data[8-size:]), nil
+}
+
+// NewIOInt builds an IO element holding value as a two's complement
+// integer of size bytes (1, 2, 4 or 8).
+func NewIOInt(id int64, size int, value int64) (io_domain.IOData, error) {
+	if err := checkIOSize(size); err != nil {
+		return io_domain.IOData{}, err
+	}
+	if shift := 64 - 8*size; value<<shift>>shift != value {
+		return io_domain.IOData{}, fmt.Errorf("IO %d value %d does not fit in %d bytes", id, value, size)
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(value))
+	return newIOData(id, data[8-size:]), nil
+}
+
+// NewIOFloat builds an IO element of size bytes from a physical value,
+// dividing it by the multiplier of scaler and rounding to the nearest
+// integer. It is the inverse of IOData.Float.
+//
+// Example:
+//
+//	entry, _ := iodict.Builtin().Lookup(72)
+//	io, err := tools.NewIOFloat(72, 4, -10.5, entry) // Dallas Temperature 1
+func NewIOFloat(id int64, size int, value float64, scaler io_domain.Scaler) (io_domain.IOData, error) {
+	scale, signed := 1.0, false
+	if scaler != nil {
+		scale, signed = scaler.Scale(), scaler.IsSigned()
+	}
+	raw := math.Round(value / scale)
+	if signed {
+		if raw < math.MinInt64 || raw >= math.MaxInt64 {
+			return io_domain.IOData{}, fmt.Errorf("IO %d value %v is out of range", id, value)
+		}
+		return NewIOInt(id, size, int64(raw))
+	}
+	if raw < 0 || raw >= math.MaxUint64 {
+		return io_domain.IOData{}, fmt.Errorf("IO %d value %v is out of range", id, value)
+	}
+	return NewIOUint(id, size, uint64(raw))
+}
+
+// NewIOBool builds a one-byte IO element holding 1 for true and 0 for
+// false.
+func NewIOBool(id int64, value bool) io_domain.IOData {
+	if value {
+		return newIOData(id, []byte{1})
+	}
+	return newIOData(id, []byte{0})
+}
+
+// NewIOBytes builds an IO element holding value as is, for variable-length
+// Codec 8E elements.
+func NewIOBytes(id int64, value []byte) io_domain.IOData {
+	return newIOData(id, value)
+}
+
 // EncodeIOData8 encodes IO elements for Codec 8.
 func EncodeIOData8(ios []io_domain.IOData) ([]byte, int64, error) {
 	var oneByte, twoByte, fourByte, eightByte []io_domain.IOData
@@ -185,12 +273,17 @@ func EncodeIOData8Extended(ios []io_domain.IOData) ([]byte, int64, error) {
 
 // EncodeIOData16 encodes IO elements for Codec 16, including generation type.
 func EncodeIOData16(ios []io_domain.IOData, generationType string) ([]byte, int64, error) {
-	var oneByte, twoByte, fourByte, eightByte []io_domain.IOData
-
 	genValue, err := EncodeGenerationType(generationType)
 	if err != nil {
 		return nil, 0, err
 	}
+	return EncodeIOData16Generation(ios, genValue)
+}
+
+// EncodeIOData16Generation encodes IO elements for Codec 16 with the
+// generation type byte as is, including values without a name.
+func EncodeIOData16Generation(ios []io_domain.IOData, genValue byte) ([]byte, int64, error) {
+	var oneByte, twoByte, fourByte, eightByte []io_domain.IOData
 
 	for _, io := range ios {
 		if io.IO < 0 || io.IO > 65535 {
@@ -1,9 +1,7 @@
 package tools
 
 import (
-	"encoding/hex"
-	"fmt"
-	"strconv"
+	"encoding/binary"
 
 	decoder_domain "github.com/danieljvsa/teltonika-go/internal/decoder"
 )
@@ -29,15 +27,10 @@ import (
 //	}
 func IsLogin(tram []byte) (bool, error) {
 	if len(tram) < 2 {
-		return false, fmt.Errorf("tram is too small")
+		return false, NewDecodeError(ErrTruncated, "IMEI length", len(tram))
 	}
 
-	length, err := strconv.ParseInt(hex.EncodeToString(tram[:2]), 16, 64)
-	if err != nil {
-		return false, err
-	}
-
-	if length == 0 {
+	if binary.BigEndian.Uint16(tram[:2]) == 0 {
 		return false, nil
 	}
 
@@ -65,25 +58,17 @@ func IsLogin(tram []byte) (bool, error) {
 //		fmt.Printf("Length: %d, IMEI: %s\n", *response.Length, *response.IMEI)
 //	}
 func Login(tram []byte) (*decoder_domain.CodecHeaderResponse, error) {
-	read := 0
 	valid, err := IsLogin(tram)
 	if err != nil {
 		return nil, err
 	}
 
 	if !valid {
-		return nil, fmt.Errorf("login message is not valid")
-	}
-
-	length, err := strconv.ParseInt(hex.EncodeToString(tram[read:read+2]), 16, 64)
-	if err != nil {
-		return nil, err
+		return nil, NewDecodeError(ErrInvalidHeader, "IMEI length", 0)
 	}
-	read += 2
 
-	hexImei := hex.EncodeToString(tram[read:])
-	bytesImei, _ := hex.DecodeString(hexImei)
-	imei := string(bytesImei)
+	length := int64(binary.BigEndian.Uint16(tram[:2]))
+	imei := string(tram[2:])
 
 	return &decoder_domain.CodecHeaderResponse{Length: &length, IMEI: &imei}, nil
 }
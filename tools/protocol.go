@@ -1,18 +1,17 @@
 package tools
 
 import (
-	"encoding/hex"
-	"fmt"
+	"encoding/binary"
 
 	tool_domain "github.com/danieljvsa/teltonika-go/internal/tool"
 )
 
 func GetProtocol(header []byte) (*tool_domain.ProtocolData, error) {
 	if len(header) < 4 {
-		return nil, fmt.Errorf("header is too small")
+		return nil, NewDecodeError(ErrTruncated, "preamble", len(header))
 	}
 
-	if hex.EncodeToString(header[:4]) == "00000000" {
+	if binary.BigEndian.Uint32(header[:4]) == 0 {
 		return &tool_domain.ProtocolData{Protocol: "TCP"}, nil
 	}
 
@@ -2,57 +2,55 @@ package tools
 
 import (
 	"encoding/binary"
-	"encoding/hex"
 	"fmt"
-	"strconv"
 	"time"
 )
 
 // CalcTimestamp converts an 8-byte big-endian byte slice representing
 // a Unix timestamp in milliseconds to a UTC time.Time pointer.
-// It uses hex string encoding to parse the bytes as a 64-bit integer.
 //
 // Parameters:
 //   - data: 8-byte slice containing millisecond timestamp
 //
 // Returns:
 //   - *time.Time: pointer to the decoded UTC time
-//   - error: parsing error if the byte slice cannot be converted to integer
+//   - error: if data is empty or longer than 8 bytes
 //
 // Example:
 //
 //	data := []byte{0x00, 0x00, 0x01, 0x6A, 0x57, 0x1F, 0x00, 0x00}
 //	timestamp, err := CalcTimestamp(data)
 func CalcTimestamp(data []byte) (*time.Time, error) {
-	timestamp, err := strconv.ParseInt(hex.EncodeToString(data), 16, 64)
+	cursor := NewCursor(data)
+	timestamp, err := cursor.Uint(len(data))
 	if err != nil {
 		return nil, err
 	}
-	date := time.UnixMilli(timestamp).UTC()
+	date := time.UnixMilli(int64(timestamp)).UTC()
 	return &date, nil
 }
 
-// CalcTimestampSeconds converts a byte slice (hex-encoded) representing
+// CalcTimestampSeconds converts a big-endian byte slice representing
 // a Unix timestamp in seconds to a UTC time.Time pointer.
-// Uses hex string encoding to parse bytes as a 64-bit integer.
 //
 // Parameters:
 //   - data: byte slice containing second timestamp
 //
 // Returns:
 //   - *time.Time: pointer to the decoded UTC time
-//   - error: parsing error if conversion fails
+//   - error: if data is empty or longer than 8 bytes
 //
 // Example:
 //
 //	data := []byte{0x56, 0xD8, 0x26, 0xA0}
 //	timestamp, err := CalcTimestampSeconds(data)
 func CalcTimestampSeconds(data []byte) (*time.Time, error) {
-	timestamp, err := strconv.ParseInt(hex.EncodeToString(data), 16, 64)
+	cursor := NewCursor(data)
+	timestamp, err := cursor.Uint(len(data))
 	if err != nil {
 		return nil, err
 	}
-	date := time.Unix(timestamp, 0).UTC()
+	date := time.Unix(int64(timestamp), 0).UTC()
 	return &date, nil
 }
 